@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/jayzheng/vectcode/pkg/mcp"
 )
@@ -22,11 +25,23 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Failed to create server: %v\n", err)
 		os.Exit(1)
 	}
-	defer server.Close()
+
+	// A terminating signal stops reading requests; those in flight still
+	// get their responses
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Run server (reads from stdin, writes to stdout)
-	if err := server.Run(os.Stdin, os.Stdout); err != nil {
-		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+	runErr := server.RunContext(ctx, os.Stdin, os.Stdout)
+
+	// Close before exiting, as os.Exit skips deferred calls
+	closeErr := server.Close()
+	if runErr != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", runErr)
+		os.Exit(1)
+	}
+	if closeErr != nil {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", closeErr)
 		os.Exit(1)
 	}
 }
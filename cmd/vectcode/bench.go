@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+func benchCmd() *cobra.Command {
+	var (
+		queriesPath string
+		labelsPath  string
+		limit       int
+		projectName string
+		repeat      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure query latency and recall",
+		Long: `Run a set of queries and report retrieval latency (p50/p95, split into
+embed and search time) and, given labels, recall of known relevant chunks.
+
+The queries file has one query per line; blank lines and lines starting
+with # are ignored. The optional labels file is YAML mapping each query
+to the IDs of the chunks it should retrieve:
+
+  "where are JWT tokens validated":
+    - my-service:internal/auth/jwt.go:Validate`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if queriesPath == "" {
+				return fmt.Errorf("--queries is required")
+			}
+			if repeat < 1 {
+				return fmt.Errorf("--repeat must be at least 1")
+			}
+
+			queries, err := readQueries(queriesPath)
+			if err != nil {
+				return err
+			}
+			if len(queries) == 0 {
+				return fmt.Errorf("no queries found in %s", queriesPath)
+			}
+
+			var labels map[string][]string
+			if labelsPath != "" {
+				data, err := os.ReadFile(labelsPath)
+				if err != nil {
+					return fmt.Errorf("failed to read labels: %w", err)
+				}
+				if err := yaml.Unmarshal(data, &labels); err != nil {
+					return fmt.Errorf("failed to parse labels: %w", err)
+				}
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// --limit overrides the configured default
+			if !cmd.Flags().Changed("limit") {
+				limit = cfg.Query.EffectiveLimit()
+			}
+
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			emb, err := a.Embedder()
+			if err != nil {
+				return err
+			}
+			store, err := a.VectorStore()
+			if err != nil {
+				return err
+			}
+
+			// Not a.Engine: its result cache would answer the repeated runs
+			engine := query.NewEngine(emb, store)
+			opts := vectorstore.SearchOptions{Limit: limit}
+			if projectName != "" {
+				opts.Projects = []string{projectName}
+			}
+
+			ctx := context.Background()
+			var embedTimes, searchTimes, totalTimes []time.Duration
+			var recallSum float64
+			var labeled int
+
+			fmt.Printf("Running %d queries x %d (limit %d)\n\n", len(queries), repeat, opts.EffectiveLimit())
+			for _, queryText := range queries {
+				var results []vectorstore.SearchResult
+				var last query.QueryStats
+				for run := 0; run < repeat; run++ {
+					results, last, err = engine.QueryWithStats(ctx, queryText, opts)
+					if err != nil {
+						return fmt.Errorf("query %q failed: %w", queryText, err)
+					}
+					embedTimes = append(embedTimes, last.Embed)
+					searchTimes = append(searchTimes, last.Search)
+					totalTimes = append(totalTimes, last.Total())
+				}
+
+				line := fmt.Sprintf("%8s  %2d results  %s", last.Total().Round(100*time.Microsecond), len(results), queryText)
+				if relevant, ok := labels[queryText]; ok && len(relevant) > 0 {
+					r := recall(results, relevant)
+					recallSum += r
+					labeled++
+					line += fmt.Sprintf("  (recall %.2f)", r)
+				}
+				fmt.Println(line)
+			}
+
+			fmt.Printf("\nLatency over %d runs:\n", len(totalTimes))
+			fmt.Printf("  total   p50 %-8s p95 %s\n", percentile(totalTimes, 50), percentile(totalTimes, 95))
+			fmt.Printf("  embed   p50 %-8s p95 %s\n", percentile(embedTimes, 50), percentile(embedTimes, 95))
+			fmt.Printf("  search  p50 %-8s p95 %s\n", percentile(searchTimes, 50), percentile(searchTimes, 95))
+
+			if labelsPath != "" {
+				if labeled == 0 {
+					fmt.Println("\nNo queries matched the labels file.")
+				} else {
+					fmt.Printf("\nRecall@%d: %.3f over %d labeled queries\n", opts.EffectiveLimit(), recallSum/float64(labeled), labeled)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&queriesPath, "queries", "", "File with one query per line (required)")
+	cmd.Flags().StringVar(&labelsPath, "labels", "", "YAML file mapping queries to relevant chunk IDs, to measure recall")
+	cmd.Flags().IntVarP(&limit, "limit", "l", vectorstore.DefaultSearchLimit, "Results per query, the k in recall@k (overrides query.default_limit)")
+	cmd.Flags().StringVarP(&projectName, "project", "p", "", "Filter by project name")
+	cmd.Flags().IntVar(&repeat, "repeat", 1, "Run each query this many times")
+
+	return cmd
+}
+
+// readQueries reads one query per line, skipping blanks and # comments
+func readQueries(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+	defer file.Close()
+
+	var queries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		queries = append(queries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+	return queries, nil
+}
+
+// recall returns the fraction of relevant chunk IDs present in results
+func recall(results []vectorstore.SearchResult, relevant []string) float64 {
+	found := make(map[string]bool, len(results))
+	for _, result := range results {
+		found[result.Chunk.ID] = true
+	}
+
+	hits := 0
+	for _, id := range relevant {
+		if found[id] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(relevant))
+}
+
+// percentile returns the nearest-rank percentile p (0-100) of durations
+func percentile(durations []time.Duration, p int) time.Duration {
+	if len(durations) == 0 {
+		return 0
+	}
+
+	sorted := make([]time.Duration, len(durations))
+	copy(sorted, durations)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a] < sorted[b] })
+
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1].Round(100 * time.Microsecond)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+func collectionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "collections",
+		Short: "Manage vector store collections",
+		Long: `List and delete the collections in the vector store, such as those left
+behind by indexing with --collection or with namespace_by_model under
+another embedding model.`,
+	}
+
+	cmd.AddCommand(collectionsListCmd())
+	cmd.AddCommand(collectionsDeleteCmd())
+
+	return cmd
+}
+
+func collectionsListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the vector store's collections",
+		Long: `Print every collection in the vector store with its chunk count, vector
+length, distance metric, and the projects the metadata records in it. The
+collection the configuration uses is marked with "*"; one no project is
+recorded in is probably left over from an experiment.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			collections, err := a.Collections(ctx)
+			if err != nil {
+				return err
+			}
+			if len(collections) == 0 {
+				fmt.Println("No collections found.")
+				return nil
+			}
+
+			current, err := cfg.ToVectorStoreConfig().CollectionName()
+			if err != nil {
+				return err
+			}
+			byCollection, unrecorded, err := projectsByCollection(ctx, a)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Collections (%d):\n\n", len(collections))
+			fmt.Printf("  %-32s %8s %10s  %-6s  %s\n", "NAME", "CHUNKS", "DIMENSIONS", "METRIC", "PROJECTS")
+			for _, collection := range collections {
+				marker := " "
+				if collection.Name == current {
+					marker = "*"
+				}
+				dimension, metric := "-", "-"
+				if collection.Dimension > 0 {
+					dimension = fmt.Sprintf("%d", collection.Dimension)
+				}
+				if collection.Metric != "" {
+					metric = string(collection.Metric)
+				}
+				projects := "-"
+				if names := byCollection[collection.Name]; len(names) > 0 {
+					projects = strings.Join(names, ", ")
+				}
+				fmt.Printf("%s %-32s %8d %10s  %-6s  %s\n", marker, collection.Name, collection.Chunks, dimension, metric, projects)
+			}
+
+			fmt.Printf("\n* the configured collection\n")
+			if unrecorded > 0 {
+				fmt.Printf("%d projects were indexed before their collection was recorded and are not listed\n", unrecorded)
+			}
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func collectionsDeleteCmd() *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a collection and every chunk in it",
+		Long: `Delete a collection from the vector store. Without --yes, only show what
+would be deleted.
+
+Projects the metadata records in the collection are not deleted; re-index
+them, or remove them with vectcode delete. Deleting the configured
+collection empties the index until the next vectcode index recreates it.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			collections, err := a.Collections(ctx)
+			if err != nil {
+				return err
+			}
+			var target *vectorstore.CollectionInfo
+			for i := range collections {
+				if collections[i].Name == name {
+					target = &collections[i]
+				}
+			}
+			if target == nil {
+				return fmt.Errorf("collection '%s' not found (vectcode collections list shows them)", name)
+			}
+
+			byCollection, _, err := projectsByCollection(ctx, a)
+			if err != nil {
+				return err
+			}
+			projects := byCollection[name]
+
+			if !yes {
+				fmt.Printf("This will delete collection '%s' with %d chunks\n", name, target.Chunks)
+				if len(projects) > 0 {
+					fmt.Printf("Projects indexed into it: %s\n", strings.Join(projects, ", "))
+				}
+				return fmt.Errorf("refusing to delete without --yes")
+			}
+
+			if err := a.DeleteCollection(ctx, name); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Collection '%s' deleted (%d chunks)\n", name, target.Chunks)
+
+			if len(projects) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d projects were indexed into '%s' and have no chunks now; re-index them or remove them with vectcode delete: %s\n",
+					len(projects), name, strings.Join(projects, ", "))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm deleting the collection")
+
+	return cmd
+}
+
+// projectsByCollection returns the names of the projects the metadata
+// records in each collection, and how many projects have none recorded
+func projectsByCollection(ctx context.Context, a *app.App) (map[string][]string, int, error) {
+	projects, err := a.ListProjects(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byCollection := make(map[string][]string)
+	unrecorded := 0
+	for _, project := range projects {
+		if project.Collection == "" {
+			unrecorded++
+			continue
+		}
+		byCollection[project.Collection] = append(byCollection[project.Collection], project.Name)
+	}
+	return byCollection, unrecorded, nil
+}
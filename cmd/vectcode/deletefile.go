@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/parser"
+)
+
+func deleteFileCmd() *cobra.Command {
+	var (
+		projectName string
+		filePath    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "delete-file",
+		Short: "Delete one file's chunks from a project",
+		Long: `Remove the chunks of a single file from the vector store and its record
+from the metadata, without re-indexing the project. The path is relative to
+the project root or to any of the project's paths.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if projectName == "" {
+				return fmt.Errorf("--project is required")
+			}
+			if filePath == "" {
+				return fmt.Errorf("--path is required")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+			metaStore := a.Metadata()
+
+			project, err := a.Project(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			// The project's chunks are in the collection it was indexed into
+			store, closeStore, err := a.ProjectStore(project)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
+
+			// Chunks record paths relative to the project root; try the path
+			// as given and under each project path, and for projects indexed
+			// before paths were relative, the joined path itself
+			root := project.RootPath()
+			candidates := []string{filepath.ToSlash(filepath.Clean(filePath))}
+			for _, dir := range project.AllPaths() {
+				joined := filepath.Join(dir, filepath.FromSlash(filePath))
+				candidates = append(candidates, parser.RelativePath(root, joined), joined)
+			}
+			var stored string
+			var count int
+			for _, candidate := range candidates {
+				count, err = store.CountChunksByFile(ctx, projectName, candidate)
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					stored = candidate
+					break
+				}
+			}
+			if stored == "" {
+				return fmt.Errorf("no chunks indexed for %s in project %s", filePath, projectName)
+			}
+
+			if err := store.DeleteByFile(ctx, projectName, stored); err != nil {
+				return err
+			}
+
+			rel := parser.RelativePath(root, stored)
+			if err := metaStore.DeleteFile(ctx, project.ID, rel); err != nil && !errors.Is(err, metadata.ErrFileNotFound) {
+				return fmt.Errorf("failed to update file metadata: %w", err)
+			}
+
+			project.ChunkCount -= count
+			if project.ChunkCount < 0 {
+				project.ChunkCount = 0
+			}
+			if err := metaStore.UpdateProject(ctx, project); err != nil {
+				return fmt.Errorf("failed to update project metadata: %w", err)
+			}
+
+			fmt.Printf("Deleted %d chunks of %s from project %s\n", count, rel, projectName)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&projectName, "project", "p", "", "Name of the project (required)")
+	cmd.Flags().StringVar(&filePath, "path", "", "File path relative to the project root or a project path (required)")
+
+	return cmd
+}
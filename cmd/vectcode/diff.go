@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+func diffCmd() *cobra.Command {
+	var (
+		projectA   string
+		projectB   string
+		threshold  float64
+		jsonOutput bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compare two indexed projects symbol by symbol",
+		Long: `Compare two indexed projects, typically two snapshots of one codebase such
+as a branch before and after a refactor, by their symbols rather than their
+text, using the embeddings already stored.
+
+Symbols are paired by package, type, and name. A pair whose embeddings are
+less similar than --threshold is reported as changed, and one edited but
+above it as modified. A symbol only in --b that closely resembles one only
+in --a is reported as renamed or moved; the others are added and removed.
+Nothing is embedded, but both projects must have been indexed with the same
+embedding model.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if projectA == "" || projectB == "" {
+				return fmt.Errorf("--a and --b are required")
+			}
+			if threshold <= 0 || threshold > 1 {
+				return fmt.Errorf("--threshold must be greater than 0 and at most 1")
+			}
+
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			diff, err := a.Diff(context.Background(), projectA, projectB, threshold)
+			if err != nil {
+				return err
+			}
+
+			if jsonOutput {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(newDiffJSON(diff))
+			}
+			printDiff(diff)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&projectA, "a", "", "Project to compare from, e.g. the code before a change (required)")
+	cmd.Flags().StringVar(&projectB, "b", "", "Project to compare to, e.g. the code after a change (required)")
+	cmd.Flags().Float64Var(&threshold, "threshold", app.DefaultDiffThreshold, "Embedding similarity below which a symbol counts as changed, and above which an unpaired one counts as renamed")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print the comparison as JSON")
+
+	return cmd
+}
+
+// diffHeadings title the sections of printDiff, with the marker of each line
+var diffHeadings = map[app.DiffKind]struct{ title, marker string }{
+	app.DiffChanged:  {"Changed (less similar than the threshold)", "~"},
+	app.DiffRenamed:  {"Renamed or moved", ">"},
+	app.DiffAdded:    {"Added", "+"},
+	app.DiffRemoved:  {"Removed", "-"},
+	app.DiffModified: {"Modified (edited, similar above the threshold)", "*"},
+}
+
+// printDiff writes a summary line and then a section per kind of change
+func printDiff(diff *app.ProjectDiff) {
+	fmt.Printf("Comparing %s -> %s (threshold %.2f)\n\n", diff.A, diff.B, diff.Threshold)
+
+	var counts []string
+	for _, kind := range app.DiffKinds {
+		counts = append(counts, fmt.Sprintf("%d %s", diff.Counts[kind], kind))
+	}
+	fmt.Printf("%s\n", strings.Join(counts, ", "))
+
+	var kind app.DiffKind
+	for _, symbol := range diff.Symbols {
+		heading := diffHeadings[symbol.Kind]
+		if symbol.Kind != kind {
+			kind = symbol.Kind
+			fmt.Printf("\n%s:\n", heading.title)
+		}
+
+		switch symbol.Kind {
+		case app.DiffAdded:
+			fmt.Printf("  %s %s  %s\n", heading.marker, diffSymbol(symbol.B), diffLocation(symbol.B))
+		case app.DiffRemoved:
+			fmt.Printf("  %s %s  %s\n", heading.marker, diffSymbol(symbol.A), diffLocation(symbol.A))
+		case app.DiffRenamed:
+			fmt.Printf("  %s %.4f  %s -> %s  %s -> %s\n", heading.marker, symbol.Similarity,
+				diffSymbol(symbol.A), diffSymbol(symbol.B), diffLocation(symbol.A), diffLocation(symbol.B))
+		default:
+			location := diffLocation(symbol.B)
+			if symbol.A.FilePath != symbol.B.FilePath {
+				location = diffLocation(symbol.A) + " -> " + location
+			}
+			fmt.Printf("  %s %.4f  %s  %s\n", heading.marker, symbol.Similarity, diffSymbol(symbol.B), location)
+		}
+	}
+}
+
+// diffSymbol names a symbol with its type, e.g. "method Server.Handle"
+func diffSymbol(chunk *chunker.CodeChunk) string {
+	return fmt.Sprintf("%s %s", chunk.ChunkType, chunk.QualifiedName())
+}
+
+// diffLocation is where a symbol starts, e.g. "server.go:40"
+func diffLocation(chunk *chunker.CodeChunk) string {
+	return fmt.Sprintf("%s:%d", chunk.FilePath, chunk.LineStart)
+}
+
+// diffJSON is the --json form of a ProjectDiff
+type diffJSON struct {
+	A         string           `json:"a"`
+	B         string           `json:"b"`
+	Threshold float64          `json:"threshold"`
+	Counts    map[string]int   `json:"counts"`
+	Symbols   []diffSymbolJSON `json:"symbols"`
+}
+
+// diffSymbolJSON is one symbol of diffJSON, located in either project
+type diffSymbolJSON struct {
+	Kind       string         `json:"kind"`
+	Similarity float64        `json:"similarity,omitempty"`
+	A          *diffSymbolRef `json:"a,omitempty"`
+	B          *diffSymbolRef `json:"b,omitempty"`
+}
+
+// diffSymbolRef locates a symbol in one of the compared projects
+type diffSymbolRef struct {
+	Type      chunker.ChunkType `json:"type"`
+	Name      string            `json:"name"`
+	Package   string            `json:"package"`
+	File      string            `json:"file"`
+	LineStart int               `json:"line_start"`
+	LineEnd   int               `json:"line_end"`
+}
+
+func newDiffJSON(diff *app.ProjectDiff) diffJSON {
+	out := diffJSON{
+		A:         diff.A,
+		B:         diff.B,
+		Threshold: diff.Threshold,
+		Counts:    make(map[string]int, len(app.DiffKinds)),
+		Symbols:   make([]diffSymbolJSON, len(diff.Symbols)),
+	}
+	for _, kind := range app.DiffKinds {
+		out.Counts[string(kind)] = diff.Counts[kind]
+	}
+	for i, symbol := range diff.Symbols {
+		out.Symbols[i] = diffSymbolJSON{
+			Kind:       string(symbol.Kind),
+			Similarity: symbol.Similarity,
+			A:          newDiffSymbolRef(symbol.A),
+			B:          newDiffSymbolRef(symbol.B),
+		}
+	}
+	return out
+}
+
+func newDiffSymbolRef(chunk *chunker.CodeChunk) *diffSymbolRef {
+	if chunk == nil {
+		return nil
+	}
+	return &diffSymbolRef{
+		Type:      chunk.ChunkType,
+		Name:      chunk.QualifiedName(),
+		Package:   chunk.Package,
+		File:      chunk.FilePath,
+		LineStart: chunk.LineStart,
+		LineEnd:   chunk.LineEnd,
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+)
+
+// File states shown by the files command
+const (
+	fileCurrent = "ok"
+	fileStale   = "stale"   // modified since it was indexed
+	fileMissing = "missing" // no longer on disk
+)
+
+func filesCmd() *cobra.Command {
+	var (
+		projectName string
+		staleOnly   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "files",
+		Short: "List the files tracked for a project",
+		Long: `Print each file recorded for an indexed project with its chunk count,
+when it was last indexed, and whether it changed since. A file is stale when
+it was modified after it was indexed, and missing when it no longer exists;
+re-index the project (or use index --since) to refresh them.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if projectName == "" {
+				return fmt.Errorf("--name is required")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+			metaStore := a.Metadata()
+
+			project, err := a.Project(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			files, err := metaStore.ListFiles(ctx, project.ID)
+			if err != nil {
+				return err
+			}
+			if len(files) == 0 {
+				fmt.Printf("No files tracked for project '%s'; re-index it to record them\n", projectName)
+				return nil
+			}
+
+			// Files the metadata already knows are stale, plus those whose
+			// modification time on disk is newer than their last index
+			staleFiles, err := metaStore.GetStaleFiles(ctx, project.ID)
+			if err != nil {
+				return err
+			}
+			stale := make(map[string]bool, len(staleFiles))
+			for _, file := range staleFiles {
+				stale[file.FilePath] = true
+			}
+
+			fmt.Printf("%-7s %6s  %-16s  %s\n", "STATUS", "CHUNKS", "LAST INDEXED", "FILE")
+			shown, outdated := 0, 0
+			for _, file := range files {
+				state := fileState(project.RootPath(), file, stale[file.FilePath])
+				if state != fileCurrent {
+					outdated++
+				} else if staleOnly {
+					continue
+				}
+				shown++
+
+				indexed := "never"
+				if file.LastIndexedAt != nil {
+					indexed = formatTimeAgo(*file.LastIndexedAt)
+				}
+				fmt.Printf("%-7s %6d  %-16s  %s\n", state, file.ChunkCount, indexed, file.FilePath)
+			}
+
+			fmt.Printf("\n%d files tracked, %d stale or missing\n", len(files), outdated)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project (required)")
+	cmd.Flags().BoolVar(&staleOnly, "stale-only", false, "Only list files that are stale or missing")
+
+	return cmd
+}
+
+// fileState checks a tracked file against the project root on disk
+func fileState(root string, file metadata.File, knownStale bool) string {
+	info, err := os.Stat(filepath.Join(root, filepath.FromSlash(file.FilePath)))
+	if err != nil {
+		return fileMissing
+	}
+	if knownStale || file.LastIndexedAt == nil || info.ModTime().After(*file.LastIndexedAt) {
+		return fileStale
+	}
+	return fileCurrent
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/parser"
+)
+
+func languagesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "languages",
+		Short: "List the languages that can be indexed and their file extensions",
+		Long: `List the languages index --lang accepts and the file extensions each
+parser handles. With --lang auto (the default), every file goes to the parser
+for its extension and files with other extensions are skipped.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			for _, language := range parser.Languages() {
+				fmt.Printf("%-8s %s\n", language.Name, strings.Join(language.Extensions, ", "))
+			}
+			return nil
+		},
+	}
+}
+
+// languageNames lists the registered languages for flag help, e.g. "go, rust"
+func languageNames() string {
+	var names []string
+	for _, language := range parser.Languages() {
+		names = append(names, language.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
 	"github.com/jayzheng/vectcode/pkg/config"
 	"github.com/jayzheng/vectcode/pkg/embedder"
-	"github.com/jayzheng/vectcode/pkg/indexer"
 	"github.com/jayzheng/vectcode/pkg/metadata"
 	"github.com/jayzheng/vectcode/pkg/parser"
 	"github.com/jayzheng/vectcode/pkg/query"
@@ -20,7 +25,10 @@ import (
 
 var version = "0.1.0"
 
-var configPath string
+var (
+	configPath string
+	profile    string
+)
 
 func getConfigPath() string {
 	if configPath != "" {
@@ -30,6 +38,12 @@ func getConfigPath() string {
 	return filepath.Join(home, ".vectcode", "config.yaml")
 }
 
+// loadConfig loads the config file with the --profile profile, or the
+// defaults if there is no config file
+func loadConfig() (*config.Config, error) {
+	return config.LoadOrDefaultProfile(getConfigPath(), profile)
+}
+
 func formatTimeAgo(t time.Time) string {
 	duration := time.Since(t)
 
@@ -68,6 +82,58 @@ func formatProjectList(projects []string) string {
 	return fmt.Sprintf("%s and %d more", projects[0], len(projects)-1)
 }
 
+// printParseReport summarizes files the parser skipped or failed to parse
+func printParseReport(report parser.Report) {
+	if report.SkippedByBuild > 0 {
+		fmt.Printf("Skipped %d files excluded by build constraints (use --all-platforms to include them)\n", report.SkippedByBuild)
+	}
+	if report.SkippedByIgnore > 0 {
+		fmt.Printf("Skipped %d files matching %s\n", report.SkippedByIgnore, parser.IgnoreFileName)
+	}
+	if report.SkippedGenerated > 0 {
+		fmt.Printf("Skipped %d generated files (use --include-generated to include them)\n", report.SkippedGenerated)
+	}
+	if len(report.Errors) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d files failed to parse:\n", len(report.Errors))
+		for _, fileErr := range report.Errors {
+			fmt.Fprintf(os.Stderr, "  %v\n", fileErr)
+		}
+	}
+}
+
+// warnEmbeddingMismatch warns when the projects being searched were indexed
+// with different embedding models from each other or from the configured one,
+// since their vectors are not comparable
+func warnEmbeddingMismatch(projects []metadata.Project, cfg embedder.Config) {
+	byModel := make(map[string][]string)
+	var labels []string
+	for _, project := range projects {
+		if project.EmbeddingModel == "" {
+			continue // indexed before models were tracked
+		}
+		label := app.EmbeddingLabel(project.EmbeddingProvider, project.EmbeddingModel)
+		if _, ok := byModel[label]; !ok {
+			labels = append(labels, label)
+		}
+		byModel[label] = append(byModel[label], project.Name)
+	}
+
+	if len(labels) > 1 {
+		fmt.Fprintf(os.Stderr, "Warning: searched projects were indexed with different embedding models; results may be unreliable:\n")
+		for _, label := range labels {
+			fmt.Fprintf(os.Stderr, "  %s: %s\n", label, formatProjectList(byModel[label]))
+		}
+	}
+
+	current := app.EmbeddingLabel(cfg.Provider, cfg.Model)
+	for _, label := range labels {
+		if label != current {
+			fmt.Fprintf(os.Stderr, "Warning: %s indexed with %s but querying with %s; re-index with --full or run reembed\n",
+				formatProjectList(byModel[label]), label, current)
+		}
+	}
+}
+
 func main() {
 	rootCmd := &cobra.Command{
 		Use:   "vectcode",
@@ -78,13 +144,27 @@ vector store for LLM-powered code understanding.`,
 	}
 
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "", "Path to config file (default: ~/.vectcode/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to use (overrides "+config.ProfileEnv+" and default_profile)")
 
 	rootCmd.AddCommand(indexCmd())
 	rootCmd.AddCommand(queryCmd())
 	rootCmd.AddCommand(listCmd())
 	rootCmd.AddCommand(infoCmd())
 	rootCmd.AddCommand(deleteCmd())
+	rootCmd.AddCommand(deleteFileCmd())
+	rootCmd.AddCommand(reembedCmd())
 	rootCmd.AddCommand(groupCmd())
+	rootCmd.AddCommand(collectionsCmd())
+	rootCmd.AddCommand(diffCmd())
+	rootCmd.AddCommand(embedCmd())
+	rootCmd.AddCommand(benchCmd())
+	rootCmd.AddCommand(warmupCmd())
+	rootCmd.AddCommand(outlineCmd())
+	rootCmd.AddCommand(languagesCmd())
+	rootCmd.AddCommand(filesCmd())
+	rootCmd.AddCommand(maintenanceCmd())
+	rootCmd.AddCommand(serveCmd())
+	rootCmd.AddCommand(versionCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -94,137 +174,214 @@ vector store for LLM-powered code understanding.`,
 
 func indexCmd() *cobra.Command {
 	var (
-		projectPath string
-		projectName string
-		groupName   string
-		description string
-		clean       bool
+		projectPaths []string
+		projectName  string
+		groupName    string
+		description  string
+		clean        bool
+		allPlatforms bool
+		methodSets   bool
+		packageDocs  bool
+		followLinks  bool
+		language     string
+		includeGen   bool
+		fileContext  bool
+		since        string
+		chunkTypes   []string
+		skipSame     bool
+		resume       bool
+		repo         string
+		minLines     int
+		summarize    bool
+		withBlame    bool
+		withDeps     bool
+		depsGroup    string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "index",
 		Short: "Index a code project",
-		Long:  `Parse and index a code project into the vector store`,
+		Long: `Parse and index a Go, Rust, or mixed Go and Rust project into the vector store.
+
+With --repo, a remote repository is cloned one commit deep into a temporary
+directory, indexed, and removed; the project records the URL and the commit
+indexed. Cloning uses git's own credential helpers and SSH keys.
+
+With --with-deps, the Go modules whose packages the project imports are
+resolved with go list and indexed too, each as its own project named
+module@version in the --deps-group group. A module version never changes,
+so one already indexed (e.g. for another project) is not indexed again.
+
+With --with-blame, each chunk records the git author of most of its lines
+and the date of the latest commit touching them, so queries can filter by
+--author. Uncommitted lines are not attributed.
+
+Each file is recorded as soon as its chunks are stored, so an index that is
+interrupted can be finished with --resume: files recorded and unchanged
+since are parsed but not embedded again.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if projectPath == "" {
-				return fmt.Errorf("--path is required")
+			if len(projectPaths) == 0 && repo == "" {
+				return fmt.Errorf("--path or --repo is required")
+			}
+			if len(projectPaths) > 0 && repo != "" {
+				return fmt.Errorf("--path and --repo cannot be used together")
 			}
 			if projectName == "" {
 				return fmt.Errorf("--name is required")
 			}
+			if since != "" && clean {
+				return fmt.Errorf("--since and --full cannot be used together")
+			}
+			if skipSame && clean {
+				return fmt.Errorf("--skip-unchanged and --full cannot be used together")
+			}
+			if resume && (clean || since != "") {
+				return fmt.Errorf("--resume cannot be combined with --full or --since")
+			}
+			if minLines < 0 {
+				return fmt.Errorf("--min-lines cannot be negative")
+			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			fmt.Printf("Indexing project: %s from path: %s\n", projectName, projectPath)
-
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
-			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+			// --chunk-types overrides the configured chunk types
+			if !cmd.Flags().Changed("chunk-types") {
+				chunkTypes = cfg.Index.ChunkTypes
 			}
-			defer metaStore.Close()
-
-			// Initialize components
-			fmt.Println("Initializing embedder...")
-			emb, err := embedder.New(cfg.Embeddings)
+			types, err := chunker.ParseChunkTypes(chunkTypes)
 			if err != nil {
-				return fmt.Errorf("failed to create embedder: %w", err)
+				return err
 			}
-
-			fmt.Println("Initializing vector store...")
-			store, err := vectorstore.New(cfg.ToVectorStoreConfig())
-			if err != nil {
-				return fmt.Errorf("failed to create vector store: %w", err)
+			if !cmd.Flags().Changed("min-lines") {
+				minLines = cfg.Index.MinLines
+			}
+			if !cmd.Flags().Changed("file-context") {
+				fileContext = cfg.Index.FileContext
 			}
-			defer store.Close()
-
-			fmt.Println("Initializing parser...")
-			parser := parser.NewGoParser()
-
-			// Create indexer
-			idx := indexer.New(parser, emb, store)
-
-			ctx := context.Background()
 
-			// Clean re-index: delete existing project first
-			if clean {
-				fmt.Printf("Cleaning existing data for project: %s\n", projectName)
-				if err := idx.DeleteProject(ctx, projectName); err != nil {
-					// Don't fail if project doesn't exist
-					fmt.Printf("Note: Could not delete existing project (may not exist): %v\n", err)
+			if repo != "" {
+				fmt.Printf("Indexing project: %s from repository: %s\n", projectName, repo)
+			} else {
+				fmt.Printf("Indexing project: %s from path: %s\n", projectName, strings.Join(projectPaths, ", "))
+			}
+			if len(types) > 0 {
+				names := make([]string, len(types))
+				for i, t := range types {
+					names[i] = string(t)
 				}
-				// Also delete from metadata store
-				metaStore.DeleteProject(ctx, projectName)
+				fmt.Printf("Indexing chunk types: %s\n", strings.Join(names, ", "))
 			}
 
-			// Run indexing
-			chunkCount, err := idx.IndexProject(ctx, projectPath, projectName)
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
 			if err != nil {
-				return fmt.Errorf("indexing failed: %w", err)
-			}
-
-			// Record metadata
-			now := time.Now()
-			project := &metadata.Project{
-				Name:          projectName,
-				Path:          projectPath,
-				Language:      parser.Language(),
-				Description:   description,
-				ChunkCount:    chunkCount,
-				LastIndexedAt: &now,
+				return err
 			}
-
-			// Get group ID if group specified
-			if groupName != "" {
-				group, err := metaStore.GetGroup(ctx, groupName)
-				if err != nil {
-					// Group doesn't exist, create it
-					group, err = metaStore.CreateGroup(ctx, groupName, "")
-					if err != nil {
-						return fmt.Errorf("failed to create group: %w", err)
-					}
-				}
-				project.GroupID = &group.ID
+			defer a.Close()
+
+			progress := newProgressPrinter(os.Stderr, "embedding")
+			result, err := a.Index(context.Background(), app.IndexOptions{
+				Paths:       projectPaths,
+				Name:        projectName,
+				Group:       groupName,
+				Description: description,
+				Repo:        repo,
+				Language:    language,
+				Parser: parser.Options{
+					AllPlatforms:     allPlatforms,
+					MethodSets:       methodSets,
+					PackageDocs:      packageDocs,
+					FollowSymlinks:   followLinks,
+					IncludeGenerated: includeGen,
+					FileContext:      fileContext,
+				},
+				ChunkTypes:    chunkTypes,
+				MinLines:      minLines,
+				Full:          clean,
+				Since:         since,
+				Summarize:     summarize,
+				SkipUnchanged: skipSame,
+				Resume:        resume,
+				Blame:         withBlame,
+				WithDeps:      withDeps,
+				DepsGroup:     depsGroup,
+				Progress:      progress.Update,
+				Output:        os.Stdout,
+				Warnings:      os.Stderr,
+			})
+			if result != nil {
+				printParseReport(result.Report)
 			}
-
-			// Check if project exists
-			existing, err := metaStore.GetProject(ctx, projectName)
-			if err == nil {
-				// Update existing project
-				project.ID = existing.ID
-				if err := metaStore.UpdateProject(ctx, project); err != nil {
-					return fmt.Errorf("failed to update project metadata: %w", err)
-				}
-			} else {
-				// Create new project
-				if err := metaStore.CreateProject(ctx, project); err != nil {
-					return fmt.Errorf("failed to create project metadata: %w", err)
-				}
+			if errors.Is(err, vectorstore.ErrDimensionMismatch) {
+				return fmt.Errorf("%w\n\nIndex with the embedding model the collection was built with, or set vector_store.collection to a new collection (or vector_store.options.namespace_by_model: true) and re-index with --full", err)
 			}
-
-			return nil
+			return err
 		},
 	}
 
-	cmd.Flags().StringVarP(&projectPath, "path", "p", "", "Path to the project directory (required)")
+	cmd.Flags().StringArrayVarP(&projectPaths, "path", "p", nil, "Path to the project directory (required unless --repo; repeat to index several directories as one project)")
+	cmd.Flags().StringVar(&repo, "repo", "", "Clone and index a remote git repository, URL[@ref] (e.g. https://github.com/org/repo@v1.2.0)")
 	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project (required)")
 	cmd.Flags().StringVarP(&groupName, "group", "g", "", "Group name to organize projects")
 	cmd.Flags().StringVarP(&description, "description", "d", "", "Project description")
-	cmd.Flags().BoolVar(&clean, "clean", false, "Delete existing project data before indexing (ensures no orphaned chunks)")
+	cmd.Flags().BoolVar(&clean, "full", false, "Delete existing project data and re-index from scratch")
+	cmd.Flags().BoolVar(&clean, "clean", false, "Same as --full")
+	cmd.Flags().MarkDeprecated("clean", "use --full")
+	cmd.Flags().StringVar(&language, "lang", parser.AutoLanguage, "Source language to parse ("+languageNames()+", or auto for every supported language; see vectcode languages)")
+	cmd.Flags().BoolVar(&allPlatforms, "all-platforms", false, "Include files excluded by build constraints for the current GOOS/GOARCH")
+	cmd.Flags().BoolVar(&methodSets, "method-sets", false, "Also index one chunk per type listing all of its methods")
+	cmd.Flags().BoolVar(&packageDocs, "package-docs", false, "Also index each Go package doc comment (e.g. doc.go) as a package chunk")
+	cmd.Flags().BoolVar(&includeGen, "include-generated", false, "Index files marked \"// Code generated ... DO NOT EDIT.\" (skipped by default)")
+	cmd.Flags().BoolVar(&fileContext, "file-context", false, "Embed a summary of each chunk's file (package, sibling types and functions, imports) with the chunk (overrides index.file_context)")
+	cmd.Flags().StringSliceVar(&chunkTypes, "chunk-types", nil, "Only index these chunk types, e.g. function,method (overrides index.chunk_types)")
+	cmd.Flags().IntVar(&minLines, "min-lines", 0, "Skip chunks spanning fewer lines unless documented or carrying HTTP/gRPC metadata (overrides index.min_lines)")
+	cmd.Flags().StringVar(&since, "since", "", "Only re-index files changed between this git ref and HEAD (falls back to a full index)")
+	cmd.Flags().BoolVar(&skipSame, "skip-unchanged", false, "Only embed chunks whose text changed since the last index, reusing the stored vectors of the rest")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Finish an interrupted index, skipping the files it already stored that have not changed since")
+	cmd.Flags().BoolVar(&summarize, "summarize", false, "Have the configured llm write a one-sentence summary of each function and type, embedded with its code (one llm call per chunk)")
+	cmd.Flags().BoolVar(&withBlame, "with-blame", false, "Record the git author and last commit date of each chunk, read with git blame")
+	cmd.Flags().BoolVar(&withDeps, "with-deps", false, "Also index the Go modules the project imports, each as a project named module@version (skipped if already indexed)")
+	cmd.Flags().StringVar(&depsGroup, "deps-group", app.DefaultDepsGroup, "Group to index dependencies into with --with-deps")
+	cmd.Flags().BoolVar(&followLinks, "follow-symlinks", false, "Walk into symlinked directories (cycles are detected and skipped)")
 
 	return cmd
 }
 
 func queryCmd() *cobra.Command {
 	var (
-		queryText   string
-		limit       int
-		projectName string
-		groupName   string
+		queryText     string
+		limit         int
+		projectName   string
+		groupName     string
+		jsonOutput    bool
+		showEmbedding bool
+		templatePath  string
+		pathPrefix    string
+		withCallgraph bool
+		offset        int
+		noCode        bool
+		summarize     bool
+		jsonLines     bool
+		symbol        string
+		receiver      string
+		author        string
+		testKind      string
+		excludeTests  []string
+		packages      []string
+		boosts        []string
+		interactive   bool
+		code          string
+		codeFile      string
+		preferNewer   bool
+		paramTypes    []string
+		returnTypes   []string
+		explain       bool
+		format        string
+		showDistance  bool
+		showMetadata  bool
 	)
 
 	cmd := &cobra.Command{
@@ -232,9 +389,58 @@ func queryCmd() *cobra.Command {
 		Short: "Query the code knowledge base",
 		Long:  `Search the indexed codebase using natural language`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if queryText == "" {
+			// --format json and jsonl are the same as --json and --jsonl
+			switch format {
+			case "text", "markdown":
+			case "json":
+				jsonOutput = true
+			case "jsonl":
+				jsonLines = true
+			default:
+				return fmt.Errorf("invalid --format %q (use text, markdown, json, or jsonl)", format)
+			}
+			markdown := format == "markdown"
+			if markdown && (jsonOutput || jsonLines) {
+				return fmt.Errorf("--format markdown cannot be combined with --json or --jsonl")
+			}
+			if markdown && templatePath != "" {
+				return fmt.Errorf("--format markdown cannot be combined with --template")
+			}
+
+			fromCode := code != "" || codeFile != ""
+			if code != "" && codeFile != "" {
+				return fmt.Errorf("--code and --code-file cannot be used together")
+			}
+			if fromCode && (queryText != "" || interactive || summarize) {
+				return fmt.Errorf("--code and --code-file cannot be combined with --query, --interactive, or --summarize")
+			}
+			if queryText == "" && !interactive && !fromCode {
 				return fmt.Errorf("--query is required")
 			}
+			if cmd.Flags().Changed("query") && strings.TrimSpace(queryText) == "" {
+				return fmt.Errorf("--query must not be empty or whitespace")
+			}
+			if interactive && (jsonOutput || jsonLines || summarize) {
+				return fmt.Errorf("--interactive cannot be combined with --json, --jsonl, or --summarize")
+			}
+			if offset < 0 {
+				return fmt.Errorf("--offset cannot be negative")
+			}
+			if jsonOutput && jsonLines {
+				return fmt.Errorf("--json and --jsonl cannot be used together")
+			}
+			if summarize && (jsonOutput || jsonLines) {
+				return fmt.Errorf("--summarize cannot be combined with --json or --jsonl")
+			}
+
+			if testKind != "" {
+				if _, err := chunker.ParseTestKinds([]string{testKind}); err != nil {
+					return fmt.Errorf("invalid --test-kind: %w", err)
+				}
+			}
+			if _, err := chunker.ParseTestKinds(excludeTests); err != nil {
+				return fmt.Errorf("invalid --exclude-test-kind: %w", err)
+			}
 
 			// Can't specify both project and group
 			if projectName != "" && groupName != "" {
@@ -242,45 +448,114 @@ func queryCmd() *cobra.Command {
 			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			ctx := context.Background()
+			// --limit overrides the configured default
+			if !cmd.Flags().Changed("limit") {
+				limit = cfg.Query.EffectiveLimit()
+			}
 
-			fmt.Printf("Querying: %s\n", queryText)
+			// --boost overrides the configured package boosts
+			packageBoosts := cfg.Query.PackageBoosts
+			if len(boosts) > 0 {
+				if packageBoosts, err = query.ParsePackageBoosts(boosts); err != nil {
+					return err
+				}
+			}
 
-			// Initialize components
-			emb, err := embedder.New(cfg.Embeddings)
+			// --template overrides the configured result template
+			if templatePath == "" {
+				templatePath = cfg.Query.ResultTemplate
+			}
+			var formatter *query.ResultFormatter
+			if markdown {
+				formatter, err = query.NewResultFormatter(query.MarkdownResultTemplate)
+			} else if noCode && templatePath == "" {
+				formatter, err = query.NewResultFormatter(query.CompactResultTemplate)
+			} else {
+				formatter, err = query.LoadResultFormatter(templatePath)
+			}
 			if err != nil {
-				return fmt.Errorf("failed to create embedder: %w", err)
+				return err
 			}
+			formatter.ShowDistance = showDistance
+			formatter.ShowMetadata = showMetadata
 
-			store, err := vectorstore.New(cfg.ToVectorStoreConfig())
-			if err != nil {
-				return fmt.Errorf("failed to create vector store: %w", err)
+			ctx := context.Background()
+
+			// Keep stdout clean for JSON and markdown output
+			var status io.Writer = os.Stdout
+			if jsonOutput || jsonLines || markdown {
+				status = os.Stderr
 			}
-			defer store.Close()
 
-			// Create query engine
-			engine := query.NewEngine(emb, store)
+			if fromCode {
+				snippet, err := readSnippet(code, codeFile)
+				if err != nil {
+					return err
+				}
+				if strings.TrimSpace(snippet) == "" {
+					return fmt.Errorf("code snippet is empty")
+				}
+				textFunc, err := app.ChunkTextFunc(cfg.Embeddings)
+				if err != nil {
+					return err
+				}
+				if queryText, err = snippetText(snippet, textFunc); err != nil {
+					return err
+				}
+				fmt.Fprintf(status, "Querying with code snippet (%d lines)\n", strings.Count(strings.TrimRight(snippet, "\n"), "\n")+1)
+			} else if !interactive {
+				fmt.Fprintf(status, "Querying: %s\n", queryText)
+			}
 
-			// Build filters
-			var filters map[string]interface{}
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			// The metadata store resolves groups and checks embedding models
+			metaStore := a.Metadata()
+
+			// Build search options
+			opts := vectorstore.SearchOptions{
+				Limit:             limit,
+				Offset:            offset,
+				PathPrefix:        pathPrefix,
+				ParamTypes:        paramTypes,
+				ReturnTypes:       returnTypes,
+				Symbol:            symbol,
+				Receiver:          receiver,
+				Author:            author,
+				Packages:          packages,
+				PackageBoosts:     packageBoosts,
+				TestKind:          testKind,
+				ExcludeTestKinds:  excludeTests,
+				IncludeEmbeddings: showEmbedding,
+				IncludeCallees:    withCallgraph,
+				PreferRecent:      preferNewer,
+				Explain:           explain,
+			}
+			var searched []metadata.Project
 			if projectName != "" {
-				filters = map[string]interface{}{
-					"project": projectName,
+				// A project with only chunks (from before the metadata store)
+				// is still searched, unless the name looks like a typo
+				project, err := a.Project(ctx, projectName)
+				var notFound *metadata.ProjectNotFoundError
+				if errors.As(err, &notFound) && len(notFound.Suggestions) > 0 {
+					return err
 				}
-				fmt.Printf("Filtering by project: %s\n", projectName)
+				if err == nil {
+					searched = []metadata.Project{*project}
+				}
+				opts.Projects = []string{projectName}
+				fmt.Fprintf(status, "Filtering by project: %s\n", projectName)
 			} else if groupName != "" {
 				// Get projects in the group
-				metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
-				if err != nil {
-					return fmt.Errorf("failed to create metadata store: %w", err)
-				}
-				defer metaStore.Close()
-
 				projects, err := metaStore.GetProjectsByGroup(ctx, groupName)
 				if err != nil {
 					return fmt.Errorf("failed to get projects in group: %w", err)
@@ -296,81 +571,275 @@ func queryCmd() *cobra.Command {
 					projectNames[i] = proj.Name
 				}
 
-				filters = map[string]interface{}{
-					"projects": projectNames,
-				}
-				fmt.Printf("Filtering by group '%s' (%d projects: %s)\n",
+				opts.Projects = projectNames
+				searched = projects
+				fmt.Fprintf(status, "Filtering by group '%s' (%d projects: %s)\n",
 					groupName, len(projectNames), formatProjectList(projectNames))
+			} else {
+				searched, _ = metaStore.ListProjects(ctx, nil)
+			}
+			warnEmbeddingMismatch(searched, cfg.Embeddings)
+			if pathPrefix != "" {
+				fmt.Fprintf(status, "Filtering by path prefix: %s\n", pathPrefix)
+			}
+			if symbol != "" {
+				fmt.Fprintf(status, "Filtering by symbol: %s\n", symbol)
+			}
+			if receiver != "" {
+				fmt.Fprintf(status, "Filtering by receiver: %s\n", receiver)
+			}
+			if author != "" {
+				fmt.Fprintf(status, "Filtering by author: %s\n", author)
 			}
 
-			// Execute query
-			results, err := engine.Query(ctx, queryText, limit, filters)
+			if interactive {
+				session := &repl{app: a, formatter: formatter, opts: opts, noCode: noCode}
+				return session.run(ctx, os.Stdin, os.Stdout)
+			}
+
+			// Execute query; --summarize also has the llm answer from the results
+			var (
+				results []vectorstore.SearchResult
+				answer  string
+			)
+			if summarize {
+				answer, results, err = a.Ask(ctx, queryText, opts)
+			} else {
+				results, err = a.Query(ctx, queryText, opts)
+			}
 			if err != nil {
 				return fmt.Errorf("query failed: %w", err)
 			}
 
-			// Display results
-			fmt.Printf("\nFound %d results:\n\n", len(results))
-			for i, result := range results {
-				chunk := result.Chunk
-				fmt.Printf("=== Result %d (Score: %.4f) ===\n", i+1, result.Score)
-				fmt.Printf("Project: %s\n", chunk.Project)
-				fmt.Printf("File: %s:%d-%d\n", chunk.FilePath, chunk.LineStart, chunk.LineEnd)
-				fmt.Printf("Type: %s %s\n", chunk.ChunkType, chunk.Name)
-				if chunk.DocString != "" {
-					fmt.Printf("Docs: %s\n", chunk.DocString)
+			if noCode {
+				stripCode(results)
+			}
+
+			if jsonOutput || jsonLines {
+				fmt.Fprintf(status, "Estimated tokens: %d\n", vectorstore.TotalTokens(results))
+			}
+
+			if jsonLines {
+				encoder := json.NewEncoder(os.Stdout)
+				for _, result := range results {
+					if err := encoder.Encode(result); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if jsonOutput {
+				if results == nil {
+					results = []vectorstore.SearchResult{}
 				}
-				fmt.Printf("\n%s\n\n", chunk.Code)
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(results)
 			}
 
+			if summarize {
+				fmt.Printf("\n%s\n", answer)
+				if len(results) == 0 {
+					return nil
+				}
+				if markdown {
+					fmt.Printf("\n## Sources\n\n")
+					return formatter.FormatAllFrom(os.Stdout, results, offset+1)
+				}
+				compact, err := query.NewResultFormatter(query.CompactResultTemplate)
+				if err != nil {
+					return err
+				}
+				compact.ShowDistance = showDistance
+				fmt.Printf("\nSources:\n")
+				return compact.FormatAllFrom(os.Stdout, results, offset+1)
+			}
+
+			// Display results
+			if offset > 0 {
+				fmt.Fprintf(status, "\nFound %d results (from %d):\n\n", len(results), offset+1)
+			} else {
+				fmt.Fprintf(status, "\nFound %d results:\n\n", len(results))
+			}
+			if err := formatter.FormatAllFrom(os.Stdout, results, offset+1); err != nil {
+				return err
+			}
+			if len(results) > 0 {
+				fmt.Fprintf(status, "Estimated tokens: %d (docs and code of %d results)\n", vectorstore.TotalTokens(results), len(results))
+			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&queryText, "query", "q", "", "Query text (required)")
-	cmd.Flags().IntVarP(&limit, "limit", "l", 5, "Maximum number of results")
+	cmd.Flags().StringVarP(&queryText, "query", "q", "", "Query text (required unless --interactive, --code, or --code-file)")
+	cmd.Flags().StringVar(&code, "code", "", "Find code similar to this snippet instead of answering a query")
+	cmd.Flags().StringVar(&codeFile, "code-file", "", "Find code similar to the snippet in this file (- reads stdin)")
+	cmd.Flags().BoolVarP(&interactive, "interactive", "i", false, "Read queries from a prompt, keeping the embedder and vector store open between them")
+	cmd.Flags().IntVarP(&limit, "limit", "l", vectorstore.DefaultSearchLimit, "Maximum number of results (overrides query.default_limit)")
+	cmd.Flags().IntVar(&offset, "offset", 0, "Skip this many top results (e.g. --offset 5 for results 6-10)")
 	cmd.Flags().StringVarP(&projectName, "project", "p", "", "Filter by project name")
 	cmd.Flags().StringVarP(&groupName, "group", "g", "", "Filter by group name (searches all projects in group)")
+	cmd.Flags().StringVar(&pathPrefix, "path-prefix", "", "Only return results under this directory (e.g. internal/auth/)")
+	cmd.Flags().StringSliceVar(&packages, "package", nil, "Only return results in these packages (e.g. core,api)")
+	cmd.Flags().StringSliceVar(&boosts, "boost", nil, "Multiply the scores of results in a package, package=factor (e.g. core=1.5; below 1 demotes; overrides query.package_boosts)")
+	cmd.Flags().StringSliceVar(&paramTypes, "param-type", nil, "Only return functions taking every one of these parameter types (e.g. context.Context)")
+	cmd.Flags().StringSliceVar(&returnTypes, "return-type", nil, "Only return functions returning every one of these types (e.g. error)")
+	cmd.Flags().StringVar(&symbol, "symbol", "", "Only return chunks with this name, or Type.Method for a method (e.g. Server.Handle)")
+	cmd.Flags().StringVar(&receiver, "receiver", "", "Only return methods of this type, with a value or pointer receiver (e.g. Server matches *Server)")
+	cmd.Flags().StringVar(&author, "author", "", "Only return chunks mostly written by this git author, by exact name (needs index --with-blame)")
+	cmd.Flags().StringVar(&testKind, "test-kind", "", "Only return Go test functions of this kind: test, benchmark, fuzz, or example")
+	cmd.Flags().StringSliceVar(&excludeTests, "exclude-test-kind", nil, "Leave out Go test functions of these kinds (e.g. example)")
+	cmd.Flags().BoolVar(&jsonOutput, "json", false, "Print results as JSON")
+	cmd.Flags().BoolVar(&jsonLines, "jsonl", false, "Print results as JSON lines, one result per line")
+	cmd.Flags().StringVar(&templatePath, "template", "", "Go text/template file for each result (overrides query.result_template)")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text, markdown (a section per result, for pasting into docs and PRs), json, or jsonl")
+	cmd.Flags().BoolVar(&showDistance, "show-distance", false, "Show each result's raw distance from the query next to its score (lower is closer), for comparing embedding models")
+	cmd.Flags().BoolVar(&showMetadata, "show-metadata", false, "Show each result's package, imports, HTTP endpoints and calls, and gRPC methods (JSON output always includes them)")
+	cmd.Flags().BoolVar(&showEmbedding, "show-embedding", false, "Include each result's stored embedding (summary in text, full vector in JSON)")
+	cmd.Flags().BoolVar(&noCode, "no-code", false, "Omit code from results, listing only score, location, type, and name")
+	cmd.Flags().BoolVar(&summarize, "summarize", false, "Answer in a few sentences with file:line citations, using the configured llm")
+	cmd.Flags().BoolVar(&withCallgraph, "with-callgraph", false, "Include the chunks of the functions each result calls (matched by name within its project)")
+	cmd.Flags().BoolVar(&explain, "explain", false, "Show which query words each result contains, and where (name, doc, code, path)")
+	cmd.Flags().BoolVar(&preferNewer, "prefer-recent", false, "Among results with nearly equal scores, list the most recently modified first")
+
+	return cmd
+}
+
+// stripCode drops the code of results and their callees, for --no-code
+func stripCode(results []vectorstore.SearchResult) {
+	for i := range results {
+		results[i].Chunk.Code = ""
+		for j := range results[i].Callees {
+			results[i].Callees[j].Code = ""
+		}
+	}
+}
+
+// embeddingPreviewValues is how many vector values embedding summaries show
+const embeddingPreviewValues = 8
+
+func embedCmd() *cobra.Command {
+	var (
+		text string
+		full bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "embed",
+		Short: "Embed text with the configured embedder (debugging)",
+		Long:  `Print the embedding of the given text using the configured embedder, to debug retrieval quality or dimension issues`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if text == "" {
+				return fmt.Errorf("--text is required")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			emb, err := embedder.New(cfg.Embeddings, embedder.WithWarnFunc(app.WarnTo(os.Stderr)))
+			if err != nil {
+				return fmt.Errorf("failed to create embedder: %w", err)
+			}
+
+			vec, err := emb.Embed(context.Background(), text)
+			if err != nil {
+				return fmt.Errorf("failed to embed text: %w", err)
+			}
+
+			fmt.Printf("Embedder: %s\n", app.EmbeddingLabel(cfg.Embeddings.Provider, cfg.Embeddings.Model))
+			if emb.Dimensions() > 0 {
+				fmt.Printf("Expected dimensions: %d\n", emb.Dimensions())
+			} else {
+				fmt.Printf("Expected dimensions: unknown (set embeddings.dimensions to check inserts)\n")
+			}
+			fmt.Printf("Embedding: %s\n", embedder.Summarize(vec, embeddingPreviewValues))
+			if emb.Dimensions() > 0 && len(vec) != emb.Dimensions() {
+				fmt.Fprintf(os.Stderr, "Warning: embedding has %d dimensions but the embedder reports %d\n", len(vec), emb.Dimensions())
+			}
+
+			if full {
+				data, err := json.Marshal(vec)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&text, "text", "t", "", "Text to embed (required)")
+	cmd.Flags().BoolVar(&full, "full", false, "Also print the full vector as JSON")
 
 	return cmd
 }
 
+// chunkDrift describes, by project name, each project whose chunk count in
+// the vector store differs from its metadata
+func chunkDrift(projects []metadata.Project, counts map[string]int) map[string]string {
+	drift := make(map[string]string)
+	for _, project := range projects {
+		stored := counts[project.Name]
+		switch {
+		case stored == project.ChunkCount:
+		case stored == 0:
+			drift[project.Name] = "missing from vector store"
+		default:
+			drift[project.Name] = fmt.Sprintf("%d chunks in vector store, %d in metadata", stored, project.ChunkCount)
+		}
+	}
+	return drift
+}
+
 func listCmd() *cobra.Command {
 	var (
 		detailed  bool
 		groupName string
+		verify    bool
+		sortOrder string
 	)
 
 	cmd := &cobra.Command{
 		Use:   "list",
 		Short: "List all indexed projects",
-		Long:  `Display all projects that have been indexed`,
+		Long: `Display all projects that have been indexed.
+
+The list comes from the metadata database. With --verify, each project's
+chunk count is also checked against the vector store, and projects whose
+chunks are missing or differ in number are flagged.
+
+Projects are listed by name; --sort recent lists the most recently indexed
+first, and --sort chunks the largest first.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			sortBy, err := metadata.ParseProjectSort(sortOrder)
+			if err != nil {
+				return fmt.Errorf("invalid --sort: %w", err)
+			}
+
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			ctx := context.Background()
-
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
 
 			// Build filter
-			var filter *metadata.ProjectFilter
-			if groupName != "" {
-				filter = &metadata.ProjectFilter{GroupName: groupName}
-			}
+			filter := &metadata.ProjectFilter{GroupName: groupName, Sort: sortBy}
 
 			// List projects from metadata
-			projects, err := metaStore.ListProjects(ctx, filter)
+			projects, err := a.ListProjects(context.Background(), filter)
 			if err != nil {
-				return fmt.Errorf("failed to list projects: %w", err)
+				return err
 			}
 
 			if len(projects) == 0 {
@@ -382,12 +851,22 @@ func listCmd() *cobra.Command {
 				return nil
 			}
 
+			// Cross-check the metadata against the vector store
+			var drift map[string]string
+			if verify {
+				counts, err := a.ChunkCounts(context.Background(), projects)
+				if err != nil {
+					return err
+				}
+				drift = chunkDrift(projects, counts)
+			}
+
 			if detailed {
 				// Detailed view
 				fmt.Printf("Indexed projects (%d):\n\n", len(projects))
 				for _, project := range projects {
 					fmt.Printf("Name: %s\n", project.Name)
-					fmt.Printf("  Path: %s\n", project.Path)
+					fmt.Printf("  Path: %s\n", strings.Join(project.AllPaths(), ", "))
 					fmt.Printf("  Language: %s\n", project.Language)
 					if project.Description != "" {
 						fmt.Printf("  Description: %s\n", project.Description)
@@ -396,6 +875,9 @@ func listCmd() *cobra.Command {
 						fmt.Printf("  Group: %s\n", project.GroupName)
 					}
 					fmt.Printf("  Chunks: %d\n", project.ChunkCount)
+					if note, ok := drift[project.Name]; ok {
+						fmt.Printf("  Vector store: %s\n", note)
+					}
 					if project.LastIndexedAt != nil {
 						fmt.Printf("  Last indexed: %s\n", formatTimeAgo(*project.LastIndexedAt))
 					} else {
@@ -411,20 +893,28 @@ func listCmd() *cobra.Command {
 					fmt.Printf("Indexed projects (%d):\n", len(projects))
 				}
 				for i, project := range projects {
+					line := fmt.Sprintf("  %d. %s", i+1, project.Name)
 					if project.GroupName != "" {
-						fmt.Printf("  %d. %s [%s]\n", i+1, project.Name, project.GroupName)
-					} else {
-						fmt.Printf("  %d. %s\n", i+1, project.Name)
+						line += fmt.Sprintf(" [%s]", project.GroupName)
+					}
+					if note, ok := drift[project.Name]; ok {
+						line += " (" + note + ")"
 					}
+					fmt.Println(line)
 				}
 			}
 
+			if len(drift) > 0 {
+				fmt.Fprintf(os.Stderr, "Warning: %d of %d projects do not match the vector store; re-index them with --full, or delete them\n", len(drift), len(projects))
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVar(&detailed, "detailed", false, "Show detailed project information")
 	cmd.Flags().StringVarP(&groupName, "group", "g", "", "Filter by group name")
+	cmd.Flags().BoolVar(&verify, "verify", false, "Check each project's chunk count against the vector store and flag mismatches")
+	cmd.Flags().StringVar(&sortOrder, "sort", string(metadata.SortByName), "Order projects by name, recent (last indexed first), or chunks (most first)")
 
 	return cmd
 }
@@ -442,29 +932,42 @@ func infoCmd() *cobra.Command {
 			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
 			ctx := context.Background()
 
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
+			metaStore := a.Metadata()
 
-			// Get project
-			project, err := metaStore.GetProject(ctx, projectName)
+			// Get project, suggesting close names if there is none
+			project, err := a.Project(ctx, projectName)
 			if err != nil {
-				return fmt.Errorf("project not found: %s", projectName)
+				return err
 			}
 
 			// Display project info
 			fmt.Printf("Project: %s\n", project.Name)
-			fmt.Printf("  Path: %s\n", project.Path)
+			fmt.Printf("  Path: %s\n", strings.Join(project.AllPaths(), ", "))
+			if project.Root != "" && project.Root != project.Path {
+				fmt.Printf("  Root: %s\n", project.Root)
+			}
+			if project.Collection != "" {
+				fmt.Printf("  Collection: %s\n", project.Collection)
+			}
+			if project.RepoURL != "" {
+				fmt.Printf("  Repository: %s\n", project.RepoURL)
+				if project.RepoRef != "" {
+					fmt.Printf("  Ref: %s\n", project.RepoRef)
+				}
+				fmt.Printf("  Commit: %s\n", project.RepoCommit)
+			}
 			fmt.Printf("  Language: %s\n", project.Language)
 
 			if project.Description != "" {
@@ -478,6 +981,18 @@ func infoCmd() *cobra.Command {
 			}
 
 			fmt.Printf("  Chunks: %d\n", project.ChunkCount)
+			if len(project.ChunkTypes) > 0 {
+				fmt.Printf("  Chunk types: %s\n", strings.Join(project.ChunkTypes, ", "))
+			} else {
+				fmt.Printf("  Chunk types: all\n")
+			}
+
+			if project.EmbeddingModel != "" && project.EmbeddingDimensions > 0 {
+				fmt.Printf("  Embedding model: %s (%d dimensions)\n",
+					app.EmbeddingLabel(project.EmbeddingProvider, project.EmbeddingModel), project.EmbeddingDimensions)
+			} else if project.EmbeddingModel != "" {
+				fmt.Printf("  Embedding model: %s\n", app.EmbeddingLabel(project.EmbeddingProvider, project.EmbeddingModel))
+			}
 
 			if project.LastIndexedAt != nil {
 				fmt.Printf("  Last indexed: %s (%s)\n",
@@ -512,58 +1027,118 @@ func infoCmd() *cobra.Command {
 }
 
 func deleteCmd() *cobra.Command {
-	var projectName string
+	var (
+		projectName string
+		groupName   string
+		yes         bool
+		deleteGroup bool
+	)
 
 	cmd := &cobra.Command{
 		Use:   "delete",
 		Short: "Delete a project from the index",
-		Long:  `Remove all data for a project from the vector store and metadata`,
+		Long: `Remove all data for a project from the vector store and metadata.
+
+With --group, every project in the group is deleted. The group itself is kept
+(with no projects) unless --delete-group is also given.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if projectName == "" {
-				return fmt.Errorf("--name is required")
+			if projectName == "" && groupName == "" {
+				return fmt.Errorf("--name or --group is required")
+			}
+			if projectName != "" && groupName != "" {
+				return fmt.Errorf("cannot specify both --name and --group")
+			}
+			if deleteGroup && groupName == "" {
+				return fmt.Errorf("--delete-group requires --group")
 			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
-			fmt.Printf("Deleting project: %s\n", projectName)
-
 			ctx := context.Background()
 
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
+			metaStore := a.Metadata()
 
-			// Initialize vector store
-			store, err := vectorstore.New(cfg.ToVectorStoreConfig())
-			if err != nil {
-				return fmt.Errorf("failed to create vector store: %w", err)
+			// Resolve group members before touching the vector store
+			var groupProjects []metadata.Project
+			if groupName != "" {
+				if _, err := metaStore.GetGroup(ctx, groupName); err != nil {
+					return err
+				}
+				groupProjects, err = metaStore.GetProjectsByGroup(ctx, groupName)
+				if err != nil {
+					return fmt.Errorf("failed to get projects in group: %w", err)
+				}
+				if !yes {
+					fmt.Printf("This will delete %d project(s) in group '%s':\n", len(groupProjects), groupName)
+					for _, project := range groupProjects {
+						fmt.Printf("  - %s\n", project.Name)
+					}
+					return fmt.Errorf("refusing to delete without --yes")
+				}
+			}
+
+			if projectName != "" {
+				// A project with only chunks (from before the metadata store)
+				// is still deleted, unless the name looks like a typo
+				var notFound *metadata.ProjectNotFoundError
+				if _, err := a.Project(ctx, projectName); errors.As(err, &notFound) && len(notFound.Suggestions) > 0 {
+					return err
+				}
+
+				fmt.Printf("Deleting project: %s\n", projectName)
+
+				err := a.Delete(ctx, projectName)
+				if errors.Is(err, metadata.ErrProjectNotFound) {
+					// Don't fail if not in metadata (might be old project)
+					fmt.Printf("Note: Project metadata not found (may be from before metadata store)\n")
+				} else if err != nil {
+					return err
+				}
+
+				fmt.Printf("✓ Project '%s' deleted successfully\n", projectName)
+				return nil
 			}
-			defer store.Close()
 
-			// Delete from vector store
-			if err := store.Delete(ctx, projectName); err != nil {
-				return fmt.Errorf("failed to delete project from vector store: %w", err)
+			fmt.Printf("Deleting %d project(s) in group '%s'\n", len(groupProjects), groupName)
+			failed := 0
+			for _, project := range groupProjects {
+				if err := a.Delete(ctx, project.Name); err != nil {
+					fmt.Printf("✗ %s: %v\n", project.Name, err)
+					failed++
+					continue
+				}
+				fmt.Printf("✓ %s\n", project.Name)
 			}
 
-			// Delete from metadata store
-			if err := metaStore.DeleteProject(ctx, projectName); err != nil {
-				// Don't fail if not in metadata (might be old project)
-				fmt.Printf("Note: Project metadata not found (may be from before metadata store)\n")
+			fmt.Printf("Deleted %d of %d project(s)\n", len(groupProjects)-failed, len(groupProjects))
+
+			if deleteGroup {
+				if err := metaStore.DeleteGroup(ctx, groupName); err != nil {
+					return fmt.Errorf("failed to delete group: %w", err)
+				}
+				fmt.Printf("✓ Group '%s' deleted\n", groupName)
 			}
 
-			fmt.Printf("✓ Project '%s' deleted successfully\n", projectName)
+			if failed > 0 {
+				return fmt.Errorf("failed to delete %d project(s)", failed)
+			}
 			return nil
 		},
 	}
 
-	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project to delete (required)")
+	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project to delete")
+	cmd.Flags().StringVarP(&groupName, "group", "g", "", "Delete every project in this group")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Confirm deleting all projects in --group")
+	cmd.Flags().BoolVar(&deleteGroup, "delete-group", false, "Also delete the group itself (with --group)")
 
 	return cmd
 }
@@ -598,19 +1173,19 @@ func groupCreateCmd() *cobra.Command {
 			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
 			ctx := context.Background()
 
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
+			metaStore := a.Metadata()
 
 			// Create group
 			group, err := metaStore.CreateGroup(ctx, name, description)
@@ -640,19 +1215,19 @@ func groupListCmd() *cobra.Command {
 		Long:  `Display all project groups`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
 			ctx := context.Background()
 
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
+			metaStore := a.Metadata()
 
 			// List groups
 			groups, err := metaStore.ListGroups(ctx)
@@ -700,19 +1275,19 @@ func groupDeleteCmd() *cobra.Command {
 			}
 
 			// Load configuration
-			cfg, err := config.LoadOrDefault(getConfigPath())
+			cfg, err := loadConfig()
 			if err != nil {
 				return fmt.Errorf("failed to load config: %w", err)
 			}
 
 			ctx := context.Background()
 
-			// Initialize metadata store
-			metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+			a, err := app.New(cfg)
 			if err != nil {
-				return fmt.Errorf("failed to create metadata store: %w", err)
+				return err
 			}
-			defer metaStore.Close()
+			defer a.Close()
+			metaStore := a.Metadata()
 
 			// Check how many projects are in this group
 			projects, err := metaStore.GetProjectsByGroup(ctx, name)
@@ -734,3 +1309,82 @@ func groupDeleteCmd() *cobra.Command {
 
 	return cmd
 }
+
+func maintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Maintain the local metadata database",
+		Long:  `Housekeeping for long-lived installations`,
+	}
+
+	cmd.AddCommand(maintenanceVacuumCmd())
+
+	return cmd
+}
+
+func maintenanceVacuumCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "vacuum",
+		Short: "Compact the metadata database",
+		Long:  `Remove orphaned file records, then run VACUUM and ANALYZE on the metadata database`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+			metaStore := a.Metadata()
+
+			sizeBefore := fileSize(cfg.Metadata.DBPath)
+
+			orphans, err := metaStore.DeleteOrphanedFiles(ctx)
+			if err != nil {
+				return err
+			}
+			if orphans > 0 {
+				fmt.Printf("Removed %d orphaned file records\n", orphans)
+			}
+
+			if err := metaStore.Vacuum(ctx); err != nil {
+				return err
+			}
+
+			sizeAfter := fileSize(cfg.Metadata.DBPath)
+			fmt.Printf("✓ Vacuumed %s: %s -> %s\n", cfg.Metadata.DBPath, formatBytes(sizeBefore), formatBytes(sizeAfter))
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+// fileSize returns the size of a file, or 0 if it cannot be read
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// formatBytes formats a byte count, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+func outlineCmd() *cobra.Command {
+	var (
+		projectName string
+		jsonLines   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "outline",
+		Short: "List every indexed chunk of a project",
+		Long: `Print the chunks of an indexed project grouped by package and file,
+with their type, name and location. No embedding or semantic search is
+involved, so this is a quick way to see what the index contains.
+
+With --jsonl, each chunk is written as one JSON line as it is fetched, in
+index order rather than grouped, so large projects stream without being
+held in memory.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if projectName == "" {
+				return fmt.Errorf("--name is required")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg)
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			project, err := a.Project(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			// The project's chunks are in the collection it was indexed into
+			store, closeStore, err := a.ProjectStore(project)
+			if err != nil {
+				return err
+			}
+			defer closeStore()
+
+			if jsonLines {
+				encoder := json.NewEncoder(os.Stdout)
+				return store.EachChunkByProject(ctx, projectName, func(chunk chunker.CodeChunk) error {
+					return encoder.Encode(chunk)
+				})
+			}
+
+			chunks, err := store.GetChunksByProject(ctx, projectName)
+			if err != nil {
+				return err
+			}
+			if len(chunks) == 0 {
+				fmt.Printf("No chunks indexed for project '%s'\n", projectName)
+				return nil
+			}
+
+			// Chunks arrive sorted by file and line; bucket files by package
+			// while keeping that order within each package.
+			packages := make(map[string][]string)
+			byFile := make(map[string][]chunker.CodeChunk)
+			for _, chunk := range chunks {
+				if _, seen := byFile[chunk.FilePath]; !seen {
+					packages[chunk.Package] = append(packages[chunk.Package], chunk.FilePath)
+				}
+				byFile[chunk.FilePath] = append(byFile[chunk.FilePath], chunk)
+			}
+
+			names := make([]string, 0, len(packages))
+			for name := range packages {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Printf("Project: %s (%d chunks)\n", projectName, len(chunks))
+			for _, pkg := range names {
+				label := pkg
+				if label == "" {
+					label = "(no package)"
+				}
+				fmt.Printf("\npackage %s\n", label)
+
+				for _, file := range packages[pkg] {
+					fmt.Printf("  %s\n", file)
+					for _, chunk := range byFile[file] {
+						fmt.Printf("    %-10s %-40s %s:%d\n", chunk.ChunkType, chunk.QualifiedName(), chunk.FilePath, chunk.LineStart)
+					}
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project (required)")
+	cmd.Flags().BoolVar(&jsonLines, "jsonl", false, "Stream chunks as JSON lines, one chunk per line")
+
+	return cmd
+}
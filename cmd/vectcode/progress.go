@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// progressWindow is how far back the rolling embedding rate looks
+const progressWindow = 10 * time.Second
+
+// progressInterval is how often progress is printed when not on a terminal
+const progressInterval = 5 * time.Second
+
+type progressSample struct {
+	at   time.Time
+	done int
+}
+
+// progressPrinter renders "embedding N/M chunks" with a rolling rate and ETA.
+// On a terminal it redraws a single line; otherwise it prints a line
+// periodically so logs stay readable.
+type progressPrinter struct {
+	out       *os.File
+	tty       bool
+	label     string
+	samples   []progressSample
+	lastPrint time.Time
+}
+
+func newProgressPrinter(out *os.File, label string) *progressPrinter {
+	return &progressPrinter{
+		out:   out,
+		tty:   isTerminal(out),
+		label: label,
+	}
+}
+
+// Update records progress and prints it if due
+func (p *progressPrinter) Update(done, total int) {
+	now := time.Now()
+	p.samples = append(p.samples, progressSample{at: now, done: done})
+	for len(p.samples) > 2 && now.Sub(p.samples[0].at) > progressWindow {
+		p.samples = p.samples[1:]
+	}
+
+	finished := done >= total
+	if !p.tty && !finished && now.Sub(p.lastPrint) < progressInterval {
+		return
+	}
+	p.lastPrint = now
+
+	line := fmt.Sprintf("%s %d/%d chunks", p.label, done, total)
+	if rate := p.rate(); rate > 0 {
+		line += fmt.Sprintf(" (%.1f/s", rate)
+		if !finished {
+			eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+			line += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+		}
+		line += ")"
+	}
+
+	if p.tty {
+		fmt.Fprintf(p.out, "\r\033[K%s", line)
+		if finished {
+			fmt.Fprintln(p.out)
+		}
+	} else {
+		fmt.Fprintln(p.out, line)
+	}
+}
+
+// rate returns chunks per second over the rolling window
+func (p *progressPrinter) rate() float64 {
+	if len(p.samples) < 2 {
+		return 0
+	}
+	first := p.samples[0]
+	last := p.samples[len(p.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(last.done-first.done) / elapsed
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
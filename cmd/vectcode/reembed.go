@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/indexer"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+func reembedCmd() *cobra.Command {
+	var (
+		projectName    string
+		fromCollection string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "reembed",
+		Short: "Recompute a project's vectors with the configured embedding model",
+		Long: `Re-embed the chunks already stored for a project with the configured
+embedding model, without parsing its source (which need not be on disk).
+
+A collection holds vectors of one dimension. When the new model's dimension
+differs, point vector_store.collection at a new collection and pass the old
+one with --from-collection; the chunks are read from it and written to the
+new one. With vector_store.options.namespace_by_model each model already has
+its own collection, and the chunks are read from the one the project was
+last indexed into.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if projectName == "" {
+				return fmt.Errorf("--name is required")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			ctx := context.Background()
+
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+			metaStore := a.Metadata()
+
+			project, err := a.Project(ctx, projectName)
+			if err != nil {
+				return err
+			}
+
+			emb, err := a.Embedder()
+			if err != nil {
+				return err
+			}
+
+			vsConfig := cfg.ToVectorStoreConfigFor(emb)
+			collection, err := vsConfig.CollectionName()
+			if err != nil {
+				return err
+			}
+			if fromCollection == "" && project.Collection != collection {
+				fromCollection = project.Collection
+			}
+			store, err := a.VectorStore()
+			if errors.Is(err, vectorstore.ErrDimensionMismatch) {
+				return fmt.Errorf("%w\n\nSet vector_store.collection to a new collection and re-run with --from-collection %s", err, collection)
+			}
+			if err != nil {
+				return err
+			}
+
+			source := store
+			if fromCollection != "" && fromCollection != collection {
+				var closeSource func()
+				source, closeSource, err = a.OpenCollection(fromCollection)
+				if err != nil {
+					return fmt.Errorf("failed to open collection %s: %w", fromCollection, err)
+				}
+				defer closeSource()
+			}
+
+			// Chunks are rebuilt from the store, so every field the text
+			// embeds must have been stored with them
+			unstored, err := app.UnstoredTextFields(cfg)
+			if err != nil {
+				return err
+			}
+			if len(unstored) > 0 {
+				return fmt.Errorf("the embedded text uses %s, which vector_store.options.metadata_fields leaves out of the store; re-index project %s with --full instead",
+					strings.Join(unstored, ", "), projectName)
+			}
+
+			textFunc, err := app.ChunkTextFunc(cfg.Embeddings)
+			if err != nil {
+				return err
+			}
+			if cfg.Embeddings.ProjectContext {
+				textFunc = chunker.WithProjectContext(textFunc, project.GroupName, project.Description)
+			}
+
+			progress := newProgressPrinter(os.Stderr, "embedding")
+			idx := indexer.New(nil, emb, store,
+				indexer.WithProgress(progress.Update),
+				indexer.WithTextFunc(textFunc),
+				indexer.WithBatchSize(cfg.Embeddings.BatchSize),
+			)
+
+			count, err := idx.Reembed(ctx, source, projectName)
+			if err != nil {
+				return fmt.Errorf("re-embedding failed: %w", err)
+			}
+
+			// The code was not re-read, so the last-indexed time stays as is
+			project.ChunkCount = count
+			project.EmbeddingProvider = cfg.Embeddings.Provider
+			project.EmbeddingModel = cfg.Embeddings.Model
+			project.EmbeddingDimensions = emb.Dimensions()
+			project.Collection = collection
+			project.TextContext = reembedTextContext(project.TextContext, cfg.Embeddings.ProjectContext)
+			if err := metaStore.UpdateProject(ctx, project); err != nil {
+				return fmt.Errorf("failed to update project metadata: %w", err)
+			}
+
+			fmt.Printf("Successfully re-embedded project: %s (%d chunks, %s)\n",
+				projectName, count, app.EmbeddingLabel(cfg.Embeddings.Provider, cfg.Embeddings.Model))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&projectName, "name", "n", "", "Name of the project (required)")
+	cmd.Flags().StringVar(&fromCollection, "from-collection", "", "Read the chunks from this collection instead of vector_store.collection")
+
+	return cmd
+}
+
+// reembedTextContext updates a project's recorded text context for a
+// re-embed, which adds or drops the project context but keeps the context
+// the parser attached to the stored chunks
+func reembedTextContext(recorded []string, projectContext bool) []string {
+	var parts []string
+	if projectContext {
+		parts = append(parts, metadata.TextContextProject)
+	}
+	for _, part := range recorded {
+		if part != metadata.TextContextProject {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// replHelp lists the commands of the interactive query prompt
+const replHelp = `Type a query to search, or a command:
+  :limit N          show N results per query
+  :offset N         skip the top N results
+  :project [NAME]   search only NAME, or every project without a name
+  :path [PREFIX]    only return results under PREFIX, or anywhere without one
+  :help             show this help
+  :quit             exit (also :exit or end of input)
+`
+
+// repl answers queries read line by line, reusing one engine (and so one
+// embedder and vector store connection) for the whole session
+type repl struct {
+	app       *app.App
+	formatter *query.ResultFormatter
+	opts      vectorstore.SearchOptions
+	noCode    bool
+}
+
+// run reads lines from in until :quit or end of input. Query errors are
+// printed and the session continues.
+func (r *repl) run(ctx context.Context, in io.Reader, out io.Writer) error {
+	fmt.Fprint(out, replHelp)
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "\n> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, ":"):
+			quit, err := r.command(line, out)
+			if err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+			}
+			if quit {
+				return nil
+			}
+		default:
+			if err := r.query(ctx, line, out); err != nil {
+				fmt.Fprintf(out, "Error: %v\n", err)
+			}
+		}
+	}
+}
+
+// command applies a ":" command and reports whether the session should end
+func (r *repl) command(line string, out io.Writer) (bool, error) {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case "quit", "exit", "q":
+		return true, nil
+	case "help", "h":
+		fmt.Fprint(out, replHelp)
+	case "limit", "offset":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 || (name == "limit" && n == 0) {
+			return false, fmt.Errorf(":%s expects a positive number", name)
+		}
+		if name == "limit" {
+			r.opts.Limit = n
+		} else {
+			r.opts.Offset = n
+		}
+		fmt.Fprintf(out, "%s: %d\n", name, n)
+	case "project":
+		r.opts.Projects = nil
+		if arg != "" {
+			r.opts.Projects = []string{arg}
+			fmt.Fprintf(out, "project: %s\n", arg)
+		} else {
+			fmt.Fprintf(out, "project: all\n")
+		}
+	case "path":
+		r.opts.PathPrefix = arg
+		if arg != "" {
+			fmt.Fprintf(out, "path prefix: %s\n", arg)
+		} else {
+			fmt.Fprintf(out, "path prefix: none\n")
+		}
+	default:
+		return false, fmt.Errorf("unknown command :%s (type :help)", name)
+	}
+	return false, nil
+}
+
+// query runs one search with the session's options and prints the results
+func (r *repl) query(ctx context.Context, queryText string, out io.Writer) error {
+	results, stats, err := r.app.QueryWithStats(ctx, queryText, r.opts)
+	if err != nil {
+		return err
+	}
+	if r.noCode {
+		stripCode(results)
+	}
+
+	fmt.Fprintf(out, "\nFound %d results in %s:\n\n", len(results), stats.Total().Round(time.Millisecond))
+	if err := r.formatter.FormatAllFrom(out, results, r.opts.Offset+1); err != nil {
+		return err
+	}
+	if len(results) > 0 {
+		fmt.Fprintf(out, "Estimated tokens: %d\n", vectorstore.TotalTokens(results))
+	}
+	return nil
+}
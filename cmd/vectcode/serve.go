@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/httpapi"
+)
+
+// shutdownTimeout bounds how long serve waits for requests in flight after
+// a terminating signal
+const shutdownTimeout = 30 * time.Second
+
+func serveCmd() *cobra.Command {
+	var addr string
+	var allowHosts []string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve search over a REST API",
+		Long: `Serve the indexed projects over HTTP with JSON requests and responses:
+
+  POST   /search          search; the body holds the query, limit and filters
+  POST   /ask             answer a question with the configured llm
+  GET    /projects        list projects (?group= to filter, ?sort= to order)
+  DELETE /projects/{name} delete a project and its chunks
+
+Errors are answered with a status for their kind, such as 400 for an
+invalid request, 404 for an unknown project and 503 when the embedder or
+vector store is unreachable, and a JSON body {"error": "..."}.
+
+The server has no authentication, and DELETE /projects/{name} deletes
+indexed projects, so it listens on localhost only by default. An --addr
+other than a loopback address, such as :8080, lets anyone who can reach it
+on the network search and delete projects.
+
+Requests must name localhost, a loopback address, or the host of --addr in
+their Host header, so web pages cannot reach the server through DNS
+rebinding; --allow-host accepts another name, such as the one the server
+is reached at when listening on every interface.
+
+Interrupting the server lets requests in flight finish before it exits.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			a, err := app.New(cfg, app.WithWarnings(os.Stderr))
+			if err != nil {
+				return err
+			}
+			defer a.Close()
+
+			handler, err := httpapi.New(a, httpapi.WithAllowedHosts(append(allowHosts, listenHost(addr))...))
+			if err != nil {
+				return err
+			}
+
+			server := &http.Server{
+				Addr:              addr,
+				Handler:           handler,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			serveErr := make(chan error, 1)
+			go func() {
+				serveErr <- server.ListenAndServe()
+			}()
+			fmt.Fprintf(os.Stderr, "Serving on %s\n", addr)
+			if !isLoopback(addr) {
+				fmt.Fprintf(os.Stderr, "Warning: %s is reachable from the network, and the API has no authentication; anyone who can reach it can delete projects\n", addr)
+			}
+
+			select {
+			case err := <-serveErr:
+				return fmt.Errorf("server failed: %w", err)
+			case <-ctx.Done():
+			}
+
+			fmt.Fprintln(os.Stderr, "Shutting down...")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				return fmt.Errorf("failed to shut down server: %w", err)
+			}
+			if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return fmt.Errorf("server failed: %w", err)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", "127.0.0.1:8080", "Address to listen on; a non-loopback address exposes unauthenticated project deletion")
+	cmd.Flags().StringArrayVar(&allowHosts, "allow-host", nil, "Host name to accept requests for besides localhost and the --addr host (repeatable)")
+
+	return cmd
+}
+
+// isLoopback reports whether addr, a host:port listen address, only accepts
+// connections from this machine
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// listenHost returns the host of a listen address, or "" when it listens on
+// every interface (":8080", "0.0.0.0:8080") and names no host
+func listenHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return ""
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsUnspecified() {
+		return ""
+	}
+	return host
+}
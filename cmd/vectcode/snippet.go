@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/parser"
+)
+
+// readSnippet returns the code given with --code, or read from --code-file
+// ("-" reads stdin)
+func readSnippet(code, codeFile string) (string, error) {
+	if codeFile == "" {
+		return code, nil
+	}
+
+	var data []byte
+	var err error
+	if codeFile == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(codeFile)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read code snippet: %w", err)
+	}
+	return string(data), nil
+}
+
+// snippetText renders a code snippet the way indexed chunks are rendered
+// for embedding, so it lands in the same space as their vectors. A snippet
+// holding a single Go or Rust declaration is parsed into a chunk to get the
+// same header (signature, fields, name); anything else is embedded as code.
+func snippetText(code string, textFunc chunker.TextFunc) (string, error) {
+	chunk := chunker.CodeChunk{Code: code}
+	if chunks, err := parser.NewGoParser().ParseSource([]byte("package snippet\n"+code), "snippet.go", "", time.Time{}); err == nil && len(chunks) == 1 {
+		chunk = chunks[0]
+		chunk.Package = ""
+	} else if chunks, err := parser.NewRustParser().ParseSource([]byte(code), "snippet.rs", "", time.Time{}); err == nil && len(chunks) == 1 {
+		chunk = chunks[0]
+		chunk.Package = ""
+	}
+
+	chunk.Sanitize()
+	return textFunc(&chunk)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/config"
+	"github.com/jayzheng/vectcode/pkg/embedder"
+)
+
+func versionCmd() *cobra.Command {
+	var verbose bool
+
+	cmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print the version, and with --verbose the configured backends",
+		Long: `Print the vectcode version and build. With --verbose, also print the
+configured embedder, vector store, metadata database, and llm, for bug
+reports. Nothing is contacted and no secrets are printed.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fmt.Printf("vectcode %s\n", version)
+			fmt.Printf("  Go: %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+			if revision := buildRevision(); revision != "" {
+				fmt.Printf("  Revision: %s\n", revision)
+			}
+			if !verbose {
+				return nil
+			}
+
+			path := getConfigPath()
+			cfg := config.DefaultConfig()
+			if _, err := os.Stat(path); err == nil {
+				if cfg, err = config.LoadProfile(path, profile); err != nil {
+					return fmt.Errorf("failed to load config: %w", err)
+				}
+				fmt.Printf("  Config: %s\n", path)
+				if cfg.Profile != "" {
+					fmt.Printf("  Profile: %s\n", cfg.Profile)
+				}
+			} else {
+				fmt.Printf("  Config: %s (not found, using defaults)\n", path)
+			}
+
+			fmt.Printf("  Embedder: %s\n", backendLabel(cfg.Embeddings.Provider, cfg.Embeddings.Model, cfg.Embeddings.Endpoint))
+			dimensions := "unknown"
+			if emb, err := embedder.New(cfg.Embeddings, embedder.WithWarnFunc(app.WarnTo(os.Stderr))); err != nil {
+				dimensions = fmt.Sprintf("unknown (%v)", err)
+			} else if emb.Dimensions() > 0 {
+				dimensions = fmt.Sprintf("%d", emb.Dimensions())
+			}
+			fmt.Printf("  Embedding dimensions: %s\n", dimensions)
+			if fallback := cfg.Embeddings.Fallback; fallback != nil {
+				fmt.Printf("  Fallback embedder: %s\n", backendLabel(fallback.Provider, fallback.Model, fallback.Endpoint))
+			}
+
+			vsConfig := cfg.ToVectorStoreConfig()
+			collection, err := vsConfig.CollectionName()
+			if err != nil {
+				collection = fmt.Sprintf("unknown (%v)", err)
+			}
+			fmt.Printf("  Vector store: %s at %s, collection %s\n", vsConfig.Type, vsConfig.Endpoint(), collection)
+			fmt.Printf("  Metadata DB: %s\n", cfg.Metadata.DBPath)
+			if cfg.LLM.Provider != "" {
+				fmt.Printf("  LLM: %s\n", backendLabel(cfg.LLM.Provider, cfg.LLM.Model, cfg.LLM.Endpoint))
+			} else {
+				fmt.Printf("  LLM: (none)\n")
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Also print the configured embedder, vector store, metadata database, and llm")
+
+	return cmd
+}
+
+// backendLabel formats a provider and model with the endpoint, if one is set
+func backendLabel(provider, model, endpoint string) string {
+	if endpoint == "" {
+		return app.EmbeddingLabel(provider, model)
+	}
+	return fmt.Sprintf("%s (%s)", app.EmbeddingLabel(provider, model), endpoint)
+}
+
+// buildRevision returns the VCS revision the binary was built from, marked
+// "-dirty" for modified trees, or "" if it was not recorded
+func buildRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	var revision, modified string
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
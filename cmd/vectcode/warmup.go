@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/indexer"
+)
+
+func warmupCmd() *cobra.Command {
+	var (
+		count     int
+		batchSize int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "warmup",
+		Short: "Load the embedding model and measure its throughput",
+		Long: `Embed a batch of synthetic code snippets with the configured embedder and
+report how long the first request took, the vectors embedded per second,
+and the vector length.
+
+The first request makes Ollama load the model, which can take long enough
+to time out in the middle of a large index; running warmup beforehand
+loads it up front. It is also a quick check that the embedder config works.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if count < 1 {
+				return fmt.Errorf("--count must be at least 1")
+			}
+
+			// Load configuration
+			cfg, err := loadConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %w", err)
+			}
+
+			// --batch-size overrides the configured batch size
+			if !cmd.Flags().Changed("batch-size") {
+				batchSize = cfg.Embeddings.BatchSize
+			}
+			if batchSize < 1 {
+				batchSize = indexer.DefaultBatchSize
+			}
+
+			emb, err := embedder.New(cfg.Embeddings, embedder.WithWarnFunc(app.WarnTo(os.Stderr)))
+			if err != nil {
+				return fmt.Errorf("failed to create embedder: %w", err)
+			}
+
+			ctx := context.Background()
+			fmt.Printf("Embedder: %s\n", app.EmbeddingLabel(cfg.Embeddings.Provider, cfg.Embeddings.Model))
+
+			// The first request includes loading the model
+			start := time.Now()
+			vec, err := emb.Embed(ctx, warmupText(0))
+			if err != nil {
+				return fmt.Errorf("failed to embed text: %w", err)
+			}
+			fmt.Printf("First request: %s (includes loading the model)\n", time.Since(start).Round(time.Millisecond))
+			fmt.Printf("Dimensions: %d\n", len(vec))
+			if emb.Dimensions() > 0 && len(vec) != emb.Dimensions() {
+				fmt.Fprintf(os.Stderr, "Warning: embedding has %d dimensions but the embedder reports %d\n", len(vec), emb.Dimensions())
+			}
+
+			texts := make([]string, count)
+			for i := range texts {
+				texts[i] = warmupText(i + 1)
+			}
+
+			var batchTimes []time.Duration
+			start = time.Now()
+			for from := 0; from < len(texts); from += batchSize {
+				to := min(from+batchSize, len(texts))
+				batchStart := time.Now()
+				if _, err := emb.EmbedBatch(ctx, texts[from:to]); err != nil {
+					return fmt.Errorf("failed to embed batch [%d:%d]: %w", from, to, err)
+				}
+				batchTimes = append(batchTimes, time.Since(batchStart))
+			}
+			elapsed := time.Since(start)
+
+			fmt.Printf("Embedded %d texts in %d batches of up to %d: %s\n", count, len(batchTimes), batchSize, elapsed.Round(time.Millisecond))
+			fmt.Printf("Throughput: %.1f vectors/sec\n", float64(count)/elapsed.Seconds())
+			fmt.Printf("Batch latency: p50 %-8s p95 %s\n", percentile(batchTimes, 50), percentile(batchTimes, 95))
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&count, "count", 64, "Number of synthetic texts to embed")
+	cmd.Flags().IntVar(&batchSize, "batch-size", indexer.DefaultBatchSize, "Texts per embedding request (overrides embeddings.batch_size)")
+
+	return cmd
+}
+
+// warmupText returns the i-th synthetic snippet, shaped like a small
+// function chunk. Each is distinct so no layer can serve it from a cache.
+func warmupText(i int) string {
+	return fmt.Sprintf(`Signature: func handleItem%[1]d(w http.ResponseWriter, r *http.Request)
+
+handleItem%[1]d loads item %[1]d and writes it as JSON.
+
+Type: function
+Name: handleItem%[1]d
+
+Code:
+func handleItem%[1]d(w http.ResponseWriter, r *http.Request) {
+	item, err := store.Get(r.Context(), %[1]d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(item)
+}`, i)
+}
@@ -0,0 +1,376 @@
+// Package app wires the embedder, vector store, metadata store, and query
+// engine together from a config, so other Go programs can index and search
+// code the way the vectcode command does.
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jayzheng/vectcode/pkg/config"
+	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/llm"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/rerank"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// App owns the components built from a config. The metadata store is
+// opened by New; the embedder, vector store, and query engine on first use,
+// so work that only reads metadata needs neither an API key nor a running
+// Chroma. Close releases them all.
+type App struct {
+	cfg       *config.Config
+	metaStore metadata.Store
+
+	embedder embedder.Embedder
+	store    vectorstore.VectorStore
+	llm      llm.Client
+	engine   *query.Engine
+
+	warnings io.Writer // see WithWarnings
+}
+
+// Option configures an App
+type Option func(*App)
+
+// WithWarnings writes the problems the embedder works around, such as
+// truncating an over-long text or switching to the fallback, to w. They are
+// discarded by default.
+func WithWarnings(w io.Writer) Option {
+	return func(a *App) {
+		a.warnings = w
+	}
+}
+
+// New creates an App from cfg, which should already be validated (as
+// config.Load does)
+func New(cfg *config.Config, opts ...Option) (*App, error) {
+	metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata store: %w", err)
+	}
+	a := &App{cfg: cfg, metaStore: metaStore, warnings: io.Discard}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.warnings == nil {
+		a.warnings = io.Discard
+	}
+	return a, nil
+}
+
+// Close closes the vector store, if it was opened, and the metadata store
+func (a *App) Close() error {
+	var errs []error
+	if a.store != nil {
+		errs = append(errs, a.store.Close())
+	}
+	errs = append(errs, a.metaStore.Close())
+	return errors.Join(errs...)
+}
+
+// WarnTo returns an embedder.WarnFunc that writes each warning to w as a
+// "Warning: ..." line
+func WarnTo(w io.Writer) embedder.WarnFunc {
+	return func(message string) {
+		fmt.Fprintf(w, "Warning: %s\n", message)
+	}
+}
+
+// EmbeddingLabel describes an embedding model for messages, e.g.
+// "ollama/bge-m3"
+func EmbeddingLabel(provider, model string) string {
+	return provider + "/" + model
+}
+
+// Config returns the config the App was created from
+func (a *App) Config() *config.Config {
+	return a.cfg
+}
+
+// Embedder creates the configured embedder on first use
+func (a *App) Embedder() (embedder.Embedder, error) {
+	if a.embedder != nil {
+		return a.embedder, nil
+	}
+	emb, err := embedder.New(a.cfg.Embeddings, embedder.WithWarnFunc(WarnTo(a.warnings)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create embedder: %w", err)
+	}
+	a.embedder = emb
+	return emb, nil
+}
+
+// Metadata returns the metadata store
+func (a *App) Metadata() metadata.Store {
+	return a.metaStore
+}
+
+// VectorStore opens the vector store on first use. It fails with
+// vectorstore.ErrDimensionMismatch if the collection holds vectors of
+// another length than the embedder's.
+func (a *App) VectorStore() (vectorstore.VectorStore, error) {
+	if a.store != nil {
+		return a.store, nil
+	}
+	emb, err := a.Embedder()
+	if err != nil {
+		return nil, err
+	}
+	store, err := vectorstore.New(a.cfg.ToVectorStoreConfigFor(emb))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vector store: %w", err)
+	}
+	a.store = store
+	return store, nil
+}
+
+// Engine returns the query engine, opening the vector store on first use.
+// Results are cached per query.cache_size. The engine can only Summarize
+// once Ask has created the llm client.
+func (a *App) Engine() (*query.Engine, error) {
+	if a.engine != nil {
+		return a.engine, nil
+	}
+	store, err := a.VectorStore()
+	if err != nil {
+		return nil, err
+	}
+	opts, err := EngineOptions(a.cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, query.WithCodeReader(CodeReader(a.metaStore)))
+	a.engine = query.New(a.embedder, store, a.llm, opts...)
+	return a.engine, nil
+}
+
+// EngineOptions configures a query engine from cfg: its result cache and,
+// if a reranker is configured, reranking
+func EngineOptions(cfg *config.Config) ([]query.Option, error) {
+	opts := []query.Option{query.WithCache(cfg.Query.CacheSize, cfg.Query.CacheTTL)}
+	if cfg.Rerank.Provider == "" {
+		return opts, nil
+	}
+	reranker, err := rerank.New(cfg.Rerank)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reranker: %w", err)
+	}
+	return append(opts, query.WithReranker(reranker, cfg.Rerank.Overfetch)), nil
+}
+
+// LLM creates the configured llm client on first use. It is only created
+// when asked for, so a missing API key does not break plain queries.
+func (a *App) LLM() (llm.Client, error) {
+	if a.llm != nil {
+		return a.llm, nil
+	}
+	client, err := llm.New(a.cfg.LLM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create llm client: %w", err)
+	}
+	a.llm = client
+	return client, nil
+}
+
+// Query searches for queryText. Results stored without code
+// (vector_store.options.store_code: false) get it from the files on disk.
+func (a *App) Query(ctx context.Context, queryText string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	results, _, err := a.QueryWithStats(ctx, queryText, opts)
+	return results, err
+}
+
+// QueryWithStats runs Query and also reports how long each stage took
+func (a *App) QueryWithStats(ctx context.Context, queryText string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, query.QueryStats, error) {
+	engine, err := a.Engine()
+	if err != nil {
+		return nil, query.QueryStats{}, err
+	}
+	return engine.QueryWithStats(ctx, queryText, opts)
+}
+
+// Ask answers a question in a few sentences with the configured llm, citing
+// the results it was drawn from, which are returned too. It fails with
+// llm.ErrNotConfigured when no llm is configured.
+func (a *App) Ask(ctx context.Context, question string, opts vectorstore.SearchOptions) (string, []vectorstore.SearchResult, error) {
+	if a.llm == nil {
+		if _, err := a.LLM(); err != nil {
+			return "", nil, err
+		}
+		a.engine = nil
+	}
+
+	results, err := a.Query(ctx, question, opts)
+	if err != nil {
+		return "", nil, err
+	}
+	answer, err := a.engine.Summarize(ctx, question, results)
+	if err != nil {
+		return "", nil, err
+	}
+	return answer, results, nil
+}
+
+// ListProjects lists the indexed projects matching filter (all if nil)
+func (a *App) ListProjects(ctx context.Context, filter *metadata.ProjectFilter) ([]metadata.Project, error) {
+	projects, err := a.metaStore.ListProjects(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list projects: %w", err)
+	}
+	return projects, nil
+}
+
+// Project looks up a project by its exact name. If there is none, it fails
+// with a *metadata.ProjectNotFoundError suggesting the projects with close
+// names, such as the same name in another case or with a typo.
+func (a *App) Project(ctx context.Context, name string) (*metadata.Project, error) {
+	project, err := a.metaStore.GetProject(ctx, name)
+	if !errors.Is(err, metadata.ErrProjectNotFound) {
+		return project, err
+	}
+	notFound := &metadata.ProjectNotFoundError{Name: name}
+	if projects, listErr := a.metaStore.ListProjects(ctx, nil); listErr == nil {
+		names := make([]string, len(projects))
+		for i, p := range projects {
+			names[i] = p.Name
+		}
+		notFound.Suggestions = metadata.SuggestNames(name, names)
+	}
+	return nil, notFound
+}
+
+// ChunkCounts returns the number of chunks the vector store holds for each
+// project, by name, to check the metadata against. Each project is counted
+// in the collection it was indexed into.
+func (a *App) ChunkCounts(ctx context.Context, projects []metadata.Project) (map[string]int, error) {
+	byCollection := make(map[string][]*metadata.Project)
+	for i := range projects {
+		project := &projects[i]
+		byCollection[project.Collection] = append(byCollection[project.Collection], project)
+	}
+
+	counts := make(map[string]int, len(projects))
+	for _, inCollection := range byCollection {
+		store, closeStore, err := a.ProjectStore(inCollection[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, project := range inCollection {
+			count, err := store.CountChunks(ctx, project.Name)
+			if err != nil {
+				closeStore()
+				return nil, fmt.Errorf("failed to count chunks of project %s: %w", project.Name, err)
+			}
+			counts[project.Name] = count
+		}
+		closeStore()
+	}
+	return counts, nil
+}
+
+// Delete removes a project's chunks from the vector store and its metadata.
+// The chunks are deleted from the collection the project was indexed into,
+// which after a model switch may not be the configured one. It fails with
+// metadata.ErrProjectNotFound if only the chunks existed.
+func (a *App) Delete(ctx context.Context, projectName string) error {
+	project, err := a.metaStore.GetProject(ctx, projectName)
+	if err != nil && !errors.Is(err, metadata.ErrProjectNotFound) {
+		return fmt.Errorf("failed to get project metadata: %w", err)
+	}
+	store, closeStore, err := a.ProjectStore(project)
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	if err := store.Delete(ctx, projectName); err != nil {
+		return fmt.Errorf("failed to delete project from vector store: %w", err)
+	}
+	return a.metaStore.DeleteProject(ctx, projectName)
+}
+
+// Collections lists the vector store's collections. It fails if the store
+// does not keep chunks in collections.
+func (a *App) Collections(ctx context.Context) ([]vectorstore.CollectionInfo, error) {
+	store, closeStore, err := a.storeWithoutEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore()
+
+	manager, ok := store.(vectorstore.CollectionManager)
+	if !ok {
+		return nil, fmt.Errorf("the %s vector store has no collections to manage", a.cfg.VectorStore.Type)
+	}
+	return manager.ListCollections(ctx)
+}
+
+// DeleteCollection deletes a vector store collection with every chunk in
+// it. Project metadata is left as is.
+func (a *App) DeleteCollection(ctx context.Context, name string) error {
+	store, closeStore, err := a.storeWithoutEmbedder()
+	if err != nil {
+		return err
+	}
+	defer closeStore()
+
+	manager, ok := store.(vectorstore.CollectionManager)
+	if !ok {
+		return fmt.Errorf("the %s vector store has no collections to manage", a.cfg.VectorStore.Type)
+	}
+	return manager.DeleteCollection(ctx, name)
+}
+
+// ProjectStore opens the vector store collection project was indexed into,
+// for work that writes no vectors, such as deleting its chunks. With
+// namespace_by_model on, that differs from the configured collection once
+// the embedding model changes. A nil project, or one indexed before
+// collections were recorded, gets the configured collection. The returned
+// func closes a store opened here.
+func (a *App) ProjectStore(project *metadata.Project) (vectorstore.VectorStore, func(), error) {
+	if project == nil || project.Collection == "" {
+		return a.storeWithoutEmbedder()
+	}
+	vsConfig := a.cfg.ToVectorStoreConfig()
+	if collection, err := vsConfig.CollectionName(); err == nil && collection == project.Collection {
+		return a.storeWithoutEmbedder()
+	}
+
+	store, closeStore, err := a.OpenCollection(project.Collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open collection %s of project %s: %w", project.Collection, project.Name, err)
+	}
+	return store, closeStore, nil
+}
+
+// OpenCollection opens the vector store collection of the given name, as is,
+// without the model suffix of namespace_by_model, for reading chunks that
+// another embedding model indexed. The returned func closes the store.
+func (a *App) OpenCollection(name string) (vectorstore.VectorStore, func(), error) {
+	vsConfig := a.cfg.ToVectorStoreConfig()
+	vsConfig.Collection = name
+	vsConfig.Model = ""
+	store, err := vectorstore.New(vsConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store, func() { store.Close() }, nil
+}
+
+// storeWithoutEmbedder returns the open vector store, or else opens one that
+// needs no embedder and accepts a collection of another model's vectors, for
+// work that writes no vectors. The returned func closes a store opened here.
+func (a *App) storeWithoutEmbedder() (vectorstore.VectorStore, func(), error) {
+	if a.store != nil {
+		return a.store, func() {}, nil
+	}
+	store, err := vectorstore.New(a.cfg.ToVectorStoreConfig())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create vector store: %w", err)
+	}
+	return store, func() { store.Close() }, nil
+}
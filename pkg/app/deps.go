@@ -0,0 +1,170 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/parser"
+)
+
+// DefaultDepsGroup is the group dependencies are indexed into when
+// IndexOptions.DepsGroup is empty
+const DefaultDepsGroup = "deps"
+
+// goModule is a module whose packages a project imports, as reported by
+// go list
+type goModule struct {
+	Path    string
+	Version string
+	Dir     string
+	Main    bool
+	Replace *goModule
+}
+
+// DepProjectName is the project a dependency is indexed as, e.g.
+// "github.com/spf13/cobra@v1.8.0". A module version never changes, so
+// projects sharing it share one index.
+func DepProjectName(modulePath, version string) string {
+	if version == "" {
+		return modulePath
+	}
+	return modulePath + "@" + version
+}
+
+// goDependencies lists the modules providing the packages imported, directly
+// or indirectly, by the Go modules in paths. Only modules whose packages are
+// imported are listed, not the whole build list. Replaced modules are listed
+// with their replacement's directory.
+func goDependencies(ctx context.Context, paths []string) ([]goModule, error) {
+	if _, err := exec.LookPath("go"); err != nil {
+		return nil, fmt.Errorf("go is not installed; it is needed to resolve dependencies")
+	}
+
+	byPath := make(map[string]goModule)
+	var found bool
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(path, "go.mod")); err != nil {
+			continue
+		}
+		found = true
+
+		cmd := exec.CommandContext(ctx, "go", "list", "-deps", "-json=Module", "./...")
+		cmd.Dir = path
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("go list failed in %s: %s", path, strings.TrimSpace(stderr.String()))
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(out))
+		for {
+			var pkg struct{ Module *goModule }
+			if err := dec.Decode(&pkg); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to parse go list output: %w", err)
+			}
+			// Standard library packages have no module
+			if pkg.Module == nil || pkg.Module.Main {
+				continue
+			}
+			module := *pkg.Module
+			if module.Replace != nil {
+				module.Dir = module.Replace.Dir
+				if module.Replace.Version != "" {
+					module.Version = module.Replace.Version
+				}
+			}
+			byPath[module.Path] = module
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no go.mod found in the project paths; dependencies can only be resolved for Go modules")
+	}
+
+	modules := make([]goModule, 0, len(byPath))
+	for _, module := range byPath {
+		modules = append(modules, module)
+	}
+	sort.Slice(modules, func(i, j int) bool { return modules[i].Path < modules[j].Path })
+	return modules, nil
+}
+
+// indexDeps indexes the Go modules a project imports, each as its own
+// project in the dependencies group. Modules already indexed at the same
+// version, such as those shared with another project, are skipped. A module
+// that fails to index is reported and the rest are still indexed.
+func (a *App) indexDeps(ctx context.Context, opts IndexOptions) error {
+	modules, err := goDependencies(ctx, opts.Paths)
+	if err != nil {
+		return err
+	}
+
+	group := opts.DepsGroup
+	if group == "" {
+		group = DefaultDepsGroup
+	}
+
+	var pending []goModule
+	var indexed, missing int
+	for _, module := range modules {
+		name := DepProjectName(module.Path, module.Version)
+		// A dependency whose index was interrupted is resumed
+		if project, err := a.metaStore.GetProject(ctx, name); err == nil && project.LastIndexedAt != nil {
+			indexed++
+			continue
+		} else if err != nil && !errors.Is(err, metadata.ErrProjectNotFound) {
+			return fmt.Errorf("failed to get project metadata: %w", err)
+		}
+		// Modules not in the module cache have no directory
+		if module.Dir == "" {
+			missing++
+			continue
+		}
+		pending = append(pending, module)
+	}
+
+	fmt.Fprintf(opts.Output, "Found %d dependencies of %s (%d already indexed)\n", len(modules), opts.Name, indexed)
+	if missing > 0 {
+		fmt.Fprintf(opts.Warnings, "Warning: %d dependencies are not downloaded; run go mod download and index again to include them\n", missing)
+	}
+
+	var failed []string
+	for i, module := range pending {
+		name := DepProjectName(module.Path, module.Version)
+		fmt.Fprintf(opts.Output, "\n[%d/%d] Indexing dependency: %s\n", i+1, len(pending), name)
+		_, err := a.Index(ctx, IndexOptions{
+			Paths:       []string{module.Dir},
+			Name:        name,
+			Group:       group,
+			Description: "Go module dependency of " + opts.Name,
+			Language:    "go",
+			Parser:      parser.Options{AllPlatforms: opts.Parser.AllPlatforms, PackageDocs: opts.Parser.PackageDocs},
+			ChunkTypes:  opts.ChunkTypes,
+			MinLines:    opts.MinLines,
+			Resume:      true,
+			Output:      opts.Output,
+			Warnings:    opts.Warnings,
+		})
+		if err != nil {
+			fmt.Fprintf(opts.Warnings, "Warning: failed to index dependency %s: %v\n", name, err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to index %d of %d dependencies: %s", len(failed), len(pending), strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,272 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// DefaultDiffThreshold is the embedding similarity below which Diff reports
+// a symbol found in both projects as changed
+const DefaultDiffThreshold = 0.95
+
+// DiffKind classifies a symbol in a ProjectDiff
+type DiffKind string
+
+const (
+	DiffChanged   DiffKind = "changed"   // in both, less similar than the threshold
+	DiffRenamed   DiffKind = "renamed"   // under a new name or package, as similar as the threshold to a symbol only in A
+	DiffAdded     DiffKind = "added"     // only in B
+	DiffRemoved   DiffKind = "removed"   // only in A
+	DiffModified  DiffKind = "modified"  // in both, code edited but as similar as the threshold
+	DiffUnchanged DiffKind = "unchanged" // in both, with identical code
+)
+
+// DiffKinds lists the kinds in the order Diff reports them
+var DiffKinds = []DiffKind{DiffChanged, DiffRenamed, DiffAdded, DiffRemoved, DiffModified, DiffUnchanged}
+
+// SymbolDiff is one symbol of a ProjectDiff
+type SymbolDiff struct {
+	Kind       DiffKind
+	A          *chunker.CodeChunk // nil if added
+	B          *chunker.CodeChunk // nil if removed
+	Similarity float64            // cosine similarity of the two embeddings; 0 if added or removed
+}
+
+// ProjectDiff compares the symbols of two indexed projects
+type ProjectDiff struct {
+	A, B      string // project names
+	Threshold float64
+
+	// Symbols lists every symbol except the unchanged ones, ordered by
+	// DiffKinds and then by file and line
+	Symbols []SymbolDiff
+
+	// Counts is the number of symbols of each kind, unchanged included
+	Counts map[DiffKind]int
+}
+
+// Diff compares project b with project a, typically two snapshots of one
+// codebase, symbol by symbol. Symbols are paired by language, package,
+// type, and qualified name, preferring one in the same file. A pair is
+// unchanged if its code is identical, and otherwise modified or changed by
+// whether the cosine similarity of its stored embeddings reaches threshold
+// (DefaultDiffThreshold if zero). Without stored code, a pair reaching the
+// threshold counts as unchanged. A symbol of b with no pair is compared with
+// the unpaired symbols of a and is renamed from the most similar if that
+// reaches the threshold, and added otherwise; the unpaired symbols of a left
+// over are removed. Both projects must be indexed with the same embedding
+// model into the configured collection.
+func (a *App) Diff(ctx context.Context, projectA, projectB string, threshold float64) (*ProjectDiff, error) {
+	if threshold == 0 {
+		threshold = DefaultDiffThreshold
+	}
+	if threshold < 0 || threshold > 1 {
+		return nil, fmt.Errorf("similarity threshold must be between 0 and 1, got %g", threshold)
+	}
+
+	store, closeStore, err := a.storeWithoutEmbedder()
+	if err != nil {
+		return nil, err
+	}
+	defer closeStore()
+
+	if err := a.checkComparable(ctx, projectA, projectB); err != nil {
+		return nil, err
+	}
+	chunksA, vectorsA, err := projectVectors(ctx, store, projectA)
+	if err != nil {
+		return nil, err
+	}
+	chunksB, vectorsB, err := projectVectors(ctx, store, projectB)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ProjectDiff{A: projectA, B: projectB, Threshold: threshold, Counts: make(map[DiffKind]int)}
+	add := func(kind DiffKind, chunkA, chunkB *chunker.CodeChunk, similarity float64) {
+		diff.Counts[kind]++
+		if kind != DiffUnchanged {
+			diff.Symbols = append(diff.Symbols, SymbolDiff{Kind: kind, A: chunkA, B: chunkB, Similarity: similarity})
+		}
+	}
+
+	// Pair symbols by name
+	byKey := make(map[string][]int)
+	for i, chunk := range chunksA {
+		byKey[diffKey(chunk)] = append(byKey[diffKey(chunk)], i)
+	}
+	pairedA := make([]bool, len(chunksA))
+	var unpairedB []int
+	for j := range chunksB {
+		chunkB := &chunksB[j]
+		match := -1
+		for _, i := range byKey[diffKey(*chunkB)] {
+			if pairedA[i] {
+				continue
+			}
+			if match < 0 {
+				match = i
+			}
+			if chunksA[i].FilePath == chunkB.FilePath {
+				match = i
+				break
+			}
+		}
+		if match < 0 {
+			unpairedB = append(unpairedB, j)
+			continue
+		}
+		pairedA[match] = true
+
+		chunkA := &chunksA[match]
+		similarity := cosineSimilarity(vectorsA[match], vectorsB[j])
+		switch {
+		case chunkA.Code != "" && chunkA.Code == chunkB.Code && chunkA.DocString == chunkB.DocString:
+			add(DiffUnchanged, chunkA, chunkB, similarity)
+		case similarity < threshold:
+			add(DiffChanged, chunkA, chunkB, similarity)
+		case chunkA.Code == "" || chunkB.Code == "":
+			add(DiffUnchanged, chunkA, chunkB, similarity)
+		default:
+			add(DiffModified, chunkA, chunkB, similarity)
+		}
+	}
+
+	// A symbol with no pair may have been renamed or moved to another
+	// package; its embedding still resembles the original's
+	for _, j := range unpairedB {
+		best, bestSimilarity := -1, 0.0
+		for i := range chunksA {
+			if pairedA[i] {
+				continue
+			}
+			if similarity := cosineSimilarity(vectorsA[i], vectorsB[j]); similarity > bestSimilarity {
+				best, bestSimilarity = i, similarity
+			}
+		}
+		if best >= 0 && bestSimilarity >= threshold {
+			pairedA[best] = true
+			add(DiffRenamed, &chunksA[best], &chunksB[j], bestSimilarity)
+			continue
+		}
+		add(DiffAdded, nil, &chunksB[j], 0)
+	}
+	for i := range chunksA {
+		if !pairedA[i] {
+			add(DiffRemoved, &chunksA[i], nil, 0)
+		}
+	}
+
+	rank := make(map[DiffKind]int, len(DiffKinds))
+	for i, kind := range DiffKinds {
+		rank[kind] = i
+	}
+	sort.SliceStable(diff.Symbols, func(i, j int) bool {
+		x, y := diff.Symbols[i], diff.Symbols[j]
+		if x.Kind != y.Kind {
+			return rank[x.Kind] < rank[y.Kind]
+		}
+		cx, cy := x.B, y.B
+		if cx == nil || cy == nil {
+			cx, cy = x.A, y.A
+		}
+		if cx.FilePath != cy.FilePath {
+			return cx.FilePath < cy.FilePath
+		}
+		return cx.LineStart < cy.LineStart
+	})
+	return diff, nil
+}
+
+// checkComparable fails unless both projects are indexed with the same
+// embedding model into the configured collection, as vectors of different
+// models cannot be compared
+func (a *App) checkComparable(ctx context.Context, names ...string) error {
+	collection, err := a.cfg.ToVectorStoreConfig().CollectionName()
+	if err != nil {
+		return err
+	}
+
+	var first *metadata.Project
+	for _, name := range names {
+		project, err := a.metaStore.GetProject(ctx, name)
+		if errors.Is(err, metadata.ErrProjectNotFound) {
+			return fmt.Errorf("project '%s' not found", name)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get project metadata: %w", err)
+		}
+		if project.Collection != "" && project.Collection != collection {
+			return fmt.Errorf("project %s was indexed into collection %s, not the configured %s", name, project.Collection, collection)
+		}
+		if first == nil {
+			first = project
+			continue
+		}
+		if first.EmbeddingModel != "" && project.EmbeddingModel != "" &&
+			(first.EmbeddingProvider != project.EmbeddingProvider || first.EmbeddingModel != project.EmbeddingModel) {
+			return fmt.Errorf("projects %s and %s were indexed with different embedding models (%s and %s), whose vectors cannot be compared; re-index one of them with the other's model",
+				first.Name, name, EmbeddingLabel(first.EmbeddingProvider, first.EmbeddingModel), EmbeddingLabel(project.EmbeddingProvider, project.EmbeddingModel))
+		}
+	}
+	return nil
+}
+
+// projectVectors returns a project's stored chunks and the embedding of
+// each
+func projectVectors(ctx context.Context, store vectorstore.VectorStore, projectName string) ([]chunker.CodeChunk, [][]float64, error) {
+	chunks, err := store.GetChunksByProject(ctx, projectName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chunks of project %s: %w", projectName, err)
+	}
+	if len(chunks) == 0 {
+		return nil, nil, fmt.Errorf("no chunks stored for project %s", projectName)
+	}
+	byHash, err := store.EmbeddingsByHash(ctx, projectName, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read embeddings of project %s: %w", projectName, err)
+	}
+
+	vectors := make([][]float64, len(chunks))
+	for i, chunk := range chunks {
+		if vec, ok := byHash[chunk.ContentHash]; ok && chunk.ContentHash != "" {
+			vectors[i] = vec
+			continue
+		}
+		// Chunks indexed before content hashes were recorded
+		if vectors[i], err = store.GetEmbedding(ctx, chunk.ID); err != nil {
+			return nil, nil, fmt.Errorf("failed to read embedding of %s: %w", chunk.ID, err)
+		}
+	}
+	return chunks, vectors, nil
+}
+
+// diffKey identifies a symbol across two snapshots of a project
+func diffKey(chunk chunker.CodeChunk) string {
+	return chunk.Language + "\x00" + chunk.Package + "\x00" + string(chunk.ChunkType) + "\x00" + chunk.QualifiedName()
+}
+
+// cosineSimilarity returns the cosine of the angle between two vectors, or
+// 0 if they differ in length or either is zero
+func cosineSimilarity(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) == 0 {
+		return 0
+	}
+	var dot, normX, normY float64
+	for i := range x {
+		dot += x[i] * y[i]
+		normX += x[i] * x[i]
+		normY += y[i] * y[i]
+	}
+	if normX == 0 || normY == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normX*normY)
+}
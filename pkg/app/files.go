@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/metadata"
+)
+
+// newFileRecord builds the metadata record of a file indexed at now, with
+// its current modification time and content hash
+func newFileRecord(projectID int64, path, rel string, chunkCount int, now time.Time) *metadata.File {
+	file := &metadata.File{
+		ProjectID:     projectID,
+		FilePath:      rel,
+		LastIndexedAt: &now,
+		ChunkCount:    chunkCount,
+	}
+	if info, err := os.Stat(path); err == nil {
+		modTime := info.ModTime()
+		file.LastModifiedAt = &modTime
+	}
+	if hash, err := fileHash(path); err == nil {
+		file.FileHash = hash
+	}
+	return file
+}
+
+// fileHash returns the hex SHA-256 of a file's content
+func fileHash(path string) (string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(src)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordFiles replaces a project's file records after a full index. counts
+// holds the chunks indexed per file path as the parser reported it, relative
+// to the project root.
+func recordFiles(ctx context.Context, metaStore metadata.Store, project *metadata.Project, counts map[string]int, now time.Time) error {
+	recorded := make(map[string]bool, len(counts))
+	for rel, count := range counts {
+		recorded[rel] = true
+		path := filepath.Join(project.RootPath(), filepath.FromSlash(rel))
+		if err := metaStore.UpsertFile(ctx, newFileRecord(project.ID, path, rel, count, now)); err != nil {
+			return fmt.Errorf("failed to update file metadata: %w", err)
+		}
+	}
+
+	// Drop records of files that no longer produced chunks
+	files, err := metaStore.ListFiles(ctx, project.ID)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if recorded[file.FilePath] {
+			continue
+		}
+		if err := metaStore.DeleteFile(ctx, project.ID, file.FilePath); err != nil && !errors.Is(err, metadata.ErrFileNotFound) {
+			return fmt.Errorf("failed to update file metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// completedFiles returns the files of a project stored by an earlier run
+// and unchanged since, by content hash, which a resumed index skips
+func completedFiles(ctx context.Context, metaStore metadata.Store, project *metadata.Project) (map[string]bool, error) {
+	files, err := metaStore.ListFiles(ctx, project.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	completed := make(map[string]bool, len(files))
+	for _, file := range files {
+		if file.LastIndexedAt == nil || file.FileHash == "" {
+			continue
+		}
+		hash, err := fileHash(filepath.Join(project.RootPath(), filepath.FromSlash(file.FilePath)))
+		if err == nil && hash == file.FileHash {
+			completed[file.FilePath] = true
+		}
+	}
+	return completed, nil
+}
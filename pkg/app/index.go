@@ -0,0 +1,552 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/config"
+	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/indexer"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/parser"
+)
+
+// IndexOptions describes a project to index
+type IndexOptions struct {
+	Paths       []string // directories indexed as one project (required unless Repo)
+	Name        string   // project name (required)
+	Group       string   // group to add the project to, created if missing
+	Description string
+
+	// Repo is a remote repository, "URL[@ref]", cloned one commit deep and
+	// indexed in place of Paths; the clone is removed afterwards, so it
+	// needs vector_store.options.store_code
+	Repo string
+
+	// Language to parse; parser.AutoLanguage if empty
+	Language string
+
+	// Parser options; Root is set to the common root of Paths
+	Parser parser.Options
+
+	// ChunkTypes restricts the chunk types indexed, e.g. "function"; empty
+	// indexes all of them
+	ChunkTypes []string
+
+	// MinLines skips chunks spanning fewer lines, unless they have a doc
+	// comment or HTTP/gRPC metadata; zero indexes every chunk
+	MinLines int
+
+	// Full deletes the project's existing data first, as needed to switch
+	// embedding models
+	Full bool
+
+	// Since re-indexes only the files git reports as changed between this
+	// ref and HEAD, falling back to a full index when it cannot. With Repo
+	// it must name a branch, tag, or commit, which is fetched too.
+	Since string
+
+	// Summarize has the configured llm write a one-sentence summary of each
+	// function and type, stored with the chunk and embedded with its text
+	Summarize bool
+
+	// SkipUnchanged reuses the stored vectors of chunks whose text did not
+	// change
+	SkipUnchanged bool
+
+	// Resume skips the files an interrupted index of the project already
+	// stored, as its file records show, unless they changed since
+	Resume bool
+
+	// Blame records the git author of most of each chunk's lines and its
+	// last commit time, which queries can filter on by author
+	Blame bool
+
+	// WithDeps also indexes the Go modules the project imports, each as a
+	// project named DepProjectName in DepsGroup (DefaultDepsGroup if empty).
+	// Modules already indexed at the same version are skipped.
+	WithDeps  bool
+	DepsGroup string
+
+	// Progress is called after each embedding batch
+	Progress indexer.ProgressFunc
+
+	// Output receives status messages, such as the commit a repository was
+	// checked out at, and Warnings the problems worked around, such as
+	// falling back to a full index. Either is discarded if nil.
+	Output   io.Writer
+	Warnings io.Writer
+}
+
+// clonedRepo is the repository a project was cloned from
+type clonedRepo struct {
+	url, ref, commit string
+}
+
+// IndexResult is the outcome of Index
+type IndexResult struct {
+	Project *metadata.Project // as recorded in the metadata store
+	Report  parser.Report     // files the parser skipped or failed on
+}
+
+// ChunkTextFunc builds the renderer for the text embedded per chunk from
+// embeddings.text_template and embeddings.split_identifiers
+func ChunkTextFunc(cfg embedder.Config) (chunker.TextFunc, error) {
+	textFunc, err := chunker.NewTextFunc(cfg.TextTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid embeddings.text_template: %w", err)
+	}
+	if cfg.SplitIdentifiers {
+		textFunc = chunker.WithIdentifierWords(textFunc)
+	}
+	return textFunc, nil
+}
+
+// UnstoredTextFields returns the chunk fields the configured text format
+// embeds that vector_store.options.metadata_fields leaves out of the store.
+// Chunks rebuilt from the store lack them, so re-embedding those chunks
+// would embed different text than indexing did.
+func UnstoredTextFields(cfg *config.Config) ([]string, error) {
+	return cfg.ToVectorStoreConfig().UnstoredFields(chunker.TextFields(cfg.Embeddings.TextTemplate))
+}
+
+// Index parses and embeds a project and records it in the metadata store.
+// Once parsing has run the result is returned even on error, so its Report
+// can be shown.
+func (a *App) Index(ctx context.Context, opts IndexOptions) (*IndexResult, error) {
+	if opts.Name == "" {
+		return nil, fmt.Errorf("no project name given")
+	}
+	if opts.Repo != "" && len(opts.Paths) > 0 {
+		return nil, fmt.Errorf("a repository and project paths cannot be indexed together")
+	}
+	if opts.Resume && (opts.Full || opts.Since != "") {
+		return nil, fmt.Errorf("resuming an index cannot be combined with a full or incremental index")
+	}
+	if opts.Repo != "" {
+		// Results would re-read the code from a clone that no longer exists
+		storeCode, err := a.cfg.ToVectorStoreConfig().StoreCode()
+		if err != nil {
+			return nil, err
+		}
+		if !storeCode {
+			return nil, fmt.Errorf("a repository cannot be indexed with vector_store.options.store_code false, as its clone is removed afterwards; clone it and index the clone's path instead")
+		}
+	}
+	if opts.Output == nil {
+		opts.Output = io.Discard
+	}
+	if opts.Warnings == nil {
+		opts.Warnings = io.Discard
+	}
+	name, err := a.projectNameInGroup(ctx, opts.Name, opts.Group, opts.Output)
+	if err != nil {
+		return nil, err
+	}
+	opts.Name = name
+
+	var repo clonedRepo
+	if opts.Repo != "" {
+		repo.url, repo.ref = ParseRepo(opts.Repo)
+		dir, err := cloneDir(opts.Name)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		fmt.Fprintf(opts.Output, "Cloning repository: %s\n", opts.Repo)
+		if repo.commit, err = cloneRepo(ctx, repo.url, repo.ref, dir); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(opts.Output, "Checked out commit: %s\n", repo.commit)
+		opts.Paths = []string{dir}
+
+		if opts.Since != "" {
+			since, err := fetchCommit(ctx, dir, opts.Since)
+			if err != nil {
+				fmt.Fprintf(opts.Warnings, "Warning: %v; running a full index\n", err)
+			}
+			opts.Since = since
+		}
+	}
+
+	result, err := a.indexPaths(ctx, opts, repo)
+	if err == nil && opts.WithDeps {
+		// Resolved from the project's go.mod, so before a clone is removed
+		err = a.indexDeps(ctx, opts)
+	}
+	return result, err
+}
+
+// projectNameInGroup returns the name to index a project into group under:
+// name itself, unless a project of that name is already in another group,
+// in which case it is metadata.QualifiedName. An ungrouped project of that
+// name is the same project, now added to group. A project already indexed
+// into group under the qualified name keeps it, even once the project it
+// clashed with is gone.
+func (a *App) projectNameInGroup(ctx context.Context, name, group string, out io.Writer) (string, error) {
+	if group == "" {
+		return name, nil
+	}
+	qualified := metadata.QualifiedName(group, name)
+	previous, err := a.metaStore.GetProject(ctx, qualified)
+	switch {
+	case err == nil && previous.GroupName == group:
+		return qualified, nil
+	case err != nil && !errors.Is(err, metadata.ErrProjectNotFound):
+		return "", fmt.Errorf("failed to get project metadata: %w", err)
+	}
+
+	existing, err := a.metaStore.GetProject(ctx, name)
+	if errors.Is(err, metadata.ErrProjectNotFound) {
+		return name, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get project metadata: %w", err)
+	}
+	if existing.GroupName == "" || existing.GroupName == group {
+		return name, nil
+	}
+
+	if previous != nil {
+		return "", fmt.Errorf("project %s is in group %s and %s in group %s; choose another project name",
+			name, existing.GroupName, qualified, previous.GroupName)
+	}
+	fmt.Fprintf(out, "Project %s is already in group %s; indexing as %s\n", name, existing.GroupName, qualified)
+	return qualified, nil
+}
+
+// startProject returns the metadata of the project about to be indexed,
+// recording it first if it is new. An existing project is left as it is
+// until the run completes.
+func (a *App) startProject(ctx context.Context, opts IndexOptions, language string, chunkTypes []string, root, collection string) (*metadata.Project, error) {
+	existing, err := a.metaStore.GetProject(ctx, opts.Name)
+	if err == nil {
+		return existing, nil
+	}
+	if !errors.Is(err, metadata.ErrProjectNotFound) {
+		return nil, fmt.Errorf("failed to get project metadata: %w", err)
+	}
+
+	// Not yet indexed, until the run completes and sets LastIndexedAt and,
+	// for parser.AutoLanguage, the languages it found
+	cfg := a.cfg.Embeddings
+	project := &metadata.Project{
+		Name:        opts.Name,
+		Path:        opts.Paths[0],
+		Paths:       opts.Paths,
+		Language:    language,
+		Description: opts.Description,
+
+		EmbeddingProvider:   cfg.Provider,
+		EmbeddingModel:      cfg.Model,
+		EmbeddingDimensions: a.embedder.Dimensions(),
+		ChunkTypes:          chunkTypes,
+		Root:                root,
+		Collection:          collection,
+		TextContext:         a.textContext(opts),
+	}
+	if err := a.metaStore.CreateProject(ctx, project); err != nil {
+		return nil, fmt.Errorf("failed to create project metadata: %w", err)
+	}
+	return project, nil
+}
+
+// detectedLanguage returns the language p parsed, or "" while a parser for
+// parser.AutoLanguage has found none, so "auto" is never recorded as a
+// project's language
+func detectedLanguage(p parser.Parser) string {
+	if language := p.Language(); language != parser.AutoLanguage {
+		return language
+	}
+	return ""
+}
+
+// textContext lists the context an index with opts embeds with each
+// chunk's text, as recorded in metadata.Project.TextContext
+func (a *App) textContext(opts IndexOptions) []string {
+	var parts []string
+	if a.cfg.Embeddings.ProjectContext {
+		parts = append(parts, metadata.TextContextProject)
+	}
+	if opts.Parser.FileContext {
+		parts = append(parts, metadata.TextContextFile)
+	}
+	return parts
+}
+
+// movedClone points a project last indexed from repo at its clone in paths,
+// as each run clones into a new directory. Chunks and file records hold
+// paths relative to the root, so they still describe the new clone.
+func movedClone(project *metadata.Project, repo clonedRepo, paths []string, root string) {
+	if repo.url == "" || project.RepoURL != repo.url {
+		return
+	}
+	project.Path, project.Paths, project.Root = paths[0], paths, root
+}
+
+// fileRecordsDiffer explains why a project's file records do not describe
+// the chunks an index with these settings stores, or returns "" if they do
+func fileRecordsDiffer(project *metadata.Project, chunkTypes []string, root, collection string, textContext []string) string {
+	switch {
+	case strings.Join(project.ChunkTypes, ",") != strings.Join(chunkTypes, ","):
+		return "chunk types differ from the last index"
+	case strings.Join(project.TextContext, ",") != strings.Join(textContext, ","):
+		return "the context embedded with each chunk differs from the last index"
+	case project.Root != root:
+		return "file paths were recorded relative to a different root"
+	case project.Collection != "" && project.Collection != collection:
+		return fmt.Sprintf("project was indexed into collection %s, not %s", project.Collection, collection)
+	}
+	return ""
+}
+
+// indexPaths indexes opts.Paths, which may be a clone of repo
+func (a *App) indexPaths(ctx context.Context, opts IndexOptions, repo clonedRepo) (*IndexResult, error) {
+	if len(opts.Paths) == 0 {
+		return nil, fmt.Errorf("no project paths given")
+	}
+	if opts.Language == "" {
+		opts.Language = parser.AutoLanguage
+	}
+
+	types, err := chunker.ParseChunkTypes(opts.ChunkTypes)
+	if err != nil {
+		return nil, err
+	}
+	chunkTypes := make([]string, len(types))
+	for i, t := range types {
+		chunkTypes[i] = string(t)
+	}
+
+	cfg := a.cfg.Embeddings
+
+	// The project context is embedded with every chunk, so a re-index
+	// without --group or --description keeps the project's own rather than
+	// embedding a different header in the chunks it re-embeds
+	if cfg.ProjectContext && (opts.Group == "" || opts.Description == "") {
+		existing, err := a.metaStore.GetProject(ctx, opts.Name)
+		switch {
+		case err == nil:
+			if opts.Group == "" {
+				opts.Group = existing.GroupName
+			}
+			if opts.Description == "" {
+				opts.Description = existing.Description
+			}
+		case !errors.Is(err, metadata.ErrProjectNotFound):
+			return nil, fmt.Errorf("failed to get project metadata: %w", err)
+		}
+	}
+
+	// Vectors from different models are not comparable, so a project
+	// must be fully re-indexed to switch models
+	if !opts.Full {
+		existing, err := a.metaStore.GetProject(ctx, opts.Name)
+		if err == nil && existing.EmbeddingModel != "" &&
+			(existing.EmbeddingProvider != cfg.Provider || existing.EmbeddingModel != cfg.Model) {
+			return nil, fmt.Errorf("project %s was indexed with %s but the configured embedder is %s; re-run with --full to re-index it with the new model, or run reembed to recompute its vectors without re-parsing",
+				opts.Name, EmbeddingLabel(existing.EmbeddingProvider, existing.EmbeddingModel), EmbeddingLabel(cfg.Provider, cfg.Model))
+		}
+	}
+
+	store, err := a.VectorStore()
+	if err != nil {
+		return nil, err
+	}
+	collection, err := a.cfg.ToVectorStoreConfig().CollectionName()
+	if err != nil {
+		return nil, err
+	}
+
+	root := parser.ProjectRoot(opts.Paths)
+	parserOpts := opts.Parser
+	parserOpts.Root = root
+	p, err := parser.New(opts.Language, parserOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	textFunc, err := ChunkTextFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ProjectContext {
+		textFunc = chunker.WithProjectContext(textFunc, opts.Group, opts.Description)
+	}
+
+	indexerOpts := []indexer.Option{
+		indexer.WithProgress(opts.Progress),
+		indexer.WithTextFunc(textFunc),
+		indexer.WithBatchSize(cfg.BatchSize),
+		indexer.WithChunkTypes(types),
+		indexer.WithMinLines(opts.MinLines),
+		indexer.WithOutput(opts.Output, opts.Warnings),
+	}
+	if opts.SkipUnchanged {
+		indexerOpts = append(indexerOpts, indexer.WithSkipUnchanged())
+	}
+	if opts.Summarize && opts.SkipUnchanged {
+		unstored, err := a.cfg.ToVectorStoreConfig().UnstoredFields([]string{"summary"})
+		if err != nil {
+			return nil, err
+		}
+		if len(unstored) > 0 {
+			fmt.Fprintf(opts.Warnings, "Warning: vector_store.options.metadata_fields leaves out summary, so stored summaries cannot be reused; every chunk is summarized again\n")
+		}
+	}
+	if opts.Summarize {
+		client, err := a.LLM()
+		if err != nil {
+			return nil, err
+		}
+		indexerOpts = append(indexerOpts, indexer.WithSummarizer(client))
+	}
+	if opts.Blame {
+		indexerOpts = append(indexerOpts, indexer.WithBlame(root))
+	}
+	idx := indexer.New(p, a.embedder, store, indexerOpts...)
+
+	// Incremental index: only files git reports as changed since the
+	// ref. Anything that prevents it falls back to a full index.
+	if opts.Since != "" {
+		existing, err := a.metaStore.GetProject(ctx, opts.Name)
+		switch {
+		case errors.Is(err, metadata.ErrProjectNotFound):
+			fmt.Fprintf(opts.Warnings, "Warning: project %s has not been indexed yet; running a full index\n", opts.Name)
+		case err != nil:
+			return nil, fmt.Errorf("failed to get project metadata: %w", err)
+		case existing.LastIndexedAt == nil:
+			fmt.Fprintf(opts.Warnings, "Warning: project %s has not been completely indexed yet; running a full index\n", opts.Name)
+		case opts.Parser.MethodSets:
+			fmt.Fprintf(opts.Warnings, "Warning: method set chunks span files; running a full index\n")
+		default:
+			movedClone(existing, repo, opts.Paths, root)
+			if differ := fileRecordsDiffer(existing, chunkTypes, root, collection, a.textContext(opts)); differ != "" {
+				fmt.Fprintf(opts.Warnings, "Warning: %s; running a full index\n", differ)
+				break
+			}
+			changes, err := gitChanges(opts.Paths, root, opts.Since, p.Language())
+			if err == nil {
+				existing.RepoURL, existing.RepoRef, existing.RepoCommit = repo.url, repo.ref, repo.commit
+				err = indexChanges(ctx, idx, store, a.metaStore, existing, changes, opts.Since, opts.Output)
+				return &IndexResult{Project: existing, Report: idx.ParseReport()}, err
+			}
+			fmt.Fprintf(opts.Warnings, "Warning: %v; running a full index\n", err)
+		}
+	}
+
+	// Clean re-index: delete existing project first
+	if opts.Full {
+		fmt.Fprintf(opts.Output, "Cleaning existing data for project: %s\n", opts.Name)
+		if err := idx.DeleteProject(ctx, opts.Name); err != nil {
+			// Don't fail if project doesn't exist
+			fmt.Fprintf(opts.Output, "Note: Could not delete existing project (may not exist): %v\n", err)
+		}
+		// Also delete from metadata store
+		a.metaStore.DeleteProject(ctx, opts.Name)
+	}
+
+	// Record the project first and each file as it is stored, so a later
+	// run can resume after an interruption. File records of an index with
+	// other settings describe other chunks, and are left to be replaced
+	// once this run completes.
+	started, err := a.startProject(ctx, opts, detectedLanguage(p), chunkTypes, root, collection)
+	if err != nil {
+		return nil, err
+	}
+	movedClone(started, repo, opts.Paths, root)
+	differ := fileRecordsDiffer(started, chunkTypes, root, collection, a.textContext(opts))
+	if differ == "" {
+		indexerOpts = append(indexerOpts, indexer.WithCheckpoint(func(rel string, chunks int) error {
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			return a.metaStore.UpsertFile(ctx, newFileRecord(started.ID, path, rel, chunks, time.Now()))
+		}))
+	}
+	if opts.Resume && differ != "" {
+		fmt.Fprintf(opts.Warnings, "Warning: %s; indexing every file\n", differ)
+	} else if opts.Resume {
+		completed, err := completedFiles(ctx, a.metaStore, started)
+		if err != nil {
+			return nil, err
+		}
+		indexerOpts = append(indexerOpts, indexer.WithResume(completed))
+	}
+	idx = indexer.New(p, a.embedder, store, indexerOpts...)
+
+	// Run indexing
+	chunkCount, err := idx.IndexProjectPaths(ctx, opts.Paths, opts.Name)
+	result := &IndexResult{Report: idx.ParseReport()}
+	if err != nil {
+		return result, fmt.Errorf("indexing failed: %w", err)
+	}
+
+	language := detectedLanguage(p)
+	if language == "" {
+		return result, fmt.Errorf("no source files of a supported language found in %s", strings.Join(opts.Paths, ", "))
+	}
+
+	// Record metadata
+	now := time.Now()
+	project := &metadata.Project{
+		Name:          opts.Name,
+		Path:          opts.Paths[0],
+		Paths:         opts.Paths,
+		Language:      language,
+		Description:   opts.Description,
+		ChunkCount:    chunkCount,
+		LastIndexedAt: &now,
+
+		EmbeddingProvider:   cfg.Provider,
+		EmbeddingModel:      cfg.Model,
+		EmbeddingDimensions: a.embedder.Dimensions(),
+		ChunkTypes:          chunkTypes,
+		Root:                root,
+		Collection:          collection,
+		RepoURL:             repo.url,
+		RepoRef:             repo.ref,
+		RepoCommit:          repo.commit,
+		TextContext:         a.textContext(opts),
+	}
+	result.Project = project
+
+	// Get group ID if group specified
+	if opts.Group != "" {
+		group, err := a.metaStore.GetGroup(ctx, opts.Group)
+		if err != nil {
+			// Group doesn't exist, create it
+			group, err = a.metaStore.CreateGroup(ctx, opts.Group, "")
+			if err != nil {
+				return result, fmt.Errorf("failed to create group: %w", err)
+			}
+		}
+		project.GroupID = &group.ID
+	}
+
+	// Check if project exists
+	existing, err := a.metaStore.GetProject(ctx, opts.Name)
+	if err == nil {
+		// Update existing project
+		project.ID = existing.ID
+		if err := a.metaStore.UpdateProject(ctx, project); err != nil {
+			return result, fmt.Errorf("failed to update project metadata: %w", err)
+		}
+	} else {
+		// Create new project
+		if err := a.metaStore.CreateProject(ctx, project); err != nil {
+			return result, fmt.Errorf("failed to create project metadata: %w", err)
+		}
+	}
+
+	if err := recordFiles(ctx, a.metaStore, project, idx.FileChunkCounts(), now); err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ParseRepo splits "URL[@ref]" into the repository URL and ref, which may
+// contain slashes, as in feature/x. An "@" before the path, as in
+// git@github.com:org/repo or https://user@host/repo, is part of the URL.
+func ParseRepo(repo string) (url, ref string) {
+	path := repoPathStart(repo)
+	if at := strings.LastIndex(repo[path:], "@"); at >= 0 {
+		return repo[:path+at], repo[path+at+1:]
+	}
+	return repo, ""
+}
+
+// repoPathStart returns the index where the path of a repository URL
+// starts: after the host of scheme://host/path, after the colon of the scp
+// form user@host:path, or 0 for a local path
+func repoPathStart(repo string) int {
+	if scheme := strings.Index(repo, "://"); scheme >= 0 {
+		host := scheme + len("://")
+		if slash := strings.Index(repo[host:], "/"); slash >= 0 {
+			return host + slash
+		}
+		return len(repo)
+	}
+	colon := strings.Index(repo, ":")
+	if colon >= 0 && !strings.Contains(repo[:colon], "/") {
+		return colon + 1
+	}
+	return 0
+}
+
+// cloneDir creates a new, private directory to clone the repository of a
+// project into. A fresh one on every run keeps other users from reading the
+// clone or planting files in it, and concurrent runs from clobbering each
+// other; movedClone carries the project's recorded root along.
+func cloneDir(projectName string) (string, error) {
+	dir, err := os.MkdirTemp("", "vectcode-repo-"+safeName(projectName)+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create clone directory: %w", err)
+	}
+	return dir, nil
+}
+
+// checkGitArg rejects a user-given ref or URL that git would parse as an
+// option, such as --upload-pack=...
+func checkGitArg(kind, arg string) error {
+	if strings.HasPrefix(arg, "-") {
+		return fmt.Errorf("invalid %s %q: must not start with '-'", kind, arg)
+	}
+	return nil
+}
+
+// safeName replaces path separators and other characters unsafe in a
+// directory name
+func safeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// runGit runs git in dir and returns its trimmed output. Credentials come
+// from git's own configuration (credential helpers, SSH agent, GIT_*
+// variables), and git may prompt for them on the terminal.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdin = os.Stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// cloneRepo fetches ref (the remote's HEAD if empty) of url into dir, an
+// empty directory, one commit deep, checks it out, and returns the commit
+func cloneRepo(ctx context.Context, url, ref, dir string) (string, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", fmt.Errorf("git is not installed; it is needed to index a remote repository")
+	}
+
+	if err := checkGitArg("repository URL", url); err != nil {
+		return "", err
+	}
+	if err := checkGitArg("ref", ref); err != nil {
+		return "", err
+	}
+
+	// init and fetch rather than clone --branch, which cannot fetch a
+	// commit by hash
+	target := ref
+	if target == "" {
+		target = "HEAD"
+	}
+	if _, err := runGit(ctx, dir, "init", "-q"); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, dir, "remote", "add", "origin", url); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, dir, "fetch", "-q", "--depth", "1", "origin", target); err != nil {
+		return "", fmt.Errorf("failed to clone %s at %s: %w", url, target, err)
+	}
+	if _, err := runGit(ctx, dir, "checkout", "-q", "FETCH_HEAD"); err != nil {
+		return "", err
+	}
+	return runGit(ctx, dir, "rev-parse", "HEAD")
+}
+
+// fetchCommit fetches ref into a clone, one commit deep, so it can be diffed
+// against HEAD, and returns its commit. ref must be a branch, tag, or commit
+// hash; relative refs like HEAD~3 cannot be fetched.
+func fetchCommit(ctx context.Context, dir, ref string) (string, error) {
+	if err := checkGitArg("ref", ref); err != nil {
+		return "", err
+	}
+	if _, err := runGit(ctx, dir, "fetch", "-q", "--depth", "1", "origin", ref); err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %w", ref, err)
+	}
+	return runGit(ctx, dir, "rev-parse", "FETCH_HEAD")
+}
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/indexer"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/parser"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// fileChange is a source file that differs between a git ref and HEAD
+type fileChange struct {
+	Path    string // on disk: the project path joined with the path git reports
+	Rel     string // relative to the project root, as chunks record it
+	Removed bool
+}
+
+// gitChanges lists the source files under each project path that changed
+// between ref and HEAD. Files the path's ignore file matches are listed as
+// removed. It fails if a path is not inside a git work tree, the ref does not
+// resolve, or the ignore file changed.
+func gitChanges(projectPaths []string, projectRoot, ref, language string) ([]fileChange, error) {
+	var changes []fileChange
+	for _, root := range projectPaths {
+		cmd := exec.Command("git", "-C", root, "diff", "--name-only", "--no-renames", "--relative", ref, "HEAD")
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("git diff %s in %s failed: %s", ref, root, strings.TrimSpace(stderr.String()))
+		}
+
+		ignore, err := parser.LoadIgnoreFile(root)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rel := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			// Which files are indexed may have changed anywhere
+			if rel == parser.IgnoreFileName {
+				return nil, fmt.Errorf("%s changed in %s", parser.IgnoreFileName, root)
+			}
+			if rel == "" || !parser.SourceFile(language, rel) {
+				continue
+			}
+			path := filepath.Join(root, filepath.FromSlash(rel))
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			_, err := os.Stat(path)
+			// An ignored file is removed, in case it was indexed before
+			removed := os.IsNotExist(err) || ignore.Ignored(rel, false)
+			changes = append(changes, fileChange{Path: path, Rel: parser.RelativePath(projectRoot, path), Removed: removed})
+		}
+	}
+	return changes, nil
+}
+
+// indexChanges re-indexes only the changed files of an existing project and
+// updates its file records, chunk count, and last-indexed time
+func indexChanges(ctx context.Context, idx *indexer.Indexer, store vectorstore.VectorStore, metaStore metadata.Store, project *metadata.Project, changes []fileChange, ref string, out io.Writer) error {
+	var changed, removed []string
+	for _, change := range changes {
+		if change.Removed {
+			removed = append(removed, change.Rel)
+		} else {
+			changed = append(changed, change.Rel)
+		}
+	}
+	fmt.Fprintf(out, "Files changed since %s: %d modified or added, %d removed\n", ref, len(changed), len(removed))
+
+	counts, err := idx.IndexFiles(ctx, project.Name, project.Root, changed, removed)
+	if err != nil {
+		return fmt.Errorf("indexing failed: %w", err)
+	}
+
+	now := time.Now()
+	for _, change := range changes {
+		if change.Removed {
+			err := metaStore.DeleteFile(ctx, project.ID, change.Rel)
+			if err != nil && !errors.Is(err, metadata.ErrFileNotFound) {
+				return fmt.Errorf("failed to update file metadata: %w", err)
+			}
+			continue
+		}
+
+		file := newFileRecord(project.ID, change.Path, change.Rel, counts[change.Rel], now)
+		if err := metaStore.UpsertFile(ctx, file); err != nil {
+			return fmt.Errorf("failed to update file metadata: %w", err)
+		}
+	}
+
+	chunks, err := store.GetChunksByProject(ctx, project.Name)
+	if err != nil {
+		return fmt.Errorf("failed to count chunks: %w", err)
+	}
+	project.ChunkCount = len(chunks)
+	project.LastIndexedAt = &now
+	if err := metaStore.UpdateProject(ctx, project); err != nil {
+		return fmt.Errorf("failed to update project metadata: %w", err)
+	}
+
+	fmt.Fprintf(out, "Successfully updated project: %s (%d chunks)\n", project.Name, project.ChunkCount)
+	return nil
+}
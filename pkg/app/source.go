@@ -0,0 +1,67 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// CodeReader returns a query.CodeReader that reads the code of results
+// stored without it from the files of the projects in metaStore
+func CodeReader(metaStore metadata.Store) query.CodeReader {
+	return func(ctx context.Context, results []vectorstore.SearchResult) {
+		readMissingCode(ctx, metaStore, results)
+	}
+}
+
+// readMissingCode fills in the code of results stored without it
+// (vector_store.options.store_code: false) from the files on disk, found
+// under each project's root. Results whose file is gone keep no code.
+func readMissingCode(ctx context.Context, metaStore metadata.Store, results []vectorstore.SearchResult) {
+	missing := false
+	for _, result := range results {
+		if result.Chunk.Code == "" {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return
+	}
+
+	projects, err := metaStore.ListProjects(ctx, nil)
+	if err != nil {
+		return
+	}
+	roots := make(map[string]string, len(projects))
+	for _, project := range projects {
+		roots[project.Name] = project.RootPath()
+	}
+
+	files := make(map[string][]string)
+	for i := range results {
+		chunk := &results[i].Chunk
+		root, ok := roots[chunk.Project]
+		if chunk.Code != "" || !ok {
+			continue
+		}
+
+		path := filepath.Join(root, filepath.FromSlash(chunk.FilePath))
+		lines, ok := files[path]
+		if !ok {
+			if data, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(data), "\n")
+			}
+			files[path] = lines
+		}
+		if chunk.LineStart >= 1 && chunk.LineEnd >= chunk.LineStart && chunk.LineEnd <= len(lines) {
+			chunk.Code = strings.Join(lines[chunk.LineStart-1:chunk.LineEnd], "\n")
+			results[i].Tokens = chunk.EstimateTokens()
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package chunker
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ChunkType represents the type of code chunk
 type ChunkType string
@@ -10,10 +14,88 @@ const (
 	ChunkTypeMethod    ChunkType = "method"
 	ChunkTypeStruct    ChunkType = "struct"
 	ChunkTypeInterface ChunkType = "interface"
+	ChunkTypeEnum      ChunkType = "enum"
+	ChunkTypeTrait     ChunkType = "trait"
+	ChunkTypeImpl      ChunkType = "impl"
 	ChunkTypePackage   ChunkType = "package"
 	ChunkTypeFile      ChunkType = "file"
+	ChunkTypeMethodSet ChunkType = "method_set" // synthetic: a type plus all its method signatures
+)
+
+// ChunkTypes lists every chunk type a parser can produce
+var ChunkTypes = []ChunkType{
+	ChunkTypeFunction, ChunkTypeMethod, ChunkTypeStruct, ChunkTypeInterface, ChunkTypeEnum,
+	ChunkTypeTrait, ChunkTypeImpl, ChunkTypePackage, ChunkTypeFile, ChunkTypeMethodSet,
+}
+
+// ParseChunkTypes converts chunk type names such as "function" into
+// ChunkTypes, rejecting unknown names
+func ParseChunkTypes(names []string) ([]ChunkType, error) {
+	var types []ChunkType
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, t := range ChunkTypes {
+			if string(t) == name {
+				types = append(types, t)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown chunk type %q (expected one of %s)", name, joinStrings(chunkTypeNames()))
+		}
+	}
+	return types, nil
+}
+
+// chunkTypeNames returns the names of ChunkTypes
+func chunkTypeNames() []string {
+	names := make([]string, len(ChunkTypes))
+	for i, t := range ChunkTypes {
+		names[i] = string(t)
+	}
+	return names
+}
+
+// TestKind classifies the functions go test runs, by name and signature
+type TestKind string
+
+const (
+	TestKindTest      TestKind = "test"      // TestXxx(t *testing.T)
+	TestKindBenchmark TestKind = "benchmark" // BenchmarkXxx(b *testing.B)
+	TestKindFuzz      TestKind = "fuzz"      // FuzzXxx(f *testing.F)
+	TestKindExample   TestKind = "example"   // ExampleXxx(), run for its output and shown in godoc
 )
 
+// TestKinds lists every test kind a parser can record
+var TestKinds = []TestKind{TestKindTest, TestKindBenchmark, TestKindFuzz, TestKindExample}
+
+// ParseTestKinds converts test kind names such as "benchmark" into
+// TestKinds, rejecting unknown names
+func ParseTestKinds(names []string) ([]TestKind, error) {
+	var kinds []TestKind
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, kind := range TestKinds {
+			if string(kind) == name {
+				kinds = append(kinds, kind)
+				found = true
+				break
+			}
+		}
+		if !found {
+			valid := make([]string, len(TestKinds))
+			for i, kind := range TestKinds {
+				valid[i] = string(kind)
+			}
+			return nil, fmt.Errorf("unknown test kind %q (expected one of %s)", name, joinStrings(valid))
+		}
+	}
+	return kinds, nil
+}
+
 // CodeChunk represents a parsed piece of code with metadata
 type CodeChunk struct {
 	// Identification
@@ -24,43 +106,128 @@ type CodeChunk struct {
 	Language string    `json:"language"` // "go", "typescript", etc.
 	
 	// Content
-	Code      string    `json:"code"`
+	Code      string    `json:"code,omitempty"`
 	ChunkType ChunkType `json:"chunk_type"`
 	Name      string    `json:"name"` // function/struct/interface name
 	
 	// For methods
 	Receiver string `json:"receiver,omitempty"` // receiver type for methods
 	
+	// TestKind marks the tests, benchmarks, fuzz tests, and examples of
+	// test files; it is empty for every other chunk
+	TestKind TestKind `json:"test_kind,omitempty"`
+	
+	// Signature is the declaration without body or doc, e.g. "func (s *Server) Start(ctx context.Context) error"
+	Signature string `json:"signature,omitempty"`
+	
+	// Params and Returns are the parameter and result types of functions and
+	// methods, one per parameter, e.g. ["context.Context", "string"], ["error"]
+	Params  []string `json:"params,omitempty"`
+	Returns []string `json:"returns,omitempty"`
+	
 	// Service interaction metadata
 	HTTPEndpoints []string `json:"http_endpoints,omitempty"` // e.g., "POST /api/users"
 	HTTPCalls     []string `json:"http_calls,omitempty"`     // outbound HTTP calls
 	GRPCMethods   []string `json:"grpc_methods,omitempty"`   // gRPC service methods
 	Imports       []string `json:"imports,omitempty"`        // imported packages
+	Calls         []string `json:"calls,omitempty"`          // called functions, e.g. "helper", "pkg.Func", "Method"
 	
 	// Documentation
 	DocString string `json:"doc_string,omitempty"` // godoc comment
 	Comments  string `json:"comments,omitempty"`   // inline comments
+	Summary   string `json:"summary,omitempty"`    // one-sentence LLM summary (index --summarize)
+	
+	// FileContext summarizes the rest of the chunk's file (package, sibling
+	// types and functions, imports) when indexed with index.file_context,
+	// e.g. "package config; types: Config; functions: Load; imports: os"
+	FileContext string `json:"file_context,omitempty"`
+	
+	// Ownership, from git blame (index --with-blame): the author of most of
+	// the chunk's lines and the date of the latest commit touching them
+	Author     string    `json:"author,omitempty"`
+	LastCommit time.Time `json:"last_commit,omitzero"`
 	
 	// Metadata
 	LineStart    int       `json:"line_start"`
 	LineEnd      int       `json:"line_end"`
 	LastModified time.Time `json:"last_modified"`
+	
+	// ContentHash identifies the text the chunk was embedded from, so a
+	// re-index can reuse the stored vector when it is unchanged
+	ContentHash string `json:"content_hash,omitempty"`
+}
+
+// QualifiedName returns Receiver.Name for methods, e.g. "Server.Handle" for
+// func (s *Server) Handle(), and Name for every other chunk
+func (c CodeChunk) QualifiedName() string {
+	if c.ChunkType != ChunkTypeMethod || c.Receiver == "" {
+		return c.Name
+	}
+	return ReceiverTypeName(c.Receiver) + "." + c.Name
+}
+
+// ReceiverTypeName strips the pointer and any type parameters from a
+// receiver expression, e.g. "*Cache[K, V]" -> "Cache"
+func ReceiverTypeName(receiver string) string {
+	name := strings.TrimPrefix(receiver, "*")
+	if idx := strings.IndexAny(name, "[<"); idx >= 0 {
+		name = name[:idx]
+	}
+	return name
 }
 
-// ToText converts the chunk to a text representation for embedding
+// ToText converts the chunk to a text representation for embedding. It leads
+// with what matters most for each kind: the signature of functions and
+// methods, the fields of structs, and the methods of interfaces and traits.
 func (c *CodeChunk) ToText() string {
 	text := ""
 	
+	switch c.ChunkType {
+	case ChunkTypeFunction, ChunkTypeMethod:
+		if c.Signature != "" {
+			text += "Signature: " + c.Signature + "\n"
+		}
+	case ChunkTypeStruct:
+		if fields := declBodyLines(c.Code); len(fields) > 0 {
+			text += "Fields: " + joinStrings(fields) + "\n"
+		}
+	case ChunkTypeInterface, ChunkTypeTrait:
+		if methods := declBodyLines(c.Code); len(methods) > 0 {
+			text += "Methods: " + joinStrings(methods) + "\n"
+		}
+	}
+	if text != "" {
+		text += "\n"
+	}
+	
+	if c.Summary != "" {
+		text += "Summary: " + c.Summary + "\n"
+	}
 	if c.DocString != "" {
 		text += c.DocString + "\n\n"
+	} else if c.Summary != "" {
+		text += "\n"
 	}
 	
+	if c.FileContext != "" {
+		text += "File context: " + c.FileContext + "\n"
+	}
 	text += "Project: " + c.Project + "\n"
 	text += "Package: " + c.Package + "\n"
 	text += "Type: " + string(c.ChunkType) + "\n"
 	
 	if c.Name != "" {
-		text += "Name: " + c.Name + "\n"
+		text += "Name: " + c.QualifiedName() + "\n"
+	}
+	if c.TestKind != "" {
+		text += "Test kind: " + string(c.TestKind) + "\n"
+	}
+	
+	if len(c.Params) > 0 {
+		text += "Parameters: " + joinStrings(c.Params) + "\n"
+	}
+	if len(c.Returns) > 0 {
+		text += "Returns: " + joinStrings(c.Returns) + "\n"
 	}
 	
 	if len(c.HTTPEndpoints) > 0 {
@@ -86,3 +253,27 @@ func joinStrings(strs []string) string {
 	}
 	return result
 }
+
+// declBodyLines returns the lines between the first '{' and the last '}' of a
+// declaration, e.g. struct fields or interface methods, with comments removed
+// and whitespace collapsed
+func declBodyLines(code string) []string {
+	open := strings.Index(code, "{")
+	close := strings.LastIndex(code, "}")
+	if open < 0 || close <= open {
+		return nil
+	}
+	
+	var lines []string
+	for _, line := range strings.Split(code[open+1:close], "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" || strings.HasPrefix(line, "#[") {
+			continue
+		}
+		lines = append(lines, strings.TrimSuffix(line, ","))
+	}
+	return lines
+}
@@ -0,0 +1,26 @@
+package chunker
+
+// WithProjectContext prepends the project's group and description to the
+// text fn renders, so queries about a project's purpose ("the billing
+// service") can tell apart similar code in many projects. They go first so
+// a long chunk truncated to the model's input limit keeps them. Without a
+// group or description fn is returned as is.
+func WithProjectContext(fn TextFunc, group, description string) TextFunc {
+	header := ""
+	if group != "" {
+		header += "Project group: " + group + "\n"
+	}
+	if description != "" {
+		header += "Project description: " + description + "\n"
+	}
+	if header == "" {
+		return fn
+	}
+	return func(c *CodeChunk) (string, error) {
+		text, err := fn(c)
+		if err != nil {
+			return "", err
+		}
+		return header + "\n" + text, nil
+	}
+}
@@ -0,0 +1,70 @@
+package chunker
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SplitIdentifier breaks a CamelCase or snake_case identifier into words,
+// e.g. "GetUserByID" -> [Get User By ID] and "parse_http_v2" -> [parse http
+// v2]. An upper-case run followed by a lower-case letter ends before its
+// last letter, so "HTTPServer" -> [HTTP Server], unless that letter is a
+// final plural "s" ("UserIDs" -> [User IDs]). Digits stay with the letters
+// before them.
+func SplitIdentifier(name string) []string {
+	var words []string
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			next := rune(0)
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			plural := next == 's' && i+2 == len(runes) // "IDs", not "I Ds"
+			if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && unicode.IsLower(next) && !plural)) {
+				words = append(words, string(runes[start:i]))
+				start = i
+			}
+		}
+		words = append(words, string(runes[start:]))
+	}
+	return words
+}
+
+// IdentifierWords returns the words of the chunk's name and, for methods,
+// its receiver type, e.g. "Get User By ID" for GetUserByID. It is empty when
+// splitting finds nothing beyond the name itself.
+func (c *CodeChunk) IdentifierWords() string {
+	var words []string
+	if c.ChunkType == ChunkTypeMethod && c.Receiver != "" {
+		words = append(words, SplitIdentifier(ReceiverTypeName(c.Receiver))...)
+	}
+	words = append(words, SplitIdentifier(c.Name)...)
+
+	joined := strings.Join(words, " ")
+	if joined == c.Name {
+		return ""
+	}
+	return joined
+}
+
+// WithIdentifierWords appends the chunk's IdentifierWords to the text fn
+// renders, so natural-language queries such as "fetch user by id" match
+// identifiers like GetUserByID. The original text is kept as is.
+func WithIdentifierWords(fn TextFunc) TextFunc {
+	return func(c *CodeChunk) (string, error) {
+		text, err := fn(c)
+		if err != nil {
+			return "", err
+		}
+		if words := c.IdentifierWords(); words != "" {
+			text += "\n\nIdentifiers: " + words
+		}
+		return text, nil
+	}
+}
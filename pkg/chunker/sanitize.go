@@ -0,0 +1,75 @@
+package chunker
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Size caps applied by Sanitize, in bytes
+const (
+	MaxDocChars  = 4096    // DocString and Comments, stored as metadata
+	MaxCodeChars = 1 << 16 // Code, stored as the document
+)
+
+// truncationMarker ends text cut by Sanitize
+const truncationMarker = "\n... (truncated)"
+
+// codeTruncationMarker ends code cut by TruncateCode
+const codeTruncationMarker = "\n// ... (truncated)"
+
+// Sanitize makes a chunk safe to embed and store: text fields become valid
+// UTF-8, control characters other than newline and tab are removed from
+// documentation and signatures, and DocString, Comments, and Code are capped
+// at MaxDocChars and MaxCodeChars. Pathological files (binary-ish doc
+// comments, huge license headers or generated tables) would otherwise bloat
+// metadata or fail the insert.
+func (c *CodeChunk) Sanitize() {
+	c.DocString = truncate(cleanText(c.DocString), MaxDocChars)
+	c.Comments = truncate(cleanText(c.Comments), MaxDocChars)
+	c.Signature = cleanText(c.Signature)
+	c.Code = truncate(strings.ToValidUTF8(c.Code, "\uFFFD"), MaxCodeChars)
+}
+
+// cleanText replaces invalid UTF-8 and drops control characters other than
+// newline and tab
+func cleanText(text string) string {
+	text = strings.ToValidUTF8(text, "\uFFFD")
+	return strings.Map(func(r rune) rune {
+		if r != '\n' && r != '\t' && unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// truncate cuts text to at most max bytes, on a rune boundary, marking the cut
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	return Truncate(text, max-len(truncationMarker)) + truncationMarker
+}
+
+// Truncate returns the longest prefix of text of at most n bytes that does
+// not split a multi-byte character, so the result stays valid UTF-8 when
+// text is
+func Truncate(text string, n int) string {
+	if len(text) <= n {
+		return text
+	}
+	cut := max(n, 0)
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut]
+}
+
+// TruncateCode cuts code longer than max bytes as Truncate does and marks
+// the cut with a "// ... (truncated)" line, for code quoted in a prompt
+func TruncateCode(code string, max int) string {
+	if len(code) <= max {
+		return code
+	}
+	return Truncate(code, max) + codeTruncationMarker
+}
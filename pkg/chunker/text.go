@@ -0,0 +1,120 @@
+package chunker
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Named text formats for embedding
+const (
+	TextFormatVerbose = "verbose"  // ToText: metadata header followed by code
+	TextFormatCode    = "code"     // the code only
+	TextFormatCodeDoc = "code_doc" // doc comment followed by code
+	TextFormatSummary = "summary"  // the LLM summary only, verbose without one
+)
+
+// verboseTextFields are the optional chunk fields ToText embeds, named as
+// in the chunk's JSON
+var verboseTextFields = []string{
+	"signature", "summary", "doc_string", "file_context", "params", "returns", "http_endpoints", "imports",
+}
+
+// templateTextFields maps the optional chunk fields a text template can
+// read, named as in the chunk's JSON, to their CodeChunk field names
+var templateTextFields = map[string]string{
+	"signature":      "Signature",
+	"doc_string":     "DocString",
+	"comments":       "Comments",
+	"summary":        "Summary",
+	"file_context":   "FileContext",
+	"params":         "Params",
+	"returns":        "Returns",
+	"http_endpoints": "HTTPEndpoints",
+	"http_calls":     "HTTPCalls",
+	"grpc_methods":   "GRPCMethods",
+	"imports":        "Imports",
+	"calls":          "Calls",
+	"last_commit":    "LastCommit",
+}
+
+// TextFields returns the optional chunk fields, named as in the chunk's
+// JSON, that the text format spec (as for NewTextFunc) embeds. The code,
+// name, and other identifying fields are not listed. A template is taken to
+// read the fields it mentions, and all of ToText's if it calls it.
+func TextFields(spec string) []string {
+	switch spec {
+	case "", TextFormatVerbose, TextFormatSummary:
+		return verboseTextFields
+	case TextFormatCode:
+		return nil
+	case TextFormatCodeDoc:
+		return []string{"doc_string"}
+	}
+
+	seen := make(map[string]bool)
+	var fields []string
+	if strings.Contains(spec, ".ToText") {
+		for _, field := range verboseTextFields {
+			seen[field] = true
+		}
+		fields = append(fields, verboseTextFields...)
+	}
+	for field, name := range templateTextFields {
+		if !seen[field] && strings.Contains(spec, "."+name) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// TextFunc renders a chunk into the text that gets embedded
+type TextFunc func(c *CodeChunk) (string, error)
+
+// NewTextFunc returns the renderer for a named format. Any other non-empty
+// spec is parsed as a text/template executed against the CodeChunk, e.g.
+// "{{.Name}}\n{{.DocString}}\n{{.Code}}". An empty spec selects verbose.
+func NewTextFunc(spec string) (TextFunc, error) {
+	switch spec {
+	case "", TextFormatVerbose:
+		return func(c *CodeChunk) (string, error) {
+			return c.ToText(), nil
+		}, nil
+	case TextFormatCode:
+		return func(c *CodeChunk) (string, error) {
+			return c.Code, nil
+		}, nil
+	case TextFormatCodeDoc:
+		return func(c *CodeChunk) (string, error) {
+			if c.DocString == "" {
+				return c.Code, nil
+			}
+			return c.DocString + "\n" + c.Code, nil
+		}, nil
+	case TextFormatSummary:
+		return func(c *CodeChunk) (string, error) {
+			if c.Summary == "" {
+				return c.ToText(), nil
+			}
+			return c.Summary, nil
+		}, nil
+	}
+
+	tmpl, err := template.New("text").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid text template: %w", err)
+	}
+
+	return func(c *CodeChunk) (string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, c); err != nil {
+			return "", fmt.Errorf("failed to render chunk %s: %w", c.ID, err)
+		}
+		return buf.String(), nil
+	}, nil
+}
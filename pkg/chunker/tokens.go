@@ -0,0 +1,34 @@
+package chunker
+
+import "unicode"
+
+// tokenRunChars is roughly how many characters of an identifier or number
+// make up one token
+const tokenRunChars = 4
+
+// EstimateTokens approximates how many LLM tokens text takes. It counts each
+// run of letters and digits as one token per four characters and each
+// punctuation or symbol character as one token, ignoring whitespace, which
+// tracks BPE tokenizers on code more closely than a flat characters-per-token
+// ratio.
+func EstimateTokens(text string) int {
+	tokens, run := 0, 0
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			run++
+			continue
+		}
+		tokens += (run + tokenRunChars - 1) / tokenRunChars
+		run = 0
+		if !unicode.IsSpace(r) {
+			tokens++
+		}
+	}
+	return tokens + (run+tokenRunChars-1)/tokenRunChars
+}
+
+// EstimateTokens approximates the tokens the chunk's documentation and code
+// take when pasted into a prompt
+func (c CodeChunk) EstimateTokens() int {
+	return EstimateTokens(c.DocString) + EstimateTokens(c.Code)
+}
@@ -4,10 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/jayzheng/vectcode/pkg/chunker"
 	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/llm"
+	"github.com/jayzheng/vectcode/pkg/rerank"
 	"github.com/jayzheng/vectcode/pkg/vectorstore"
 )
 
@@ -16,6 +22,42 @@ type Config struct {
 	VectorStore VectorStoreConfig `yaml:"vector_store"`
 	Embeddings  embedder.Config   `yaml:"embeddings"`
 	Metadata    MetadataConfig    `yaml:"metadata"`
+	Query       QueryConfig       `yaml:"query"`
+	LLM         llm.Config        `yaml:"llm"`
+	Rerank      rerank.Config     `yaml:"rerank"`
+	Index       IndexConfig       `yaml:"index"`
+
+	// Profile is the name of the profile applied by Load, if any
+	Profile string `yaml:"-"`
+}
+
+// ProfileEnv names the environment variable selecting a profile when Load is
+// not given one
+const ProfileEnv = "VECTCODE_PROFILE"
+
+// profiles are the named variants of a config file. Each holds any of the
+// top-level sections, and the keys it sets override the base config's.
+type profiles struct {
+	DefaultProfile string               `yaml:"default_profile"`
+	Profiles       map[string]yaml.Node `yaml:"profiles"`
+}
+
+// IndexConfig holds indexing defaults
+type IndexConfig struct {
+	// ChunkTypes restricts indexing to these chunk types (e.g. function,
+	// method); empty indexes every type. The index --chunk-types flag
+	// overrides it.
+	ChunkTypes []string `yaml:"chunk_types"`
+
+	// MinLines skips chunks spanning fewer lines (one-line getters, empty
+	// stubs) unless they have a doc comment or HTTP/gRPC metadata; zero
+	// indexes every chunk. The index --min-lines flag overrides it.
+	MinLines int `yaml:"min_lines"`
+
+	// FileContext adds a summary of each chunk's file (package, sibling
+	// types and functions, imports) to its embedded text. The index
+	// --file-context flag overrides it.
+	FileContext bool `yaml:"file_context"`
 }
 
 // VectorStoreConfig holds vector store configuration
@@ -31,8 +73,53 @@ type MetadataConfig struct {
 	DBPath string `yaml:"db_path"`
 }
 
-// Load reads and parses the configuration file
+// QueryConfig holds query defaults and output configuration
+type QueryConfig struct {
+	// DefaultLimit is the number of results returned when no limit is given
+	// (CLI --limit, MCP search_code limit); zero uses the built-in default
+	DefaultLimit int `yaml:"default_limit"`
+
+	// CacheSize is the number of recent query results the MCP server keeps,
+	// so repeated queries skip embedding and search; zero disables the cache
+	CacheSize int `yaml:"cache_size"`
+
+	// CacheTTL is how long cached results are served, e.g. "5m"; zero uses
+	// query.DefaultCacheTTL. Re-indexing does not clear the cache.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+
+	// ToolTimeout bounds each MCP tool call, e.g. "30s", so a slow embedder
+	// fails the call instead of hanging it; zero uses mcp.DefaultToolTimeout.
+	// The VECTCODE_TOOL_TIMEOUT environment variable overrides it.
+	ToolTimeout time.Duration `yaml:"tool_timeout"`
+
+	// ResultTemplate is a Go text/template file used to print each result
+	ResultTemplate string `yaml:"result_template"`
+
+	// PackageBoosts multiplies the scores of results in these packages, e.g.
+	// core: 1.5, for queries that do not give their own (CLI --boost, MCP
+	// search_code boost_packages)
+	PackageBoosts map[string]float64 `yaml:"package_boosts"`
+}
+
+// EffectiveLimit returns DefaultLimit, or vectorstore.DefaultSearchLimit if unset
+func (q QueryConfig) EffectiveLimit() int {
+	if q.DefaultLimit <= 0 {
+		return vectorstore.DefaultSearchLimit
+	}
+	return q.DefaultLimit
+}
+
+// Load reads and parses the configuration file, applying the profile named
+// by ProfileEnv or else its default_profile. See LoadProfile.
 func Load(configPath string) (*Config, error) {
+	return LoadProfile(configPath, "")
+}
+
+// LoadProfile reads and parses the configuration file and applies a profile:
+// the keys the profile sets override those of the rest of the file. An
+// empty profile uses ProfileEnv, then the file's default_profile, and
+// otherwise none, so files without profiles load as they are.
+func LoadProfile(configPath, profile string) (*Config, error) {
 	// Expand ~ to home directory
 	if configPath[:2] == "~/" {
 		home, err := os.UserHomeDir()
@@ -53,6 +140,9 @@ func Load(configPath string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := applyProfile(&cfg, data, profile); err != nil {
+		return nil, err
+	}
 
 	// Expand ~ in vector store path
 	if len(cfg.VectorStore.Path) > 0 && cfg.VectorStore.Path[:2] == "~/" {
@@ -72,12 +162,125 @@ func Load(configPath string) (*Config, error) {
 		cfg.Metadata.DBPath = filepath.Join(home, cfg.Metadata.DBPath[2:])
 	}
 
+	// Expand ~ in result template path
+	if len(cfg.Query.ResultTemplate) > 1 && cfg.Query.ResultTemplate[:2] == "~/" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		cfg.Query.ResultTemplate = filepath.Join(home, cfg.Query.ResultTemplate[2:])
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
 
+// Validate checks settings that would otherwise fail later, mid-index
+func (c *Config) Validate() error {
+	if c.Query.DefaultLimit < 0 {
+		return fmt.Errorf("invalid query.default_limit %d (expected a positive integer)", c.Query.DefaultLimit)
+	}
+	if c.Query.CacheSize < 0 {
+		return fmt.Errorf("invalid query.cache_size %d (expected a positive integer)", c.Query.CacheSize)
+	}
+	if c.Query.CacheTTL < 0 {
+		return fmt.Errorf("invalid query.cache_ttl %s (expected a positive duration)", c.Query.CacheTTL)
+	}
+	for pkg, factor := range c.Query.PackageBoosts {
+		if factor <= 0 {
+			return fmt.Errorf("invalid query.package_boosts factor %g for %s (expected a positive number)", factor, pkg)
+		}
+	}
+	if _, err := chunker.ParseChunkTypes(c.Index.ChunkTypes); err != nil {
+		return fmt.Errorf("invalid index.chunk_types: %w", err)
+	}
+	if c.Index.MinLines < 0 {
+		return fmt.Errorf("invalid index.min_lines %d (expected zero or a positive integer)", c.Index.MinLines)
+	}
+	if c.Embeddings.Dimensions < 0 {
+		return fmt.Errorf("invalid embeddings.dimensions %d (expected a positive integer)", c.Embeddings.Dimensions)
+	}
+	if f := c.Embeddings.Fallback; f != nil && f.Dimensions < 0 {
+		return fmt.Errorf("invalid embeddings.fallback.dimensions %d (expected a positive integer)", f.Dimensions)
+	}
+	if c.Embeddings.MaxInputTokens < 0 {
+		return fmt.Errorf("invalid embeddings.max_input_tokens %d (expected a positive integer)", c.Embeddings.MaxInputTokens)
+	}
+	if _, err := c.Embeddings.LongInputs(); err != nil {
+		return err
+	}
+	if c.Embeddings.BatchSize < 0 {
+		return fmt.Errorf("invalid embeddings.batch_size %d (expected a positive integer)", c.Embeddings.BatchSize)
+	}
+	if _, err := c.ToVectorStoreConfig().InsertBatchSize(); err != nil {
+		return fmt.Errorf("invalid vector_store.options: %w", err)
+	}
+	if _, err := c.ToVectorStoreConfig().CollectionName(); err != nil {
+		return fmt.Errorf("invalid vector_store.options: %w", err)
+	}
+	if _, err := c.ToVectorStoreConfig().StoreCode(); err != nil {
+		return fmt.Errorf("invalid vector_store.options: %w", err)
+	}
+	if _, err := c.ToVectorStoreConfig().MetadataFields(); err != nil {
+		return fmt.Errorf("invalid vector_store.options: %w", err)
+	}
+	if err := c.LLM.Validate(); err != nil {
+		return fmt.Errorf("llm: %w", err)
+	}
+	if err := c.Rerank.Validate(); err != nil {
+		return fmt.Errorf("rerank: %w", err)
+	}
+	return nil
+}
+
+// applyProfile decodes the selected profile of a config file over cfg
+func applyProfile(cfg *Config, data []byte, profile string) error {
+	var file profiles
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if profile == "" {
+		profile = file.DefaultProfile
+	}
+	if profile == "" {
+		return nil
+	}
+
+	node, ok := file.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(file.Profiles))
+		for name := range file.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q (the config file defines no profiles)", profile)
+		}
+		return fmt.Errorf("unknown profile %q (the config file defines %s)", profile, strings.Join(names, ", "))
+	}
+	if err := node.Decode(cfg); err != nil {
+		return fmt.Errorf("failed to parse profile %s: %w", profile, err)
+	}
+	cfg.Profile = profile
+	return nil
+}
+
 // LoadOrDefault loads config from path, or returns default if not found
 func LoadOrDefault(configPath string) (*Config, error) {
-	cfg, err := Load(configPath)
+	return LoadOrDefaultProfile(configPath, "")
+}
+
+// LoadOrDefaultProfile loads config from path with a profile, as
+// LoadProfile does, or returns default if not found
+func LoadOrDefaultProfile(configPath, profile string) (*Config, error) {
+	cfg, err := LoadProfile(configPath, profile)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return DefaultConfig(), nil
@@ -110,12 +313,21 @@ func DefaultConfig() *Config {
 	}
 }
 
+// ToVectorStoreConfigFor converts to vectorstore.Config for a store written
+// or searched with vectors from emb, so their length is checked
+func (c *Config) ToVectorStoreConfigFor(emb embedder.Embedder) vectorstore.Config {
+	vsConfig := c.ToVectorStoreConfig()
+	vsConfig.Dimensions = emb.Dimensions()
+	return vsConfig
+}
+
 // ToVectorStoreConfig converts to vectorstore.Config
 func (c *Config) ToVectorStoreConfig() vectorstore.Config {
 	return vectorstore.Config{
 		Type:       c.VectorStore.Type,
 		Path:       c.VectorStore.Path,
 		Collection: c.VectorStore.Collection,
+		Model:      c.Embeddings.Model,
 		Options:    c.VectorStore.Options,
 	}
 }
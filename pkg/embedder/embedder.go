@@ -2,14 +2,29 @@ package embedder
 
 import (
 	"context"
+	"errors"
 	"fmt"
 )
 
+// Errors returned (wrapped) by Embedder implementations; test with errors.Is
+var (
+	// ErrUnavailable means the embedding service could not be reached
+	ErrUnavailable = errors.New("embedder unavailable")
+
+	// ErrModelUnavailable means the service does not have the configured
+	// model, e.g. it has not been pulled into Ollama
+	ErrModelUnavailable = errors.New("embedding model unavailable")
+
+	// ErrInputTooLong means a text is over the model's input limit and
+	// embeddings.long_inputs is "error"
+	ErrInputTooLong = errors.New("embedding input too long")
+)
+
 // Embedder defines the interface for generating embeddings
 type Embedder interface {
 	Embed(ctx context.Context, text string) ([]float64, error)
 	EmbedBatch(ctx context.Context, texts []string) ([][]float64, error)
-	Dimensions() int
+	Dimensions() int // vector length, or 0 if unknown
 }
 
 // Config holds embedder configuration
@@ -18,16 +33,115 @@ type Config struct {
 	Model     string `yaml:"model"`
 	APIKeyEnv string `yaml:"api_key_env"`
 	Endpoint  string `yaml:"endpoint"`
+
+	// TextTemplate selects the text embedded for each chunk: "verbose"
+	// (default), "code", "code_doc", "summary", or a Go text/template over
+	// CodeChunk
+	TextTemplate string `yaml:"text_template"`
+
+	// SplitIdentifiers appends the words of each chunk's name to its text,
+	// e.g. "Get User By ID" for GetUserByID
+	SplitIdentifiers bool `yaml:"split_identifiers"`
+
+	// ProjectContext prepends the project's group and description to each
+	// chunk's text when indexing
+	ProjectContext bool `yaml:"project_context"`
+
+	// BatchSize is the number of texts sent per EmbedBatch call; zero uses
+	// the indexer default
+	BatchSize int `yaml:"batch_size"`
+
+	// Dimensions is the model's vector length, for models the embedder does
+	// not know; zero uses the known size of the model
+	Dimensions int `yaml:"dimensions"`
+
+	// MaxInputTokens is the longest text, in estimated tokens, the model
+	// accepts; zero uses the known limit of the model, if any
+	MaxInputTokens int `yaml:"max_input_tokens"`
+
+	// LongInputsStrategy handles texts over the input limit: "truncate"
+	// (default), "mean_pool", or "error"; see LongInputs
+	LongInputsStrategy string `yaml:"long_inputs"`
+
+	// Fallback is a second embedder used when this one keeps failing. It
+	// must produce vectors of the same length, and should serve the same
+	// model so vectors from either are comparable.
+	Fallback *Config `yaml:"fallback"`
+}
+
+// WarnFunc receives a problem an embedder worked around, such as switching
+// to the fallback, as one line without a trailing newline
+type WarnFunc func(message string)
+
+// Option configures the embedders New and the wrapping constructors create
+type Option func(*options)
+
+type options struct {
+	warn WarnFunc // nil drops warnings
 }
 
-// New creates an embedder based on the provider in the config
-func New(config Config) (Embedder, error) {
+// WithWarnFunc reports the problems embedders work around to fn; without
+// it they are dropped
+func WithWarnFunc(fn WarnFunc) Option {
+	return func(o *options) {
+		o.warn = fn
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// warnf passes a warning to the WarnFunc, if there is one
+func (o options) warnf(format string, args ...interface{}) {
+	if o.warn != nil {
+		o.warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// New creates an embedder based on the provider in the config, wrapped in a
+// LimitEmbedder if the model's input limit is known and in a
+// FallbackEmbedder if a fallback is configured
+func New(config Config, opts ...Option) (Embedder, error) {
+	if config.Fallback != nil {
+		fallbackConfig := *config.Fallback
+		config.Fallback = nil
+
+		primary, err := New(config, opts...)
+		if err != nil {
+			return nil, err
+		}
+		secondary, err := New(fallbackConfig, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fallback embedder: %w", err)
+		}
+		return NewFallbackEmbedder(primary, secondary, opts...)
+	}
+
+	strategy, err := config.LongInputs()
+	if err != nil {
+		return nil, err
+	}
+
+	var emb Embedder
 	switch config.Provider {
 	case "ollama":
-		return NewOllamaEmbedder(config)
+		emb, err = NewOllamaEmbedder(config)
 	case "openai":
-		return NewOpenAIEmbedder(config)
+		emb, err = NewOpenAIEmbedder(config)
 	default:
 		return nil, fmt.Errorf("unsupported embedder provider: %s", config.Provider)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := config.InputLimit(); limit > 0 {
+		emb = NewLimitEmbedder(emb, limit, strategy, opts...)
+	}
+	return emb, nil
 }
@@ -0,0 +1,98 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DefaultFallbackAfter is the number of consecutive primary failures after
+// which FallbackEmbedder stops trying the primary
+const DefaultFallbackAfter = 3
+
+// FallbackEmbedder sends requests to a primary embedder and retries failed
+// ones on a secondary. After DefaultFallbackAfter consecutive primary
+// failures it switches to the secondary for good, so one run does not
+// alternate between the two. Both must produce vectors of the same length;
+// for comparable vectors the secondary should serve the same model (e.g.
+// another Ollama host).
+type FallbackEmbedder struct {
+	primary   Embedder
+	secondary Embedder
+	options
+
+	mu       sync.Mutex
+	failures int  // consecutive primary failures
+	switched bool // primary abandoned
+}
+
+// NewFallbackEmbedder wraps primary with secondary. Both must report the
+// same, known dimensions. The switch to secondary is reported to
+// WithWarnFunc.
+func NewFallbackEmbedder(primary, secondary Embedder, opts ...Option) (*FallbackEmbedder, error) {
+	pd, sd := primary.Dimensions(), secondary.Dimensions()
+	if pd == 0 || sd == 0 {
+		return nil, fmt.Errorf("embedding fallback needs known dimensions; set embeddings.dimensions and embeddings.fallback.dimensions")
+	}
+	if pd != sd {
+		return nil, fmt.Errorf("embedding fallback produces %d-dimensional vectors but the primary produces %d", sd, pd)
+	}
+	return &FallbackEmbedder{primary: primary, secondary: secondary, options: newOptions(opts)}, nil
+}
+
+func (e *FallbackEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if e.usePrimary() {
+		embedding, err := e.primary.Embed(ctx, text)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		e.recordPrimary(err)
+		if err == nil {
+			return embedding, nil
+		}
+	}
+	return e.secondary.Embed(ctx, text)
+}
+
+func (e *FallbackEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	if e.usePrimary() {
+		embeddings, err := e.primary.EmbedBatch(ctx, texts)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		e.recordPrimary(err)
+		if err == nil {
+			return embeddings, nil
+		}
+	}
+	return e.secondary.EmbedBatch(ctx, texts)
+}
+
+// Dimensions returns the shared vector length of both embedders
+func (e *FallbackEmbedder) Dimensions() int {
+	return e.primary.Dimensions()
+}
+
+// usePrimary reports whether requests still go to the primary
+func (e *FallbackEmbedder) usePrimary() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return !e.switched
+}
+
+// recordPrimary counts consecutive primary failures, switching to the
+// secondary once there are DefaultFallbackAfter of them
+func (e *FallbackEmbedder) recordPrimary(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err == nil {
+		e.failures = 0
+		return
+	}
+	e.failures++
+	if e.failures >= DefaultFallbackAfter && !e.switched {
+		e.switched = true
+		e.warnf("primary embedder failed %d times in a row, switching to the fallback: %v", e.failures, err)
+	}
+}
@@ -0,0 +1,198 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// Strategies for texts longer than the model's input limit, set by
+// embeddings.long_inputs
+const (
+	LongInputsTruncate = "truncate"  // embed the first part that fits, with a warning (default)
+	LongInputsMeanPool = "mean_pool" // embed every part and average the vectors
+	LongInputsError    = "error"     // fail with ErrInputTooLong
+)
+
+// knownInputLimits are the input lengths, in tokens, of models whose limit
+// is known, keyed by provider and model
+var knownInputLimits = map[string]int{
+	"ollama/bge-m3":                 8192,
+	"ollama/mxbai-embed-large":      512,
+	"ollama/nomic-embed-text":       2048, // Ollama's default context, below the model's 8192
+	"openai/text-embedding-3-small": 8191,
+	"openai/text-embedding-3-large": 8191,
+	"openai/text-embedding-ada-002": 8191,
+}
+
+// InputLimit returns embeddings.max_input_tokens if set, otherwise the
+// limit of a known model, or 0 if there is none to enforce
+func (c Config) InputLimit() int {
+	if c.MaxInputTokens > 0 {
+		return c.MaxInputTokens
+	}
+	return knownInputLimits[c.Provider+"/"+c.Model]
+}
+
+// LongInputs returns the strategy for texts over InputLimit, validating
+// embeddings.long_inputs
+func (c Config) LongInputs() (string, error) {
+	switch c.LongInputsStrategy {
+	case "":
+		return LongInputsTruncate, nil
+	case LongInputsTruncate, LongInputsMeanPool, LongInputsError:
+		return c.LongInputsStrategy, nil
+	default:
+		return "", fmt.Errorf("invalid embeddings.long_inputs %q (expected %s, %s, or %s)",
+			c.LongInputsStrategy, LongInputsTruncate, LongInputsMeanPool, LongInputsError)
+	}
+}
+
+// LimitEmbedder enforces an embedder's input limit on the texts it is
+// given, so an over-long chunk is neither cut short silently by the server
+// nor rejected by it. Lengths are estimated with chunker.EstimateTokens, so
+// the limit should leave some room for the model's own tokenizer.
+type LimitEmbedder struct {
+	embedder  Embedder
+	maxTokens int
+	strategy  string
+	options
+}
+
+// NewLimitEmbedder wraps e so texts over maxTokens are handled by strategy:
+// LongInputsTruncate, LongInputsMeanPool, or LongInputsError. Truncated
+// texts are reported to WithWarnFunc.
+func NewLimitEmbedder(e Embedder, maxTokens int, strategy string, opts ...Option) *LimitEmbedder {
+	return &LimitEmbedder{embedder: e, maxTokens: maxTokens, strategy: strategy, options: newOptions(opts)}
+}
+
+func (e *LimitEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	embeddings, err := e.EmbedBatch(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedBatch embeds texts within the limit as they are. Over-long texts are
+// truncated to the part that fits, split into parts whose vectors are
+// averaged, weighted by length, or rejected, as the strategy says.
+func (e *LimitEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	var inputs []string
+	parts := make([][]int, len(texts)) // indexes in inputs of each text's parts
+	var truncated int
+	for i, text := range texts {
+		tokens := chunker.EstimateTokens(text)
+		if tokens <= e.maxTokens {
+			parts[i] = []int{len(inputs)}
+			inputs = append(inputs, text)
+			continue
+		}
+
+		switch e.strategy {
+		case LongInputsError:
+			return nil, fmt.Errorf("text at index %d is about %d tokens, over the limit of %d: %w", i, tokens, e.maxTokens, ErrInputTooLong)
+		case LongInputsMeanPool:
+			for _, part := range splitInput(text, e.maxTokens) {
+				parts[i] = append(parts[i], len(inputs))
+				inputs = append(inputs, part)
+			}
+		default:
+			truncated++
+			parts[i] = []int{len(inputs)}
+			inputs = append(inputs, splitInput(text, e.maxTokens)[0])
+		}
+	}
+	if truncated > 0 {
+		e.warnf("truncated %d texts longer than %d tokens to fit the embedding model; set embeddings.long_inputs: mean_pool to embed all of them", truncated, e.maxTokens)
+	}
+
+	vectors, err := e.embedder.EmbedBatch(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	embeddings := make([][]float64, len(texts))
+	for i, indexes := range parts {
+		if len(indexes) == 1 {
+			embeddings[i] = vectors[indexes[0]]
+			continue
+		}
+		pooled := make([][]float64, len(indexes))
+		weights := make([]float64, len(indexes))
+		for j, n := range indexes {
+			pooled[j] = vectors[n]
+			weights[j] = float64(chunker.EstimateTokens(inputs[n]))
+		}
+		embeddings[i] = meanPool(pooled, weights)
+	}
+	return embeddings, nil
+}
+
+// Dimensions returns the wrapped embedder's vector length
+func (e *LimitEmbedder) Dimensions() int {
+	return e.embedder.Dimensions()
+}
+
+// splitInput cuts text into parts of at most maxTokens estimated tokens,
+// each ending at a line break where one falls within it
+func splitInput(text string, maxTokens int) []string {
+	var parts []string
+	for chunker.EstimateTokens(text) > maxTokens {
+		// The longest prefix within the limit, then back to a rune start
+		n := sort.Search(len(text), func(n int) bool {
+			return chunker.EstimateTokens(text[:n+1]) > maxTokens
+		})
+		n = len(chunker.Truncate(text, n))
+		if line := strings.LastIndexByte(text[:n], '\n'); line > 0 {
+			n = line + 1
+		}
+		if n == 0 {
+			_, n = utf8.DecodeRuneInString(text)
+		}
+		parts = append(parts, text[:n])
+		text = text[n:]
+	}
+	return append(parts, text)
+}
+
+// meanPool averages vectors by weight and scales the result to their
+// average length, so pooled vectors compare like the model's own (e.g.
+// staying unit length for normalized models)
+func meanPool(vectors [][]float64, weights []float64) []float64 {
+	mean := make([]float64, len(vectors[0]))
+	var total, norms float64
+	for i, vec := range vectors {
+		for j, v := range vec {
+			mean[j] += v * weights[i]
+		}
+		total += weights[i]
+		norms += norm(vec) * weights[i]
+	}
+	if total == 0 {
+		return mean
+	}
+	for j := range mean {
+		mean[j] /= total
+	}
+	if n := norm(mean); n > 0 {
+		scale := norms / total / n
+		for j := range mean {
+			mean[j] *= scale
+		}
+	}
+	return mean
+}
+
+// norm returns a vector's Euclidean length
+func norm(vec []float64) float64 {
+	var sum float64
+	for _, v := range vec {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
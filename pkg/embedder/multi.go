@@ -0,0 +1,32 @@
+package embedder
+
+import "context"
+
+// SparseVector is a lexical embedding: weights for the vocabulary entries a
+// text activates, as produced by BGE-M3's sparse head. Indices are
+// ascending and parallel to Values.
+type SparseVector struct {
+	Indices []uint32  `json:"indices"`
+	Values  []float32 `json:"values"`
+}
+
+// Empty reports whether the vector has no entries
+func (v SparseVector) Empty() bool {
+	return len(v.Indices) == 0
+}
+
+// MultiEmbedding holds every representation a MultiEmbedder computes for
+// one text
+type MultiEmbedding struct {
+	Dense  []float64
+	Sparse SparseVector
+}
+
+// MultiEmbedder is implemented by embedders whose model also produces a
+// sparse vector, for hybrid dense and lexical scoring with a HybridStore.
+// No built-in embedder implements it, and the indexer and query engine use
+// the dense vectors alone; it is the extension point for a sparse backend.
+type MultiEmbedder interface {
+	Embedder
+	EmbedMulti(ctx context.Context, texts []string) ([]MultiEmbedding, error)
+}
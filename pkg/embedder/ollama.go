@@ -68,12 +68,15 @@ func (e *OllamaEmbedder) Embed(ctx context.Context, text string) ([]float64, err
 
 	resp, err := e.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to Ollama: %w", err)
+		return nil, fmt.Errorf("failed to send request to Ollama: %w: %w", ErrUnavailable, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: ollama has no model %s (run: ollama pull %s): %s", ErrModelUnavailable, e.model, e.model, string(body))
+		}
 		return nil, fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
@@ -103,9 +106,13 @@ func (e *OllamaEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 	return embeddings, nil
 }
 
+// Dimensions returns embeddings.dimensions if set, otherwise the size of a
+// known model, or 0 for models it does not know
 func (e *OllamaEmbedder) Dimensions() int {
-	// BGE-M3 produces 1024-dimensional embeddings
-	// This could be made configurable for other models
+	if e.config.Dimensions > 0 {
+		return e.config.Dimensions
+	}
+
 	switch e.model {
 	case "bge-m3":
 		return 1024
@@ -114,7 +121,6 @@ func (e *OllamaEmbedder) Dimensions() int {
 	case "nomic-embed-text":
 		return 768
 	default:
-		// Default to BGE-M3 dimensions
-		return 1024
+		return 0
 	}
 }
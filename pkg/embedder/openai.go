@@ -33,6 +33,9 @@ func (e *OpenAIEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]fl
 }
 
 func (e *OpenAIEmbedder) Dimensions() int {
+	if e.config.Dimensions > 0 {
+		return e.config.Dimensions
+	}
 	if e.config.Model == "text-embedding-3-large" {
 		return 3072
 	}
@@ -0,0 +1,34 @@
+package embedder
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Norm returns the euclidean length of a vector
+func Norm(vec []float64) float64 {
+	var sum float64
+	for _, v := range vec {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
+
+// Summarize describes a vector by its dimension, norm, and first head
+// values, for debugging embeddings without printing thousands of numbers
+func Summarize(vec []float64, head int) string {
+	if head > len(vec) {
+		head = len(vec)
+	}
+	values := make([]string, head)
+	for i := 0; i < head; i++ {
+		values[i] = fmt.Sprintf("%.6f", vec[i])
+	}
+
+	summary := fmt.Sprintf("dimensions=%d norm=%.6f first=[%s", len(vec), Norm(vec), strings.Join(values, ", "))
+	if head < len(vec) {
+		summary += ", ..."
+	}
+	return summary + "]"
+}
@@ -0,0 +1,358 @@
+// Package httpapi serves the query engine over plain HTTP and JSON, for web
+// frontends and tools that do not speak MCP
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/llm"
+	"github.com/jayzheng/vectcode/pkg/metadata"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// maxBodyBytes bounds request bodies, which hold a query and its filters
+const maxBodyBytes = 1 << 20
+
+// Server answers REST requests with an App:
+//
+//	POST   /search          search the indexed code
+//	POST   /ask             answer a question with the configured llm
+//	GET    /projects        list the indexed projects
+//	DELETE /projects/{name} delete a project
+//
+// Errors are answered with a status code for their kind and a JSON body
+// {"error": "..."}.
+//
+// Requests must name a loopback host (localhost, 127.0.0.1, [::1]) or one
+// allowed by WithAllowedHosts in their Host header. The API has no
+// authentication, and without the check a web page could reach a server on
+// localhost through DNS rebinding, searching the indexed code and deleting
+// projects.
+type Server struct {
+	app *app.App
+	mux *http.ServeMux
+
+	allowedHosts map[string]bool // lowercased, without ports
+}
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithAllowedHosts accepts requests for hosts besides the loopback ones,
+// such as the name or address the server is reached at over the network.
+// A host may carry a port, which is ignored.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(s *Server) {
+		for _, host := range hosts {
+			if host != "" {
+				s.allowedHosts[hostName(host)] = true
+			}
+		}
+	}
+}
+
+// New creates a Server for a. The App's query engine, with the vector store
+// and embedder, and its llm client if one is configured, are created here
+// rather than on first use, as App creates them without locking and
+// requests are served concurrently.
+func New(a *app.App, opts ...Option) (*Server, error) {
+	if a.Config().LLM.Provider != "" {
+		if _, err := a.LLM(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := a.Engine(); err != nil {
+		return nil, err
+	}
+
+	s := &Server{app: a, mux: http.NewServeMux(), allowedHosts: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.mux.HandleFunc("POST /search", s.handleSearch)
+	s.mux.HandleFunc("POST /ask", s.handleAsk)
+	s.mux.HandleFunc("GET /projects", s.handleListProjects)
+	s.mux.HandleFunc("DELETE /projects/{name}", s.handleDeleteProject)
+	return s, nil
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.hostAllowed(r.Host) {
+		writeJSON(w, http.StatusForbidden, map[string]string{
+			"error": fmt.Sprintf("host %q is not allowed; serve with --allow-host %s to accept it", r.Host, hostName(r.Host)),
+		})
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// hostAllowed reports whether a request's Host header names a loopback host
+// or an allowed one
+func (s *Server) hostAllowed(host string) bool {
+	name := hostName(host)
+	if name == "localhost" || s.allowedHosts[name] {
+		return true
+	}
+	ip := net.ParseIP(name)
+	return ip != nil && ip.IsLoopback()
+}
+
+// hostName strips the port and IPv6 brackets from a host and lowercases it
+func hostName(host string) string {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	return strings.ToLower(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+}
+
+// searchRequest is the body of POST /search and POST /ask. Query is
+// required; the rest are optional.
+type searchRequest struct {
+	Query            string             `json:"query"`
+	Limit            int                `json:"limit"`
+	Offset           int                `json:"offset"`
+	Projects         []string           `json:"projects"`
+	Language         string             `json:"language"`
+	ChunkType        string             `json:"chunk_type"`
+	Packages         []string           `json:"packages"`
+	PathPrefix       string             `json:"path_prefix"`
+	Symbol           string             `json:"symbol"`
+	Receiver         string             `json:"receiver"`
+	TestKind         string             `json:"test_kind"`
+	ExcludeTestKinds []string           `json:"exclude_test_kinds"`
+	PackageBoosts    map[string]float64 `json:"package_boosts"`
+	MinScore         float64            `json:"min_score"`
+}
+
+// searchResponse is the body answering POST /search
+type searchResponse struct {
+	Results []vectorstore.SearchResult `json:"results"`
+}
+
+// askResponse is the body answering POST /ask: the answer and the results
+// it was drawn from
+type askResponse struct {
+	Answer  string                     `json:"answer"`
+	Results []vectorstore.SearchResult `json:"results"`
+}
+
+// projectJSON is a project as listed by GET /projects
+type projectJSON struct {
+	Name           string   `json:"name"`
+	Group          string   `json:"group,omitempty"`
+	Paths          []string `json:"paths"`
+	Language       string   `json:"language"`
+	Description    string   `json:"description,omitempty"`
+	ChunkCount     int      `json:"chunk_count"`
+	LastIndexedAt  string   `json:"last_indexed_at,omitempty"`
+	EmbeddingModel string   `json:"embedding_model,omitempty"`
+	RepoURL        string   `json:"repo_url,omitempty"`
+	RepoCommit     string   `json:"repo_commit,omitempty"`
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	opts, queryText, err := s.decodeSearch(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	results, err := s.app.Query(r.Context(), queryText, opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []vectorstore.SearchResult{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Results: results})
+}
+
+func (s *Server) handleAsk(w http.ResponseWriter, r *http.Request) {
+	opts, question, err := s.decodeSearch(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	answer, results, err := s.app.Ask(r.Context(), question, opts)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if results == nil {
+		results = []vectorstore.SearchResult{}
+	}
+	writeJSON(w, http.StatusOK, askResponse{Answer: answer, Results: results})
+}
+
+func (s *Server) handleListProjects(w http.ResponseWriter, r *http.Request) {
+	sortBy, err := metadata.ParseProjectSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		writeError(w, badRequest("invalid sort: %v", err))
+		return
+	}
+	filter := &metadata.ProjectFilter{GroupName: r.URL.Query().Get("group"), Sort: sortBy}
+	projects, err := s.app.ListProjects(r.Context(), filter)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	out := make([]projectJSON, len(projects))
+	for i, project := range projects {
+		out[i] = projectJSON{
+			Name:           project.Name,
+			Group:          project.GroupName,
+			Paths:          project.Paths,
+			Language:       project.Language,
+			Description:    project.Description,
+			ChunkCount:     project.ChunkCount,
+			EmbeddingModel: project.EmbeddingModel,
+			RepoURL:        project.RepoURL,
+			RepoCommit:     project.RepoCommit,
+		}
+		if project.LastIndexedAt != nil {
+			out[i].LastIndexedAt = project.LastIndexedAt.Format("2006-01-02T15:04:05Z07:00")
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleDeleteProject(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, err := s.app.Project(r.Context(), name); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.app.Delete(r.Context(), name); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeSearch reads and validates a searchRequest, returning its query
+// text and search options. The limit defaults to query.default_limit, and
+// the package boosts to query.package_boosts.
+func (s *Server) decodeSearch(r *http.Request) (vectorstore.SearchOptions, string, error) {
+	var req searchRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		if errors.Is(err, io.EOF) {
+			return vectorstore.SearchOptions{}, "", badRequest("request body must be a JSON object with a query")
+		}
+		return vectorstore.SearchOptions{}, "", badRequest("invalid request body: %v", err)
+	}
+
+	cfg := s.app.Config()
+	switch {
+	case strings.TrimSpace(req.Query) == "":
+		return vectorstore.SearchOptions{}, "", badRequest("query must not be empty or whitespace")
+	case req.Limit < 0:
+		return vectorstore.SearchOptions{}, "", badRequest("limit cannot be negative")
+	case req.Offset < 0:
+		return vectorstore.SearchOptions{}, "", badRequest("offset cannot be negative")
+	}
+	if req.Limit == 0 {
+		req.Limit = cfg.Query.EffectiveLimit()
+	}
+	if req.ChunkType != "" {
+		if _, err := chunker.ParseChunkTypes([]string{req.ChunkType}); err != nil {
+			return vectorstore.SearchOptions{}, "", badRequest("invalid chunk_type: %v", err)
+		}
+	}
+	if req.TestKind != "" {
+		if _, err := chunker.ParseTestKinds([]string{req.TestKind}); err != nil {
+			return vectorstore.SearchOptions{}, "", badRequest("invalid test_kind: %v", err)
+		}
+	}
+	if _, err := chunker.ParseTestKinds(req.ExcludeTestKinds); err != nil {
+		return vectorstore.SearchOptions{}, "", badRequest("invalid exclude_test_kinds: %v", err)
+	}
+	for pkg, factor := range req.PackageBoosts {
+		if factor <= 0 {
+			return vectorstore.SearchOptions{}, "", badRequest("invalid package_boosts factor %g for %s (expected a positive number)", factor, pkg)
+		}
+	}
+	if req.PackageBoosts == nil {
+		req.PackageBoosts = cfg.Query.PackageBoosts
+	}
+
+	opts := vectorstore.SearchOptions{
+		Projects:         req.Projects,
+		Language:         req.Language,
+		ChunkType:        req.ChunkType,
+		Packages:         req.Packages,
+		PathPrefix:       req.PathPrefix,
+		Symbol:           req.Symbol,
+		Receiver:         req.Receiver,
+		TestKind:         req.TestKind,
+		ExcludeTestKinds: req.ExcludeTestKinds,
+		PackageBoosts:    req.PackageBoosts,
+		MinScore:         req.MinScore,
+		Limit:            req.Limit,
+		Offset:           req.Offset,
+	}
+	return opts, req.Query, nil
+}
+
+// requestError is a problem with a request, answered with 400 Bad Request
+type requestError struct {
+	msg string
+}
+
+func (e *requestError) Error() string {
+	return e.msg
+}
+
+func badRequest(format string, args ...any) error {
+	return &requestError{msg: fmt.Sprintf(format, args...)}
+}
+
+// statusCode maps an error to the HTTP status answering it
+func statusCode(err error) int {
+	var reqErr *requestError
+	switch {
+	case errors.As(err, &reqErr),
+		errors.Is(err, query.ErrEmptyQuery),
+		errors.Is(err, vectorstore.ErrInvalidFilter),
+		errors.Is(err, embedder.ErrInputTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, metadata.ErrProjectNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, llm.ErrNotConfigured):
+		return http.StatusNotImplemented
+	case errors.Is(err, embedder.ErrUnavailable),
+		errors.Is(err, embedder.ErrModelUnavailable),
+		errors.Is(err, vectorstore.ErrUnavailable),
+		errors.Is(err, vectorstore.ErrCollectionMissing),
+		errors.Is(err, llm.ErrUnavailable),
+		errors.Is(err, llm.ErrModelUnavailable):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, statusCode(err), map[string]string{"error": err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
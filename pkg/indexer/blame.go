@@ -0,0 +1,205 @@
+package indexer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// blameWorkers is the number of files blamed concurrently
+const blameWorkers = 8
+
+// WithBlame records on each chunk the git author of most of its lines and
+// the time of the latest commit touching them, read with git blame. Chunk
+// file paths are resolved against root, the directory the parser reports
+// them relative to. Uncommitted lines are not attributed, and files git
+// cannot blame are indexed without an author.
+func WithBlame(root string) Option {
+	return func(i *Indexer) {
+		i.blameRoot = root
+	}
+}
+
+// blameLine is who last changed a line of a file, and when
+type blameLine struct {
+	author string // empty for uncommitted lines
+	time   time.Time
+}
+
+// blame fills in the Author and LastCommit of chunks when WithBlame is set.
+// A file git fails on is skipped; blame only fails if every file did.
+func (i *Indexer) blame(ctx context.Context, chunks []chunker.CodeChunk) error {
+	if i.blameRoot == "" {
+		return nil
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is not installed; it is needed to read authors")
+	}
+
+	var files []string
+	byFile := make(map[string][]int) // indexes of each file's chunks
+	for idx, chunk := range chunks {
+		if _, ok := byFile[chunk.FilePath]; !ok {
+			files = append(files, chunk.FilePath)
+		}
+		byFile[chunk.FilePath] = append(byFile[chunk.FilePath], idx)
+	}
+
+	fmt.Fprintf(i.output, "Reading git blame of %d files...\n", len(files))
+	work := make(chan int)
+	errs := make([]error, len(files))
+	var wg sync.WaitGroup
+	for w := 0; w < blameWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				file := files[n]
+				lines, err := gitBlame(ctx, filepath.Join(i.blameRoot, filepath.FromSlash(file)))
+				if err != nil {
+					errs[n] = fmt.Errorf("%s: %w", file, err)
+					continue
+				}
+				// Each file's chunks are only touched by the worker
+				// blaming it
+				for _, idx := range byFile[file] {
+					attribute(&chunks[idx], lines)
+				}
+			}
+		}()
+	}
+	for n := range files {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- n
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed int
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed == len(files) {
+		return fmt.Errorf("failed to read git blame (is %s in a git repository?): %w", i.blameRoot, first)
+	}
+	if failed > 0 {
+		fmt.Fprintf(i.warnings, "Warning: %d files were indexed without authors, e.g. because they are not committed (first error: %v)\n", failed, first)
+	}
+	return nil
+}
+
+// gitBlame returns who last changed each line of a file, indexed by line
+// number minus one
+func gitBlame(ctx context.Context, path string) ([]blameLine, error) {
+	cmd := exec.CommandContext(ctx, "git", "blame", "--line-porcelain", "--", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git blame failed: %s", msg)
+		}
+		return nil, fmt.Errorf("git blame failed: %w", err)
+	}
+	return parseBlame(out)
+}
+
+// parseBlame reads git blame --line-porcelain output, in which every line of
+// the file is preceded by a header naming its commit and line number and by
+// the commit's details
+func parseBlame(out []byte) ([]blameLine, error) {
+	var lines []blameLine
+	var current blameLine
+	var lineNum int
+	var uncommitted bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	header := true
+	for scanner.Scan() {
+		text := scanner.Text()
+		switch {
+		case header:
+			// <commit> <original line> <final line> [<group size>]
+			fields := strings.Fields(text)
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("unexpected git blame line %q", text)
+			}
+			n, err := strconv.Atoi(fields[2])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("unexpected git blame line %q", text)
+			}
+			lineNum = n
+			uncommitted = strings.Trim(fields[0], "0") == ""
+			current = blameLine{}
+			header = false
+		case strings.HasPrefix(text, "\t"):
+			// The line's content ends its entry
+			for len(lines) < lineNum {
+				lines = append(lines, blameLine{})
+			}
+			if !uncommitted {
+				lines[lineNum-1] = current
+			}
+			header = true
+		case strings.HasPrefix(text, "author "):
+			current.author = strings.TrimPrefix(text, "author ")
+		case strings.HasPrefix(text, "committer-time "):
+			if sec, err := strconv.ParseInt(strings.TrimPrefix(text, "committer-time "), 10, 64); err == nil {
+				current.time = time.Unix(sec, 0).UTC()
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// attribute sets a chunk's author to whoever last changed most of its
+// lines, the first by name on a tie, and its last commit to the latest
+// change among them
+func attribute(chunk *chunker.CodeChunk, lines []blameLine) {
+	counts := make(map[string]int)
+	var latest time.Time
+	for n := max(chunk.LineStart, 1); n <= chunk.LineEnd && n <= len(lines); n++ {
+		line := lines[n-1]
+		if line.author == "" {
+			continue
+		}
+		counts[line.author]++
+		if line.time.After(latest) {
+			latest = line.time
+		}
+	}
+
+	var author string
+	for name, count := range counts {
+		if count > counts[author] || (count == counts[author] && name < author) {
+			author = name
+		}
+	}
+	chunk.Author = author
+	chunk.LastCommit = latest
+}
@@ -0,0 +1,98 @@
+package indexer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// CheckpointFunc is called once every chunk of a file has been embedded
+// and stored, with the file's path as chunks record it and its number of
+// chunks. An error stops indexing.
+type CheckpointFunc func(filePath string, chunks int) error
+
+// WithCheckpoint registers a callback recording each file as it is
+// completely stored, so an interrupted index can later be resumed with
+// WithResume. It applies to IndexProjectPaths.
+func WithCheckpoint(fn CheckpointFunc) Option {
+	return func(i *Indexer) {
+		i.checkpoint = fn
+	}
+}
+
+// WithResume skips embedding and storing the chunks of files completed by
+// an interrupted run, as recorded by WithCheckpoint. The files are still
+// parsed, so their chunks are counted and kept rather than removed as
+// orphans. Method set chunks, which span files, are always indexed again.
+func WithResume(completed map[string]bool) Option {
+	return func(i *Indexer) {
+		i.resumed = completed
+	}
+}
+
+// fileTracker counts the chunks of each file still to be stored, so a file
+// can be checkpointed once the last of them is. Files are added by the
+// parsing stage and marked stored by the storing stage.
+type fileTracker struct {
+	mu        sync.Mutex
+	remaining map[string]int
+	total     map[string]int
+}
+
+func newFileTracker() *fileTracker {
+	return &fileTracker{remaining: make(map[string]int), total: make(map[string]int)}
+}
+
+// add records chunks about to be sent for embedding. All of a file's chunks
+// must be added before any of them is stored.
+func (t *fileTracker) add(chunks []chunker.CodeChunk) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, chunk := range chunks {
+		t.remaining[chunk.FilePath]++
+		t.total[chunk.FilePath]++
+	}
+}
+
+// stored marks chunks as stored and returns the files, with their chunk
+// counts, that have none left to store
+func (t *fileTracker) stored(chunks []chunker.CodeChunk) map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var done map[string]int
+	for _, chunk := range chunks {
+		n, ok := t.remaining[chunk.FilePath]
+		if !ok {
+			continue
+		}
+		if n > 1 {
+			t.remaining[chunk.FilePath] = n - 1
+			continue
+		}
+		delete(t.remaining, chunk.FilePath)
+		if done == nil {
+			done = make(map[string]int)
+		}
+		done[chunk.FilePath] = t.total[chunk.FilePath]
+	}
+	return done
+}
+
+// checkpointStored passes the files a stored batch completed to the
+// checkpoint callback, if there is one. Resumed files were checkpointed by
+// the run that stored them.
+func (i *Indexer) checkpointStored(chunks []chunker.CodeChunk) error {
+	if i.checkpoint == nil || i.tracker == nil {
+		return nil
+	}
+	for filePath, count := range i.tracker.stored(chunks) {
+		if i.resumed[filePath] {
+			continue
+		}
+		if err := i.checkpoint(filePath, count); err != nil {
+			return fmt.Errorf("failed to checkpoint %s: %w", filePath, err)
+		}
+	}
+	return nil
+}
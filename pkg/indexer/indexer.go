@@ -2,57 +2,436 @@ package indexer
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	
 	"github.com/jayzheng/vectcode/pkg/chunker"
 	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/llm"
 	"github.com/jayzheng/vectcode/pkg/parser"
 	"github.com/jayzheng/vectcode/pkg/vectorstore"
 )
 
+// DefaultBatchSize is the number of chunks embedded per EmbedBatch call
+const DefaultBatchSize = 32
+
+// ProgressFunc is called after each embedding batch with the number of
+// chunks embedded so far, counting those that reuse a stored vector, and the
+// total to embed. The total grows while later project paths are still being
+// parsed.
+type ProgressFunc func(done, total int)
+
+// Option configures an Indexer
+type Option func(*Indexer)
+
+// WithProgress registers a callback for embedding progress
+func WithProgress(fn ProgressFunc) Option {
+	return func(i *Indexer) {
+		i.progress = fn
+	}
+}
+
+// WithTextFunc sets how chunks are rendered into text before embedding.
+// The default is CodeChunk.ToText.
+func WithTextFunc(fn chunker.TextFunc) Option {
+	return func(i *Indexer) {
+		i.text = fn
+	}
+}
+
+// WithBatchSize sets the number of chunks embedded per EmbedBatch call.
+// Non-positive sizes keep DefaultBatchSize.
+func WithBatchSize(n int) Option {
+	return func(i *Indexer) {
+		if n > 0 {
+			i.batchSize = n
+		}
+	}
+}
+
+// WithChunkTypes restricts indexing to chunks of the given types. The parser
+// still extracts every chunk; the others are dropped before embedding. No
+// types indexes everything.
+func WithChunkTypes(types []chunker.ChunkType) Option {
+	return func(i *Indexer) {
+		if len(types) == 0 {
+			i.chunkTypes = nil
+			return
+		}
+		i.chunkTypes = make(map[chunker.ChunkType]bool, len(types))
+		for _, t := range types {
+			i.chunkTypes[t] = true
+		}
+	}
+}
+
+// WithMinLines drops chunks spanning fewer than n lines, such as one-line
+// getters and empty stubs, unless they have a doc comment or HTTP or gRPC
+// metadata. Zero indexes every chunk.
+func WithMinLines(n int) Option {
+	return func(i *Indexer) {
+		i.minLines = n
+	}
+}
+
+// WithSkipUnchanged reuses the stored vector of every chunk whose embedding
+// text is unchanged since the last index, so only new and edited chunks are
+// embedded. Chunks are still written back to refresh their metadata, such as
+// line numbers.
+func WithSkipUnchanged() Option {
+	return func(i *Indexer) {
+		i.skipUnchanged = true
+	}
+}
+
+// WithOutput sets where progress messages and warnings are written. A nil
+// writer discards them. The defaults are os.Stdout and os.Stderr.
+func WithOutput(output, warnings io.Writer) Option {
+	return func(i *Indexer) {
+		if output == nil {
+			output = io.Discard
+		}
+		if warnings == nil {
+			warnings = io.Discard
+		}
+		i.output, i.warnings = output, warnings
+	}
+}
+
 // Indexer orchestrates the indexing process
 type Indexer struct {
 	parser      parser.Parser
 	embedder    embedder.Embedder
 	vectorStore vectorstore.VectorStore
+	batchSize   int
+	progress    ProgressFunc
+	text        chunker.TextFunc
+	chunkTypes  map[chunker.ChunkType]bool // nil indexes every type
+	minLines    int
+	skipUnchanged bool
+	skippedShort int // chunks dropped by WithMinLines in the current run
+	summarizer  llm.Client // nil unless WithSummarizer
+	blameRoot   string     // empty unless WithBlame
+	report      parser.Report
+	fileCounts  map[string]int
+	checkpoint  CheckpointFunc  // nil unless WithCheckpoint
+	tracker     *fileTracker    // files awaiting their checkpoint in the current run
+	resumed     map[string]bool // files skipped by WithResume
+	output      io.Writer       // progress messages, see WithOutput
+	warnings    io.Writer
 }
 
-func New(p parser.Parser, e embedder.Embedder, vs vectorstore.VectorStore) *Indexer {
-	return &Indexer{
+func New(p parser.Parser, e embedder.Embedder, vs vectorstore.VectorStore, opts ...Option) *Indexer {
+	i := &Indexer{
 		parser:      p,
 		embedder:    e,
 		vectorStore: vs,
+		batchSize:   DefaultBatchSize,
+		output:      os.Stdout,
+		warnings:    os.Stderr,
+	}
+	for _, opt := range opts {
+		opt(i)
 	}
+	return i
 }
 
 func (i *Indexer) IndexProject(ctx context.Context, projectPath string, projectName string) (int, error) {
-	fmt.Printf("Parsing project: %s\n", projectName)
+	return i.IndexProjectPaths(ctx, []string{projectPath}, projectName)
+}
 
-	chunks, err := i.parser.Parse(ctx, projectPath, projectName)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse project: %w", err)
+// IndexProjectPaths parses every path and indexes the merged chunks under one
+// project. Chunk IDs include the file path, so files from different paths do
+// not collide; a file reached through overlapping paths is indexed once.
+// Each path's chunks are embedded and stored while the next path is parsed.
+// Chunks already stored for the project that this run did not produce are
+// deleted afterwards. The count returned includes chunks of files skipped
+// by WithResume.
+func (i *Indexer) IndexProjectPaths(ctx context.Context, projectPaths []string, projectName string) (int, error) {
+	fmt.Fprintf(i.output, "Parsing project: %s\n", projectName)
+
+	i.report = parser.Report{}
+	i.fileCounts = make(map[string]int)
+	i.skippedShort = 0
+	i.tracker = newFileTracker()
+	defer func() { i.tracker = nil }()
+	var resumedChunks int
+	resumedFiles := make(map[string]bool)
+
+	// Stored vectors and summaries are looked up a batch at a time, as the
+	// chunks reach them, rather than read for the whole project up front
+	stored := i.storedEmbeddings(projectName)
+	summaries := i.storedSummaries(projectName)
+
+	seen := make(map[string]bool)
+	source := func(ctx context.Context, emit func([]chunker.CodeChunk) error) error {
+		for _, projectPath := range projectPaths {
+			pathChunks, err := i.parser.Parse(ctx, projectPath, projectName)
+			if reporter, ok := i.parser.(parser.Reporter); ok {
+				i.report.Add(reporter.Report())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", projectPath, err)
+			}
+
+			var chunks []chunker.CodeChunk
+			for _, chunk := range i.prepareChunks(pathChunks) {
+				if seen[chunk.ID] {
+					continue
+				}
+				seen[chunk.ID] = true
+				i.fileCounts[chunk.FilePath]++
+				if i.resumed[chunk.FilePath] && chunk.ChunkType != chunker.ChunkTypeMethodSet {
+					resumedChunks++
+					resumedFiles[chunk.FilePath] = true
+					continue
+				}
+				chunks = append(chunks, chunk)
+			}
+			if len(chunks) == 0 {
+				continue
+			}
+
+			if len(projectPaths) > 1 {
+				fmt.Fprintf(i.output, "Found %d code chunks in %s\n", len(chunks), projectPath)
+			} else {
+				fmt.Fprintf(i.output, "Found %d code chunks\n", len(chunks))
+			}
+			if err := i.blame(ctx, chunks); err != nil {
+				return err
+			}
+			if err := i.summarize(ctx, chunks, summaries); err != nil {
+				return err
+			}
+			i.tracker.add(chunks)
+			if err := emit(chunks); err != nil {
+				return err
+			}
+		}
+		i.printSkippedShort()
+		if i.resumed != nil {
+			fmt.Fprintf(i.output, "Resumed %d files already indexed (%d chunks); indexing %d files\n",
+				len(resumedFiles), resumedChunks, len(i.fileCounts)-len(resumedFiles))
+		}
+		return nil
 	}
 
-	if len(chunks) == 0 {
+	count, err := i.pipeline(ctx, source, stored)
+	if err != nil {
+		return 0, err
+	}
+	count += resumedChunks
+	if count == 0 {
+		if i.chunkTypes != nil || i.minLines > 0 {
+			return 0, fmt.Errorf("no code chunks of the selected types and length found in project")
+		}
 		return 0, fmt.Errorf("no code chunks found in project")
 	}
 
-	fmt.Printf("Found %d code chunks\n", len(chunks))
-	fmt.Printf("Generating embeddings...\n")
+	// Chunks stored by an earlier run but not produced by this one belong to
+	// deleted symbols or files; remove them once the new chunks are in place
+	removed, err := i.removeOrphans(ctx, projectName, seen)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove orphaned chunks: %w", err)
+	}
+	if removed > 0 {
+		fmt.Fprintf(i.output, "Removed %d orphaned chunks\n", removed)
+	}
+
+	fmt.Fprintf(i.output, "Successfully indexed project: %s\n", projectName)
+	return count, nil
+}
+
+// IndexFiles re-indexes individual files of an already indexed project.
+// Files are given relative to the project root, as chunks record them, and
+// the parser must report paths relative to the same root. Chunks of every
+// changed and removed file are deleted first, then the changed files are
+// parsed and indexed again. It returns the number of chunks indexed per
+// changed file; a file may legitimately yield none (e.g. it is now excluded
+// by a build constraint).
+func (i *Indexer) IndexFiles(ctx context.Context, projectName, root string, changed, removed []string) (map[string]int, error) {
+	// Read the changed files' vectors and summaries before their chunks are
+	// deleted
+	var stored embeddingLookup
+	var summaries summaryLookup
+	if i.skipUnchanged && len(changed) > 0 {
+		vectors, err := i.vectorStore.EmbeddingsByHash(ctx, projectName, changed)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored embeddings: %w", err)
+		}
+		stored = func(context.Context, []chunker.CodeChunk) (map[string][]float64, error) {
+			return vectors, nil
+		}
+	}
+	if lookup := i.storedSummaries(projectName); lookup != nil && len(changed) > 0 {
+		chunks, err := lookup(ctx, changed)
+		if err != nil {
+			return nil, err
+		}
+		summaries = func(context.Context, []string) (map[string]chunker.CodeChunk, error) {
+			return chunks, nil
+		}
+	}
+
+	for _, filePath := range append(append([]string(nil), removed...), changed...) {
+		if err := i.vectorStore.DeleteByFile(ctx, projectName, filePath); err != nil {
+			return nil, err
+		}
+	}
+
+	i.report = parser.Report{}
+	i.skippedShort = 0
+	counts := make(map[string]int, len(changed))
+	source := func(ctx context.Context, emit func([]chunker.CodeChunk) error) error {
+		var chunks []chunker.CodeChunk
+		for _, filePath := range changed {
+			fileChunks, err := i.parser.Parse(ctx, filepath.Join(root, filepath.FromSlash(filePath)), projectName)
+			if reporter, ok := i.parser.(parser.Reporter); ok {
+				i.report.Add(reporter.Report())
+			}
+			if err != nil {
+				return fmt.Errorf("failed to parse %s: %w", filePath, err)
+			}
+			fileChunks = i.prepareChunks(fileChunks)
+			counts[filePath] = len(fileChunks)
+			chunks = append(chunks, fileChunks...)
+		}
+
+		i.printSkippedShort()
+		if len(chunks) == 0 {
+			return nil
+		}
+
+		fmt.Fprintf(i.output, "Found %d code chunks in %d changed files\n", len(chunks), len(changed))
+		if err := i.blame(ctx, chunks); err != nil {
+			return err
+		}
+		if err := i.summarize(ctx, chunks, summaries); err != nil {
+			return err
+		}
+		return emit(chunks)
+	}
+
+	if _, err := i.pipeline(ctx, source, stored); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
 
-	embeddings, err := i.generateEmbeddings(ctx, chunks)
+// Reembed recomputes the vectors of a project's chunks as stored in source,
+// without parsing any files, and writes them to the indexer's vector store
+// (which may be source itself). It returns the number of chunks re-embedded.
+func (i *Indexer) Reembed(ctx context.Context, source vectorstore.VectorStore, projectName string) (int, error) {
+	chunks, err := source.GetChunksByProject(ctx, projectName)
 	if err != nil {
-		return 0, fmt.Errorf("failed to generate embeddings: %w", err)
+		return 0, err
+	}
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("no chunks stored for project %s", projectName)
+	}
+	for _, chunk := range chunks {
+		if chunk.Code == "" {
+			return 0, fmt.Errorf("the code of project %s is not stored (vector_store.options.store_code is false); re-index it with --full instead", projectName)
+		}
+	}
+
+	fmt.Fprintf(i.output, "Re-embedding %d chunks of project: %s\n", len(chunks), projectName)
+	return i.pipeline(ctx, func(ctx context.Context, emit func([]chunker.CodeChunk) error) error {
+		return emit(chunks)
+	}, nil)
+}
+
+// storedEmbeddings returns a lookup of the project's stored vectors in the
+// files of each batch when WithSkipUnchanged is set, and nil otherwise
+func (i *Indexer) storedEmbeddings(projectName string) embeddingLookup {
+	if !i.skipUnchanged {
+		return nil
+	}
+	return func(ctx context.Context, chunks []chunker.CodeChunk) (map[string][]float64, error) {
+		stored, err := i.vectorStore.EmbeddingsByHash(ctx, projectName, chunkFiles(chunks))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored embeddings: %w", err)
+		}
+		return stored, nil
 	}
+}
 
-	fmt.Printf("Storing in vector database...\n")
-	err = i.vectorStore.InsertBatch(ctx, chunks, embeddings)
+// removeOrphans deletes the project's stored chunks whose IDs are not in
+// keep and returns how many were deleted
+func (i *Indexer) removeOrphans(ctx context.Context, projectName string, keep map[string]bool) (int, error) {
+	ids, err := i.vectorStore.ListChunkIDs(ctx, projectName)
 	if err != nil {
-		return 0, fmt.Errorf("failed to store chunks: %w", err)
+		return 0, err
+	}
+
+	var orphans []string
+	for _, id := range ids {
+		if !keep[id] {
+			orphans = append(orphans, id)
+		}
+	}
+	if len(orphans) == 0 {
+		return 0, nil
+	}
+
+	if err := i.vectorStore.DeleteChunks(ctx, orphans); err != nil {
+		return 0, err
+	}
+	return len(orphans), nil
+}
+
+// prepareChunks drops chunks whose type is not selected by WithChunkTypes
+// or that are too short for WithMinLines, and sanitizes the rest for
+// embedding and storage
+func (i *Indexer) prepareChunks(chunks []chunker.CodeChunk) []chunker.CodeChunk {
+	var kept []chunker.CodeChunk
+	for _, chunk := range chunks {
+		if i.chunkTypes != nil && !i.chunkTypes[chunk.ChunkType] {
+			continue
+		}
+		if i.trivial(chunk) {
+			i.skippedShort++
+			continue
+		}
+		chunk.Sanitize()
+		kept = append(kept, chunk)
+	}
+	return kept
+}
+
+// trivial reports whether WithMinLines drops a chunk: it spans fewer lines
+// than the minimum and carries nothing else worth finding it by
+func (i *Indexer) trivial(chunk chunker.CodeChunk) bool {
+	if i.minLines <= 0 || chunk.LineEnd-chunk.LineStart+1 >= i.minLines {
+		return false
+	}
+	return chunk.DocString == "" && len(chunk.HTTPEndpoints) == 0 &&
+		len(chunk.HTTPCalls) == 0 && len(chunk.GRPCMethods) == 0
+}
+
+// printSkippedShort logs how many chunks WithMinLines dropped
+func (i *Indexer) printSkippedShort() {
+	if i.skippedShort > 0 {
+		fmt.Fprintf(i.output, "Skipped %d chunks shorter than %d lines\n", i.skippedShort, i.minLines)
 	}
+}
+
+// FileChunkCounts returns the number of chunks indexed per file, keyed by
+// the file path the parser reported (relative to the project root), from the
+// last IndexProjectPaths run.
+// Files that yielded no chunks are not included.
+func (i *Indexer) FileChunkCounts() map[string]int {
+	return i.fileCounts
+}
 
-	fmt.Printf("Successfully indexed project: %s\n", projectName)
-	return len(chunks), nil
+// ParseReport returns the skipped and failed files from the last index run,
+// combined across all of its paths
+func (i *Indexer) ParseReport() parser.Report {
+	return i.report
 }
 
 func (i *Indexer) DeleteProject(ctx context.Context, projectName string) error {
@@ -63,11 +442,8 @@ func (i *Indexer) ListProjects(ctx context.Context) ([]string, error) {
 	return i.vectorStore.ListProjects(ctx)
 }
 
-func (i *Indexer) generateEmbeddings(ctx context.Context, chunks []chunker.CodeChunk) ([][]float64, error) {
-	texts := make([]string, len(chunks))
-	for idx, chunk := range chunks {
-		texts[idx] = chunk.ToText()
-	}
-	
-	return i.embedder.EmbedBatch(ctx, texts)
+// contentHash identifies an embedding text
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,218 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// pipelineDepth is the number of batches each stage of the indexing pipeline
+// may run ahead of the next
+const pipelineDepth = 2
+
+// chunkSource produces the chunks to index, passing them to emit in as many
+// slices as it likes. It must stop and return emit's error if emit fails.
+type chunkSource func(ctx context.Context, emit func([]chunker.CodeChunk) error) error
+
+// embeddedBatch is a batch of chunks with their vectors, passed from the
+// embedding stage to the storing stage
+type embeddedBatch struct {
+	chunks     []chunker.CodeChunk
+	embeddings [][]float64
+}
+
+// pipeline indexes the chunks source produces in three concurrent stages:
+// source parses them, the embedding stage embeds them a batch at a time, and
+// the storing stage writes each batch to the vector store. The stages are
+// connected by channels holding pipelineDepth batches, so the chunks and
+// vectors in memory at once scale with the batch size rather than with the
+// project. An error in any stage stops the others and is returned; batches
+// stored before it stay stored. It returns the number of chunks stored.
+func (i *Indexer) pipeline(ctx context.Context, source chunkSource, reuse embeddingLookup) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The first error cancels the other stages, whose own errors are then
+	// only a consequence of it
+	var once sync.Once
+	var first error
+	fail := func(err error) {
+		once.Do(func() {
+			first = err
+			cancel()
+		})
+	}
+
+	parsed := make(chan []chunker.CodeChunk, pipelineDepth)
+	embedded := make(chan embeddedBatch, pipelineDepth)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(parsed)
+		err := source(ctx, func(chunks []chunker.CodeChunk) error {
+			return send(ctx, parsed, chunks)
+		})
+		if err != nil {
+			fail(err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(embedded)
+		if err := i.embedStage(ctx, parsed, reuse, embedded); err != nil {
+			fail(fmt.Errorf("failed to generate embeddings: %w", err))
+		}
+	}()
+
+	var count int
+	for batch := range embedded {
+		if err := i.vectorStore.InsertBatch(ctx, batch.chunks, batch.embeddings); err != nil {
+			fail(fmt.Errorf("failed to store chunks: %w", err))
+			break
+		}
+		if err := i.checkpointStored(batch.chunks); err != nil {
+			fail(err)
+			break
+		}
+		count += len(batch.chunks)
+	}
+	wg.Wait()
+	return count, first
+}
+
+// embeddingLookup returns the stored vectors, by content hash, that chunks
+// about to be embedded may reuse
+type embeddingLookup func(ctx context.Context, chunks []chunker.CodeChunk) (map[string][]float64, error)
+
+// embedStage embeds the chunks received from in, up to batchSize per
+// request, and sends each batch on to out with its vectors. It records the
+// hash of each chunk's text; a chunk whose hash is among the vectors reuse
+// returns for its batch keeps that vector instead of being embedded again.
+// A nil reuse embeds every chunk.
+//
+// Progress counts the chunks received so far, so its total grows while the
+// source is still producing chunks. Chunks reusing a vector count as done.
+func (i *Indexer) embedStage(ctx context.Context, in <-chan []chunker.CodeChunk, reuse embeddingLookup, out chan<- embeddedBatch) error {
+	var batch embeddedBatch
+	var texts []string // the text of each chunk in batch
+	var done, embedded, total, reused int
+
+	flush := func() error {
+		if len(batch.chunks) == 0 {
+			return nil
+		}
+		var stored map[string][]float64
+		if reuse != nil {
+			var err error
+			if stored, err = reuse(ctx, batch.chunks); err != nil {
+				return err
+			}
+		}
+
+		var pending []int // indexes in batch of the chunks to embed
+		var pendingTexts []string
+		batch.embeddings = make([][]float64, len(batch.chunks))
+		for idx, chunk := range batch.chunks {
+			if vec, ok := stored[chunk.ContentHash]; ok {
+				batch.embeddings[idx] = vec
+				reused++
+				continue
+			}
+			pending = append(pending, idx)
+			pendingTexts = append(pendingTexts, texts[idx])
+		}
+		if len(pendingTexts) > 0 {
+			vectors, err := i.embedder.EmbedBatch(ctx, pendingTexts)
+			if err != nil {
+				return fmt.Errorf("failed to embed batch [%d:%d]: %w", embedded, embedded+len(pendingTexts), err)
+			}
+			for j, vec := range vectors {
+				batch.embeddings[pending[j]] = vec
+			}
+			embedded += len(pendingTexts)
+		}
+		done += len(batch.chunks)
+		if i.progress != nil {
+			i.progress(done, total)
+		}
+
+		if err := send(ctx, out, batch); err != nil {
+			return err
+		}
+		batch, texts = embeddedBatch{}, nil
+		return nil
+	}
+
+	started := false
+	for chunks := range in {
+		if !started {
+			fmt.Fprintf(i.output, "Generating embeddings...\n")
+			started = true
+		}
+
+		// Render the whole slice first, so that progress counts all of it
+		rendered := make([]string, len(chunks))
+		for idx := range chunks {
+			text := chunks[idx].ToText()
+			if i.text != nil {
+				var err error
+				if text, err = i.text(&chunks[idx]); err != nil {
+					return err
+				}
+			}
+			chunks[idx].ContentHash = contentHash(text)
+			rendered[idx] = text
+		}
+		total += len(chunks)
+		if i.progress != nil {
+			i.progress(done, total)
+		}
+
+		for idx, chunk := range chunks {
+			batch.chunks = append(batch.chunks, chunk)
+			texts = append(texts, rendered[idx])
+			if len(batch.chunks) >= i.batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	if reused > 0 {
+		fmt.Fprintf(i.output, "Reused embeddings of %d unchanged chunks\n", reused)
+	}
+	return nil
+}
+
+// chunkFiles returns the distinct files of chunks, in order
+func chunkFiles(chunks []chunker.CodeChunk) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		if !seen[chunk.FilePath] {
+			seen[chunk.FilePath] = true
+			files = append(files, chunk.FilePath)
+		}
+	}
+	return files
+}
+
+// send passes v to the next stage of the pipeline, unless ctx is done first
+func send[T any](ctx context.Context, ch chan<- T, v T) error {
+	select {
+	case ch <- v:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
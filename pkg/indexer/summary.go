@@ -0,0 +1,178 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/llm"
+)
+
+// summaryWorkers is the number of chunks summarized concurrently
+const summaryWorkers = 4
+
+// maxSummaryCodeChars caps the code sent to the LLM per chunk, keeping the
+// prompt small enough for local models
+const maxSummaryCodeChars = 4000
+
+// summarySystemPrompt asks for the one-sentence description that is embedded
+// with each chunk
+const summarySystemPrompt = `You describe code for a search index.
+Reply with one sentence of plain prose, under 30 words, saying what the code does and what it is for.
+Do not repeat its name or signature, and do not use markdown.`
+
+// summaryTypes are the chunk types WithSummarizer summarizes; files,
+// packages, and impl blocks are too broad for one sentence
+var summaryTypes = map[chunker.ChunkType]bool{
+	chunker.ChunkTypeFunction:  true,
+	chunker.ChunkTypeMethod:    true,
+	chunker.ChunkTypeStruct:    true,
+	chunker.ChunkTypeInterface: true,
+	chunker.ChunkTypeEnum:      true,
+	chunker.ChunkTypeTrait:     true,
+}
+
+// WithSummarizer has client write a one-sentence summary of every function,
+// method, and type before it is embedded. The summary is stored with the
+// chunk and is part of its text, so queries phrased as intent can match it.
+// This costs one LLM call per chunk; with WithSkipUnchanged, chunks whose
+// code is unchanged keep their stored summary instead.
+func WithSummarizer(client llm.Client) Option {
+	return func(i *Indexer) {
+		i.summarizer = client
+	}
+}
+
+// summaryLookup returns the stored chunks of the given files that have a
+// summary, by ID
+type summaryLookup func(ctx context.Context, filePaths []string) (map[string]chunker.CodeChunk, error)
+
+// storedSummaries returns a lookup of the project's stored summaries when
+// summarizing with WithSkipUnchanged, and nil otherwise
+func (i *Indexer) storedSummaries(projectName string) summaryLookup {
+	if i.summarizer == nil || !i.skipUnchanged {
+		return nil
+	}
+	return func(ctx context.Context, filePaths []string) (map[string]chunker.CodeChunk, error) {
+		chunks, err := i.vectorStore.GetChunksByFile(ctx, projectName, filePaths)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stored summaries: %w", err)
+		}
+		stored := make(map[string]chunker.CodeChunk)
+		for _, chunk := range chunks {
+			if chunk.Summary != "" && chunk.Code != "" {
+				stored[chunk.ID] = chunk
+			}
+		}
+		return stored, nil
+	}
+}
+
+// summarize fills in the Summary of the chunks WithSummarizer applies to,
+// reusing the stored summary of a chunk whose code is unchanged, looked up a
+// batch of chunks at a time. A chunk the LLM fails on is indexed without
+// one; summarize only fails if every call did.
+func (i *Indexer) summarize(ctx context.Context, chunks []chunker.CodeChunk, lookup summaryLookup) error {
+	if i.summarizer == nil {
+		return nil
+	}
+
+	var pending []int // indexes of the chunks to summarize
+	var reused int
+	for start := 0; start < len(chunks); start += i.batchSize {
+		batch := chunks[start:min(start+i.batchSize, len(chunks))]
+		var stored map[string]chunker.CodeChunk
+		if lookup != nil {
+			var err error
+			if stored, err = lookup(ctx, chunkFiles(batch)); err != nil {
+				return err
+			}
+		}
+		for n, chunk := range batch {
+			if !summaryTypes[chunk.ChunkType] {
+				continue
+			}
+			if prev, ok := stored[chunk.ID]; ok && prev.Code == chunk.Code {
+				batch[n].Summary = prev.Summary
+				reused++
+				continue
+			}
+			pending = append(pending, start+n)
+		}
+	}
+	if reused > 0 {
+		fmt.Fprintf(i.output, "Reusing summaries of %d unchanged chunks\n", reused)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(i.output, "Summarizing %d chunks...\n", len(pending))
+	work := make(chan int)
+	errs := make([]error, len(pending))
+	var wg sync.WaitGroup
+	for w := 0; w < summaryWorkers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range work {
+				chunk := &chunks[pending[n]]
+				summary, err := i.summarizer.Complete(ctx, summarySystemPrompt, summaryPrompt(chunk))
+				if err != nil {
+					errs[n] = fmt.Errorf("%s %s: %w", chunk.ChunkType, chunk.QualifiedName(), err)
+					continue
+				}
+				chunk.Summary = cleanSummary(summary)
+			}
+		}()
+	}
+	for n := range pending {
+		if ctx.Err() != nil {
+			break
+		}
+		work <- n
+	}
+	close(work)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var failed int
+	var first error
+	for _, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed++
+		}
+	}
+	if failed == len(pending) {
+		return fmt.Errorf("failed to summarize chunks: %w", first)
+	}
+	if failed > 0 {
+		fmt.Fprintf(i.warnings, "Warning: %d chunks were indexed without a summary (first error: %v)\n", failed, first)
+	}
+	return nil
+}
+
+// summaryPrompt shows the LLM a chunk's location, doc comment, and code
+func summaryPrompt(chunk *chunker.CodeChunk) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s in package %s (%s)\n", chunk.ChunkType, chunk.QualifiedName(), chunk.Package, chunk.FilePath)
+	if chunk.DocString != "" {
+		fmt.Fprintf(&b, "%s\n", chunk.DocString)
+	}
+	fmt.Fprintf(&b, "```%s\n%s\n```\n", chunk.Language, chunker.TruncateCode(chunk.Code, maxSummaryCodeChars))
+	return b.String()
+}
+
+// cleanSummary reduces a reply to one line, as models sometimes wrap it or
+// add quotes
+func cleanSummary(reply string) string {
+	summary := strings.Join(strings.Fields(reply), " ")
+	return strings.Trim(summary, "\"'`")
+}
@@ -0,0 +1,84 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Errors returned (wrapped) by Client implementations; test with errors.Is
+var (
+	// ErrUnavailable means the LLM service could not be reached
+	ErrUnavailable = errors.New("llm unavailable")
+
+	// ErrModelUnavailable means the service does not have the configured
+	// model, e.g. it has not been pulled into Ollama
+	ErrModelUnavailable = errors.New("llm model unavailable")
+
+	// ErrNotConfigured means no llm section was configured
+	ErrNotConfigured = errors.New("no llm configured (set llm.provider in the config file)")
+)
+
+// Client generates text from a prompt
+type Client interface {
+	// Complete returns the model's reply to prompt, following the system
+	// instructions
+	Complete(ctx context.Context, system, prompt string) (string, error)
+}
+
+// Config holds LLM configuration
+type Config struct {
+	Provider  string `yaml:"provider"`
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"`
+	Endpoint  string `yaml:"endpoint"`
+
+	// MaxTokens caps the length of a reply; zero uses the provider default
+	MaxTokens int `yaml:"max_tokens"`
+
+	// Temperature controls how varied replies are, from 0 to 2; unset uses
+	// the provider default
+	Temperature *float64 `yaml:"temperature"`
+}
+
+// maxOutputTokens is the longest reply known models can produce
+var maxOutputTokens = map[string]int{
+	"gpt-4o":       16384,
+	"gpt-4o-mini":  16384,
+	"gpt-4.1":      32768,
+	"gpt-4.1-mini": 32768,
+	"gpt-4.1-nano": 32768,
+}
+
+// Validate checks MaxTokens, against the model's limit when the model is
+// known, and Temperature
+func (c Config) Validate() error {
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("invalid max_tokens %d (expected a positive integer)", c.MaxTokens)
+	}
+	if limit, ok := maxOutputTokens[c.Model]; ok && c.MaxTokens > limit {
+		return fmt.Errorf("invalid max_tokens %d (model %s replies with at most %d tokens)", c.MaxTokens, c.Model, limit)
+	}
+	if c.Temperature != nil && (*c.Temperature < 0 || *c.Temperature > 2) {
+		return fmt.Errorf("invalid temperature %g (expected 0 to 2)", *c.Temperature)
+	}
+	return nil
+}
+
+// New creates a client based on the provider in the config
+func New(config Config) (Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case "ollama":
+		return NewOllamaClient(config)
+	case "openai":
+		return NewOpenAIClient(config)
+	default:
+		return nil, fmt.Errorf("unsupported llm provider: %s", config.Provider)
+	}
+}
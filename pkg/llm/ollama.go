@@ -0,0 +1,112 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// OllamaClient implements Client using Ollama's local chat API
+type OllamaClient struct {
+	httpClient *http.Client
+	endpoint   string
+	model      string
+	options    *ollamaOptions
+}
+
+// ollamaChatRequest represents the request to Ollama's chat API
+type ollamaChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Options  *ollamaOptions `json:"options,omitempty"`
+}
+
+// ollamaOptions are the model parameters of a chat request
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// ollamaChatResponse represents the response from Ollama's chat API
+type ollamaChatResponse struct {
+	Message chatMessage `json:"message"`
+}
+
+// chatMessage is one message of a chat request or response
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+func NewOllamaClient(config Config) (*OllamaClient, error) {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:11434"
+	}
+
+	model := config.Model
+	if model == "" {
+		model = "llama3.2"
+	}
+
+	var options *ollamaOptions
+	if config.MaxTokens > 0 || config.Temperature != nil {
+		options = &ollamaOptions{NumPredict: config.MaxTokens, Temperature: config.Temperature}
+	}
+
+	return &OllamaClient{
+		httpClient: &http.Client{},
+		endpoint:   endpoint,
+		model:      model,
+		options:    options,
+	}, nil
+}
+
+func (c *OllamaClient) Complete(ctx context.Context, system, prompt string) (string, error) {
+	reqBody := ollamaChatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		Options: c.options,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", c.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to Ollama: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("%w: ollama has no model %s (run: ollama pull %s): %s", ErrModelUnavailable, c.model, c.model, string(body))
+		}
+		return "", fmt.Errorf("ollama API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return chatResp.Message.Content, nil
+}
@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// OpenAIClient implements Client using OpenAI's chat completions API
+type OpenAIClient struct {
+	httpClient  *http.Client
+	endpoint    string
+	model       string
+	apiKey      string
+	maxTokens   int
+	temperature *float64
+}
+
+// openAIChatRequest represents the request to the chat completions API
+type openAIChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+}
+
+// openAIChatResponse represents the response from the chat completions API
+type openAIChatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func NewOpenAIClient(config Config) (*OpenAIClient, error) {
+	apiKey := os.Getenv(config.APIKeyEnv)
+	if apiKey == "" {
+		return nil, fmt.Errorf("API key not found in environment variable %s", config.APIKeyEnv)
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "https://api.openai.com/v1"
+	}
+
+	model := config.Model
+	if model == "" {
+		model = "gpt-4o-mini"
+	}
+
+	return &OpenAIClient{
+		httpClient:  &http.Client{},
+		endpoint:    endpoint,
+		model:       model,
+		apiKey:      apiKey,
+		maxTokens:   config.MaxTokens,
+		temperature: config.Temperature,
+	}, nil
+}
+
+func (c *OpenAIClient) Complete(ctx context.Context, system, prompt string) (string, error) {
+	reqBody := openAIChatRequest{
+		Model: c.model,
+		Messages: []chatMessage{
+			{Role: "system", Content: system},
+			{Role: "user", Content: prompt},
+		},
+		MaxTokens:   c.maxTokens,
+		Temperature: c.temperature,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/chat/completions", c.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request to OpenAI: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("%w: %s: %s", ErrModelUnavailable, c.model, string(body))
+		}
+		return "", fmt.Errorf("OpenAI API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var chatResp openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chatResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(chatResp.Choices) == 0 {
+		return "", fmt.Errorf("no choices returned from OpenAI")
+	}
+
+	return chatResp.Choices[0].Message.Content, nil
+}
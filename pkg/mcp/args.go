@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// searchCodeArgs are the arguments of the search_code tool
+type searchCodeArgs struct {
+	Query            string   `json:"query"`
+	Project          string   `json:"project"`
+	PathPrefix       string   `json:"path_prefix"`
+	Packages         []string `json:"packages"`
+	BoostPackages    []string `json:"boost_packages"`
+	ParamTypes       []string `json:"param_types"`
+	ReturnTypes      []string `json:"return_types"`
+	TestKind         string   `json:"test_kind"`
+	ExcludeTestKinds []string `json:"exclude_test_kinds"`
+	Limit            int      `json:"limit"`
+	Offset           int      `json:"offset"`
+	MaxCodeChars     int      `json:"max_code_chars"`
+	Full             bool     `json:"full"`
+	WithCallgraph    bool     `json:"with_callgraph"`
+	Structured       bool     `json:"structured"`
+}
+
+// getChunkArgs are the arguments of the get_chunk tool
+type getChunkArgs struct {
+	ID            string `json:"id"`
+	ShowEmbedding bool   `json:"show_embedding"`
+}
+
+// ArgumentError reports a tool argument that does not match the tool's
+// input schema
+type ArgumentError struct {
+	Field   string
+	Problem string
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Field == "" {
+		return e.Problem
+	}
+	return fmt.Sprintf("argument %q: %s", e.Field, e.Problem)
+}
+
+// decodeArguments checks raw tool arguments against an input schema and
+// unmarshals them into out, whose fields should hold the defaults. Missing
+// and null arguments are treated as an empty object.
+func decodeArguments(schema map[string]interface{}, raw json.RawMessage, out interface{}) error {
+	if len(bytes.TrimSpace(raw)) == 0 || bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		raw = json.RawMessage("{}")
+	}
+
+	var args map[string]interface{}
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return &ArgumentError{Problem: "arguments must be a JSON object"}
+	}
+	if err := validateArguments(schema, args); err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(raw, out); err != nil {
+		return &ArgumentError{Problem: err.Error()}
+	}
+	return nil
+}
+
+// validateArguments checks required and unknown properties, property types
+// (string, integer, boolean, array of strings), and integer minimums. Problems are reported in
+// field order so the error is deterministic.
+func validateArguments(schema map[string]interface{}, args map[string]interface{}) error {
+	properties, _ := schema["properties"].(map[string]interface{})
+	required, _ := schema["required"].([]string)
+
+	for _, field := range required {
+		if _, ok := args[field]; !ok {
+			return &ArgumentError{Field: field, Problem: "is required"}
+		}
+	}
+
+	fields := make([]string, 0, len(args))
+	for field := range args {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		property, ok := properties[field].(map[string]interface{})
+		if !ok {
+			return &ArgumentError{Field: field, Problem: "unknown argument"}
+		}
+		if err := validateValue(property, args[field]); err != nil {
+			return &ArgumentError{Field: field, Problem: err.Error()}
+		}
+	}
+	return nil
+}
+
+// validateValue checks one argument against its property schema
+func validateValue(property map[string]interface{}, value interface{}) error {
+	switch property["type"] {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected string, got %s", jsonType(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected boolean, got %s", jsonType(value))
+		}
+	case "integer":
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) {
+			return fmt.Errorf("expected integer, got %s", jsonType(value))
+		}
+		if minimum, ok := property["minimum"].(int); ok && number < float64(minimum) {
+			return fmt.Errorf("must be at least %d", minimum)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("expected array, got %s", jsonType(value))
+		}
+		for i, item := range items {
+			if _, ok := item.(string); !ok {
+				return fmt.Errorf("item %d: expected string, got %s", i, jsonType(item))
+			}
+		}
+	}
+	return nil
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v != math.Trunc(v) {
+			return "number"
+		}
+		return "integer"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
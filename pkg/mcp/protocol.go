@@ -14,6 +14,32 @@ type JSONRPCRequest struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 }
 
+// CancelledParams is the payload of a notifications/cancelled notification
+type CancelledParams struct {
+	RequestID interface{} `json:"requestId"`
+	Reason    string      `json:"reason,omitempty"`
+}
+
+// Server error codes (the JSON-RPC range -32000 to -32099 is reserved for
+// implementation-defined errors). Failures not covered here use -32603.
+const (
+	ErrCodeUnavailable      = -32001 // vector store or embedder unreachable
+	ErrCodeNotFound         = -32002 // unknown chunk
+	ErrCodeIndexMismatch    = -32003 // index unusable with the current embedder or collection
+	ErrCodeModelUnavailable = -32004 // embedding model not installed
+	ErrCodeProjectNotFound  = -32005 // search filtered on a project that is not indexed
+	ErrCodeTimeout          = -32006 // tool call ran longer than the tool timeout
+	ErrCodeShuttingDown     = -32007 // request received after shutdown
+)
+
+// ErrorData is the data of a tool call's error, so clients can tell causes
+// apart, and whether to retry, without parsing the message
+type ErrorData struct {
+	Reason    string `json:"reason"`          // e.g. "embedder_unavailable"; see errorData
+	Retryable bool   `json:"retryable"`       // the same call may succeed later, once a service is back
+	Field     string `json:"field,omitempty"` // the offending argument, for "invalid_argument"
+}
+
 // JSONRPCResponse represents a JSON-RPC 2.0 response
 type JSONRPCResponse struct {
 	JSONRPC string      `json:"jsonrpc"`
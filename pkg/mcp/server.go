@@ -3,21 +3,49 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/jayzheng/vectcode/pkg/app"
+	"github.com/jayzheng/vectcode/pkg/chunker"
 	"github.com/jayzheng/vectcode/pkg/config"
 	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/metadata"
 	"github.com/jayzheng/vectcode/pkg/query"
 	"github.com/jayzheng/vectcode/pkg/vectorstore"
 )
 
-// Server implements an MCP server for VectCode
+// Server implements an MCP server for VectCode.
+//
+// handleRequest is safe for concurrent use: the only mutable state is the
+// in-flight request table, guarded by mu; the embedders only share an
+// http.Client, and the vector store serializes its writes. Tool calls are
+// read-only.
 type Server struct {
 	config      *config.Config
 	embedder    embedder.Embedder
 	vectorStore vectorstore.VectorStore
 	queryEngine *query.Engine
+	toolTimeout time.Duration
+
+	// metaStore locates project files, to read the code of chunks stored
+	// without it (vector_store.options.store_code: false)
+	metaStore metadata.Store
+
+	// resultFormatter renders search_code results as text, with
+	// query.AgentResultTemplate
+	resultFormatter *query.ResultFormatter
+
+	mu       sync.Mutex
+	inflight map[string]context.CancelFunc // cancel funcs of running requests, by ID
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // NewServer creates a new MCP server
@@ -28,63 +56,306 @@ func NewServer(configPath string) (*Server, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
-	// Initialize embedder
-	emb, err := embedder.New(cfg.Embeddings)
+	// Initialize embedder; stdout carries the protocol, so its warnings go
+	// to stderr
+	emb, err := embedder.New(cfg.Embeddings, embedder.WithWarnFunc(app.WarnTo(os.Stderr)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create embedder: %w", err)
 	}
 
 	// Initialize vector store
-	store, err := vectorstore.New(cfg.ToVectorStoreConfig())
+	store, err := vectorstore.New(cfg.ToVectorStoreConfigFor(emb))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vector store: %w", err)
 	}
 
-	// Create query engine
-	engine := query.NewEngine(emb, store)
+	metaStore, err := metadata.NewSQLiteStore(cfg.Metadata.DBPath)
+	if err != nil {
+		store.Close()
+		return nil, fmt.Errorf("failed to open metadata store: %w", err)
+	}
+	closeStores := func() {
+		store.Close()
+		metaStore.Close()
+	}
+
+	// Create query engine; agents often repeat a query, so cache results
+	// when query.cache_size is set
+	engineOpts, err := app.EngineOptions(cfg)
+	if err != nil {
+		closeStores()
+		return nil, err
+	}
+	engineOpts = append(engineOpts, query.WithCodeReader(app.CodeReader(metaStore)))
+	engine := query.NewEngine(emb, store, engineOpts...)
+
+	toolTimeout, err := toolTimeout(cfg.Query.ToolTimeout)
+	if err != nil {
+		closeStores()
+		return nil, err
+	}
+
+	formatter, err := query.NewResultFormatter(query.AgentResultTemplate)
+	if err != nil {
+		closeStores()
+		return nil, err
+	}
 
 	return &Server{
-		config:      cfg,
-		embedder:    emb,
-		vectorStore: store,
-		queryEngine: engine,
+		config:          cfg,
+		embedder:        emb,
+		vectorStore:     store,
+		queryEngine:     engine,
+		metaStore:       metaStore,
+		resultFormatter: formatter,
+		toolTimeout:     toolTimeout,
 	}, nil
 }
 
-// Close closes the server resources
-func (s *Server) Close() error {
-	if s.vectorStore != nil {
-		return s.vectorStore.Close()
+// DefaultToolTimeout bounds a tool call when query.tool_timeout is not set
+const DefaultToolTimeout = 30 * time.Second
+
+// ToolTimeoutEnv names the environment variable that overrides
+// query.tool_timeout, e.g. VECTCODE_TOOL_TIMEOUT=1m
+const ToolTimeoutEnv = "VECTCODE_TOOL_TIMEOUT"
+
+// errToolTimeout is returned for a tool call cut short by the tool timeout
+var errToolTimeout = errors.New("timed out")
+
+// toolTimeout returns the configured tool timeout, overridden by
+// ToolTimeoutEnv, or DefaultToolTimeout if neither is set
+func toolTimeout(configured time.Duration) (time.Duration, error) {
+	if env := os.Getenv(ToolTimeoutEnv); env != "" {
+		timeout, err := time.ParseDuration(env)
+		if err != nil || timeout <= 0 {
+			return 0, fmt.Errorf("invalid %s %q: must be a positive duration such as 30s", ToolTimeoutEnv, env)
+		}
+		return timeout, nil
 	}
-	return nil
+	if configured < 0 {
+		return 0, fmt.Errorf("invalid query.tool_timeout %s: must not be negative", configured)
+	}
+	if configured == 0 {
+		return DefaultToolTimeout, nil
+	}
+	return configured, nil
 }
 
-// Run starts the MCP server and handles requests
+// Close releases the server's resources: the vector store connection first,
+// then the metadata store, then the embedder if it holds any. It is safe to call more than once, as
+// Run already calls it on shutdown.
+func (s *Server) Close() error {
+	s.closeOnce.Do(func() {
+		var errs []error
+		if s.vectorStore != nil {
+			if err := s.vectorStore.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close vector store: %w", err))
+			}
+		}
+		if s.metaStore != nil {
+			if err := s.metaStore.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close metadata store: %w", err))
+			}
+		}
+		if closer, ok := s.embedder.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close embedder: %w", err))
+			}
+		}
+		s.closeErr = errors.Join(errs...)
+	})
+	return s.closeErr
+}
+
+// Run starts the MCP server and handles requests until end of input. See
+// RunContext.
 func (s *Server) Run(input io.Reader, output io.Writer) error {
+	return s.RunContext(context.Background(), input, output)
+}
+
+// RunContext starts the MCP server and handles requests. Each request runs
+// in its own goroutine with a context that notifications/cancelled can
+// cancel, so responses may be written out of order.
+//
+// RunContext returns at end of input, on an exit notification, or when ctx
+// is done (e.g. on a terminating signal), always after waiting for the
+// requests in flight so their responses are written. A shutdown request
+// waits for them too, closes the server's resources, and is answered with
+// an empty result; requests after it fail with ErrCodeShuttingDown until
+// exit or end of input.
+func (s *Server) RunContext(ctx context.Context, input io.Reader, output io.Writer) error {
+	// Requests are not cancelled with ctx, so in-flight ones can finish
+	reqBase, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		writeMu  sync.Mutex
+		writeErr error
+	)
+	write := func(resp *JSONRPCResponse) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if err := WriteResponse(output, resp); err != nil && writeErr == nil {
+			writeErr = err
+		}
+	}
+	failed := func() error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if writeErr != nil {
+			return fmt.Errorf("failed to write response: %w", writeErr)
+		}
+		return nil
+	}
+	defer wg.Wait()
+
+	// Input is read in its own goroutine so ctx can end Run while a read
+	// blocks; that goroutine then ends with the input. A single decoder
+	// keeps any input it buffered past the current request.
+	type message struct {
+		raw json.RawMessage
+		err error
+	}
+	messages := make(chan message)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		decoder := json.NewDecoder(input)
+		for {
+			var msg message
+			msg.err = decoder.Decode(&msg.raw)
+			select {
+			case messages <- msg:
+			case <-stop:
+				return
+			}
+			if msg.err != nil {
+				return
+			}
+		}
+	}()
+
+	shuttingDown := false
 	for {
-		req, err := ReadRequest(input)
-		if err != nil {
-			if err == io.EOF {
-				return nil
+		if err := failed(); err != nil {
+			return err
+		}
+
+		var msg message
+		select {
+		case msg = <-messages:
+		case <-ctx.Done():
+			wg.Wait()
+			return failed()
+		}
+		if err := msg.err; err != nil {
+			if errors.Is(err, io.EOF) {
+				wg.Wait()
+				return failed()
 			}
+			// The stream cannot be resynchronized after malformed JSON
+			write(NewErrorResponse(nil, -32700, fmt.Sprintf("Parse error: %v", err)))
+			return fmt.Errorf("failed to read request: %w", err)
+		}
+
+		req := &JSONRPCRequest{}
+		if err := json.Unmarshal(msg.raw, req); err != nil {
 			// Write error response and continue
-			resp := NewErrorResponse(nil, -32700, fmt.Sprintf("Parse error: %v", err))
-			WriteResponse(output, resp)
+			write(NewErrorResponse(nil, -32700, fmt.Sprintf("Parse error: %v", err)))
+			continue
+		}
+
+		// Notifications (including cancellations) are handled inline and
+		// never get a response
+		if req.ID == nil {
+			if req.Method == "exit" {
+				wg.Wait()
+				return failed()
+			}
+			s.handleRequest(reqBase, req)
 			continue
 		}
 
-		resp := s.handleRequest(req)
-		// Only write response if there is one (notifications return nil)
-		if resp != nil {
-			if err := WriteResponse(output, resp); err != nil {
-				return fmt.Errorf("failed to write response: %w", err)
+		if shuttingDown {
+			write(NewErrorResponse(req.ID, ErrCodeShuttingDown, "Server is shutting down"))
+			continue
+		}
+		if req.Method == "shutdown" {
+			shuttingDown = true
+			wg.Wait()
+			if err := s.Close(); err != nil {
+				write(NewErrorResponse(req.ID, -32603, fmt.Sprintf("Shutdown failed: %v", err)))
+				continue
 			}
+			write(NewSuccessResponse(req.ID, struct{}{}))
+			continue
+		}
+
+		// A second request with the ID of a running one would take over its
+		// cancellation, so it is rejected instead
+		reqCtx, ok := s.track(reqBase, req.ID)
+		if !ok {
+			write(NewErrorResponse(req.ID, -32600, fmt.Sprintf("Invalid Request: ID %v is already in use by a running request", req.ID)))
+			continue
 		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer s.untrack(req.ID)
+
+			resp := s.handleRequest(reqCtx, req)
+			// A cancelled request gets no response
+			if resp != nil && reqCtx.Err() == nil {
+				write(resp)
+			}
+		}()
+	}
+}
+
+// track registers a cancelable context for an in-flight request. It reports
+// false, registering nothing, if a request with the same ID is still running.
+func (s *Server) track(parent context.Context, id interface{}) (context.Context, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := requestKey(id)
+	if _, running := s.inflight[key]; running {
+		return nil, false
+	}
+	if s.inflight == nil {
+		s.inflight = make(map[string]context.CancelFunc)
+	}
+	ctx, cancel := context.WithCancel(parent)
+	s.inflight[key] = cancel
+	return ctx, true
+}
+
+// untrack releases a finished request's context
+func (s *Server) untrack(id interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.inflight[requestKey(id)]; ok {
+		cancel()
+		delete(s.inflight, requestKey(id))
 	}
 }
 
+// cancelRequest cancels an in-flight request's context, if it is still running
+func (s *Server) cancelRequest(id interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.inflight[requestKey(id)]; ok {
+		cancel()
+	}
+}
+
+// requestKey normalizes a JSON-RPC ID (string or number) for map lookups
+func requestKey(id interface{}) string {
+	return fmt.Sprintf("%T:%v", id, id)
+}
+
 // handleRequest processes a JSON-RPC request
-func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
+func (s *Server) handleRequest(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
 	// Check if this is a notification (no response needed)
 	if req.ID == nil {
 		// Notifications don't get responses, just handle them silently
@@ -92,7 +363,10 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 		case "notifications/initialized":
 			// Client initialized, nothing to do
 		case "notifications/cancelled":
-			// Request cancelled, nothing to do
+			var params CancelledParams
+			if err := json.Unmarshal(req.Params, &params); err == nil && params.RequestID != nil {
+				s.cancelRequest(params.RequestID)
+			}
 		}
 		return nil
 	}
@@ -103,7 +377,7 @@ func (s *Server) handleRequest(req *JSONRPCRequest) *JSONRPCResponse {
 	case "tools/list":
 		return s.handleToolsList(req)
 	case "tools/call":
-		return s.handleToolsCall(req)
+		return s.handleToolsCall(ctx, req)
 	default:
 		return NewErrorResponse(req.ID, -32601, fmt.Sprintf("Method not found: %s", req.Method))
 	}
@@ -135,6 +409,15 @@ func (s *Server) handleInitialize(req *JSONRPCRequest) *JSONRPCResponse {
 	return NewSuccessResponse(req.ID, result)
 }
 
+// testKindNames lists the names of chunker.TestKinds, for tool schemas
+func testKindNames() []string {
+	names := make([]string, len(chunker.TestKinds))
+	for i, kind := range chunker.TestKinds {
+		names[i] = string(kind)
+	}
+	return names
+}
+
 // Tool represents an MCP tool definition
 type Tool struct {
 	Name        string      `json:"name"`
@@ -142,8 +425,10 @@ type Tool struct {
 	InputSchema interface{} `json:"inputSchema"`
 }
 
-func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
-	tools := []Tool{
+// toolDefinitions returns the tools served by tools/list. Their input schemas
+// are also used to validate tools/call arguments.
+func toolDefinitions(defaultLimit int) []Tool {
+	return []Tool{
 		{
 			Name:        "search_code",
 			Description: "Search indexed codebases using semantic search. Returns relevant code chunks with file paths, line numbers, and code content.",
@@ -158,15 +443,96 @@ func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
 						"type":        "string",
 						"description": "Optional: filter results to a specific project name",
 					},
+					"path_prefix": map[string]interface{}{
+						"type":        "string",
+						"description": "Optional: only return results under this directory (e.g. 'internal/auth/')",
+					},
+					"packages": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional: only return results in these packages (e.g. ['core', 'api'])",
+					},
+					"boost_packages": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional: multiply the scores of results in packages, as 'package=factor' (e.g. ['core=1.5', 'legacy=0.5']), favoring them without filtering out the rest. Defaults to the configured query.package_boosts.",
+					},
+					"param_types": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional: only return functions taking every one of these parameter types (e.g. ['context.Context'])",
+					},
+					"return_types": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Optional: only return functions returning every one of these types (e.g. ['error'])",
+					},
+					"test_kind": map[string]interface{}{
+						"type":        "string",
+						"enum":        testKindNames(),
+						"description": "Optional: only return Go test functions of this kind: test, benchmark, fuzz, or example",
+					},
+					"exclude_test_kinds": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string", "enum": testKindNames()},
+						"description": "Optional: leave out Go test functions of these kinds (e.g. ['example'])",
+					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
-						"description": "Maximum number of results to return (default: 5)",
-						"default":     5,
+						"description": fmt.Sprintf("Maximum number of results to return (default: %d)", defaultLimit),
+						"default":     defaultLimit,
+						"minimum":     1,
+					},
+					"offset": map[string]interface{}{
+						"type":        "integer",
+						"description": "Skip this many top results to fetch the next page, e.g. 5 for results 6-10 (default: 0)",
+						"default":     0,
+						"minimum":     0,
+					},
+					"max_code_chars": map[string]interface{}{
+						"type":        "integer",
+						"description": fmt.Sprintf("Truncate each result's code to this many characters (default: %d). Use get_chunk to fetch the full code.", defaultMaxCodeChars),
+						"default":     defaultMaxCodeChars,
+						"minimum":     1,
+					},
+					"full": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return complete code for every result without truncation (default: false)",
+						"default":     false,
+					},
+					"structured": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return the results as JSON (also in structuredContent) with id, score, file, line_start, line_end, type, name, and code fields, instead of formatted text (default: false)",
+						"default":     false,
+					},
+					"with_callgraph": map[string]interface{}{
+						"type":        "boolean",
+						"description": "List the functions each result calls, resolved by name within its project, with their chunk IDs (default: false)",
+						"default":     false,
 					},
 				},
 				"required": []string{"query"},
 			},
 		},
+		{
+			Name:        "get_chunk",
+			Description: "Fetch a single indexed code chunk by ID with its complete code. IDs are shown in search_code results.",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"id": map[string]interface{}{
+						"type":        "string",
+						"description": "Chunk ID as returned by search_code",
+					},
+					"show_embedding": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Include a summary of the stored embedding vector (default: false)",
+						"default":     false,
+					},
+				},
+				"required": []string{"id"},
+			},
+		},
 		{
 			Name:        "list_projects",
 			Description: "List all indexed projects available for search.",
@@ -176,93 +542,328 @@ func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
 			},
 		},
 	}
+}
+
+// defaultLimit is the search_code limit used when a call gives none
+func (s *Server) defaultLimit() int {
+	if s.config == nil {
+		return vectorstore.DefaultSearchLimit
+	}
+	return s.config.Query.EffectiveLimit()
+}
+
+// toolSchema returns the input schema of a tool
+func (s *Server) toolSchema(name string) (map[string]interface{}, bool) {
+	for _, tool := range toolDefinitions(s.defaultLimit()) {
+		if tool.Name == name {
+			schema, ok := tool.InputSchema.(map[string]interface{})
+			return schema, ok
+		}
+	}
+	return nil, false
+}
 
+func (s *Server) handleToolsList(req *JSONRPCRequest) *JSONRPCResponse {
 	return NewSuccessResponse(req.ID, map[string]interface{}{
-		"tools": tools,
+		"tools": toolDefinitions(s.defaultLimit()),
 	})
 }
 
 // ToolCallParams represents parameters for a tool call
 type ToolCallParams struct {
-	Name      string                 `json:"name"`
-	Arguments map[string]interface{} `json:"arguments"`
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// errProjectNotFound means search_code was filtered on a project that has
+// no chunks in the vector store
+var errProjectNotFound = errors.New("project not found")
+
+// errorData maps a tool failure to its JSON-RPC error code and data. The
+// reasons are invalid_argument, embedder_unavailable,
+// vector_store_unavailable, model_unavailable, chunk_not_found,
+// project_not_found, dimension_mismatch, collection_missing, timeout, and
+// internal.
+func errorData(err error) (int, ErrorData) {
+	var argErr *ArgumentError
+	switch {
+	case errors.Is(err, errToolTimeout):
+		return ErrCodeTimeout, ErrorData{Reason: "timeout", Retryable: true}
+	case errors.As(err, &argErr):
+		return -32602, ErrorData{Reason: "invalid_argument", Field: argErr.Field}
+	case errors.Is(err, embedder.ErrUnavailable):
+		return ErrCodeUnavailable, ErrorData{Reason: "embedder_unavailable", Retryable: true}
+	case errors.Is(err, vectorstore.ErrUnavailable):
+		return ErrCodeUnavailable, ErrorData{Reason: "vector_store_unavailable", Retryable: true}
+	case errors.Is(err, embedder.ErrModelUnavailable):
+		return ErrCodeModelUnavailable, ErrorData{Reason: "model_unavailable"}
+	case errors.Is(err, vectorstore.ErrChunkNotFound):
+		return ErrCodeNotFound, ErrorData{Reason: "chunk_not_found"}
+	case errors.Is(err, errProjectNotFound):
+		return ErrCodeProjectNotFound, ErrorData{Reason: "project_not_found"}
+	case errors.Is(err, vectorstore.ErrDimensionMismatch):
+		return ErrCodeIndexMismatch, ErrorData{Reason: "dimension_mismatch"}
+	case errors.Is(err, vectorstore.ErrCollectionMissing):
+		return ErrCodeIndexMismatch, ErrorData{Reason: "collection_missing"}
+	default:
+		return -32603, ErrorData{Reason: "internal"}
+	}
 }
 
-func (s *Server) handleToolsCall(req *JSONRPCRequest) *JSONRPCResponse {
+// toolError is the error response for a failed tool call, its message
+// saying what failed and its code and data identifying why
+func toolError(id interface{}, what string, err error) *JSONRPCResponse {
+	code, data := errorData(err)
+	resp := NewErrorResponse(id, code, fmt.Sprintf("%s: %v", what, err))
+	resp.Error.Data = data
+	return resp
+}
+
+func (s *Server) handleToolsCall(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
 	var params ToolCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
 		return NewErrorResponse(req.ID, -32602, fmt.Sprintf("Invalid params: %v", err))
 	}
 
+	schema, ok := s.toolSchema(params.Name)
+	if !ok {
+		return NewErrorResponse(req.ID, -32601, fmt.Sprintf("Tool not found: %s", params.Name))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.toolTimeout)
+	defer cancel()
+	resp := s.callTool(ctx, req.ID, params, schema)
+
+	// A call cut short fails with whatever error the embedder or store made
+	// of the expired context; report the timeout itself instead
+	if resp.Error != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return toolError(req.ID, "Tool call failed", fmt.Errorf("%s %w after %s", params.Name, errToolTimeout, s.toolTimeout))
+	}
+	return resp
+}
+
+// callTool runs the named tool, checking its arguments against schema
+func (s *Server) callTool(ctx context.Context, id interface{}, params ToolCallParams, schema map[string]interface{}) *JSONRPCResponse {
 	switch params.Name {
 	case "search_code":
-		return s.handleSearchCode(req.ID, params.Arguments)
-	case "list_projects":
-		return s.handleListProjects(req.ID)
+		args := searchCodeArgs{Limit: s.defaultLimit(), MaxCodeChars: defaultMaxCodeChars}
+		if err := decodeArguments(schema, params.Arguments, &args); err != nil {
+			return toolError(id, "Invalid params", err)
+		}
+		return s.handleSearchCode(ctx, id, args)
+	case "get_chunk":
+		var args getChunkArgs
+		if err := decodeArguments(schema, params.Arguments, &args); err != nil {
+			return toolError(id, "Invalid params", err)
+		}
+		return s.handleGetChunk(ctx, id, args)
 	default:
-		return NewErrorResponse(req.ID, -32601, fmt.Sprintf("Tool not found: %s", params.Name))
+		if err := decodeArguments(schema, params.Arguments, &struct{}{}); err != nil {
+			return toolError(id, "Invalid params", err)
+		}
+		return s.handleListProjects(ctx, id)
 	}
 }
 
-func (s *Server) handleSearchCode(id interface{}, args map[string]interface{}) *JSONRPCResponse {
-	// Extract query parameter
-	queryText, ok := args["query"].(string)
-	if !ok || queryText == "" {
-		return NewErrorResponse(id, -32602, "Missing required parameter: query")
+func (s *Server) handleSearchCode(ctx context.Context, id interface{}, args searchCodeArgs) *JSONRPCResponse {
+	if strings.TrimSpace(args.Query) == "" {
+		return toolError(id, "Invalid params", &ArgumentError{Field: "query", Problem: "must not be empty or whitespace"})
+	}
+
+	maxCodeChars := args.MaxCodeChars
+	if args.Full {
+		maxCodeChars = 0
 	}
 
-	// Extract optional parameters
-	limit := 5
-	if l, ok := args["limit"].(float64); ok {
-		limit = int(l)
+	if args.TestKind != "" {
+		if _, err := chunker.ParseTestKinds([]string{args.TestKind}); err != nil {
+			return toolError(id, "Invalid params", &ArgumentError{Field: "test_kind", Problem: err.Error()})
+		}
+	}
+	if _, err := chunker.ParseTestKinds(args.ExcludeTestKinds); err != nil {
+		return toolError(id, "Invalid params", &ArgumentError{Field: "exclude_test_kinds", Problem: err.Error()})
 	}
 
-	var filters map[string]interface{}
-	if project, ok := args["project"].(string); ok && project != "" {
-		filters = map[string]interface{}{
-			"project": project,
+	boosts := s.config.Query.PackageBoosts
+	if len(args.BoostPackages) > 0 {
+		var err error
+		if boosts, err = query.ParsePackageBoosts(args.BoostPackages); err != nil {
+			return toolError(id, "Invalid params", &ArgumentError{Field: "boost_packages", Problem: err.Error()})
 		}
 	}
 
+	opts := vectorstore.SearchOptions{
+		Limit:            args.Limit,
+		Offset:           args.Offset,
+		PathPrefix:       args.PathPrefix,
+		Packages:         args.Packages,
+		PackageBoosts:    boosts,
+		ParamTypes:       args.ParamTypes,
+		ReturnTypes:      args.ReturnTypes,
+		TestKind:         args.TestKind,
+		ExcludeTestKinds: args.ExcludeTestKinds,
+		IncludeCallees:   args.WithCallgraph,
+	}
+	if args.Project != "" {
+		opts.Projects = []string{args.Project}
+	}
+
 	// Execute search
-	ctx := context.Background()
-	results, err := s.queryEngine.Query(ctx, queryText, limit, filters)
+	results, err := s.queryEngine.Query(ctx, args.Query, opts)
 	if err != nil {
-		return NewErrorResponse(id, -32603, fmt.Sprintf("Search failed: %v", err))
+		return toolError(id, "Search failed", err)
+	}
+	if len(results) == 0 && args.Project != "" {
+		if err := s.checkProject(ctx, args.Project); err != nil {
+			return toolError(id, "Search failed", err)
+		}
 	}
 
-	// Format results
+	if args.Structured {
+		structured := map[string]interface{}{
+			"results": structuredResults(results, args.Offset, maxCodeChars),
+		}
+		data, err := json.Marshal(structured)
+		if err != nil {
+			return NewErrorResponse(id, -32603, fmt.Sprintf("Failed to encode results: %v", err))
+		}
+		// The JSON is repeated as text for clients that ignore
+		// structuredContent
+		return NewSuccessResponse(id, map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"type": "text",
+					"text": string(data),
+				},
+			},
+			"structuredContent": structured,
+		})
+	}
+
+	return NewSuccessResponse(id, map[string]interface{}{
+		"content": []map[string]interface{}{
+			{
+				"type": "text",
+				"text": formatSearchResults(s.resultFormatter, results, args.Offset, maxCodeChars),
+			},
+		},
+	})
+}
+
+// structuredResults lists search results as JSON objects for programmatic
+// clients, with code truncated as in the text format
+func structuredResults(results []vectorstore.SearchResult, offset, maxCodeChars int) []map[string]interface{} {
 	formattedResults := make([]map[string]interface{}, len(results))
 	for i, result := range results {
 		chunk := result.Chunk
-		formattedResults[i] = map[string]interface{}{
+		code, truncated := truncateCode(chunk.Code, maxCodeChars)
+		formatted := map[string]interface{}{
+			"rank":       offset + i + 1,
+			"id":         chunk.ID,
 			"score":      result.Score,
 			"project":    chunk.Project,
 			"file":       chunk.FilePath,
 			"line_start": chunk.LineStart,
 			"line_end":   chunk.LineEnd,
+			"language":   chunk.Language,
 			"type":       chunk.ChunkType,
-			"name":       chunk.Name,
-			"code":       chunk.Code,
+			"name":       chunk.QualifiedName(),
+			"signature":  chunk.Signature,
+			"summary":    chunk.Summary,
+			"code":       code,
+			"truncated":  truncated,
 			"doc_string": chunk.DocString,
 		}
+		if chunk.TestKind != "" {
+			formatted["test_kind"] = chunk.TestKind
+		}
+		if chunk.Author != "" {
+			formatted["author"] = chunk.Author
+			formatted["last_commit"] = chunk.LastCommit.Format(time.RFC3339)
+		}
+		if len(result.Callees) > 0 {
+			calls := make([]map[string]interface{}, len(result.Callees))
+			for j, callee := range result.Callees {
+				calls[j] = map[string]interface{}{
+					"id":         callee.ID,
+					"type":       callee.ChunkType,
+					"name":       callee.QualifiedName(),
+					"signature":  callee.Signature,
+					"file":       callee.FilePath,
+					"line_start": callee.LineStart,
+				}
+			}
+			formatted["calls"] = calls
+		}
+		formattedResults[i] = formatted
+	}
+	return formattedResults
+}
+
+// checkProject returns errProjectNotFound if project is not indexed, telling
+// a search of a misspelled project apart from one with no matches. A store
+// error is ignored, leaving the search's empty result.
+func (s *Server) checkProject(ctx context.Context, project string) error {
+	projects, err := s.vectorStore.ListProjects(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, name := range projects {
+		if name == project {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q is not indexed (list_projects shows the indexed projects)", errProjectNotFound, project)
+}
+
+func (s *Server) handleGetChunk(ctx context.Context, id interface{}, args getChunkArgs) *JSONRPCResponse {
+	chunkID := args.ID
+	if chunkID == "" {
+		return toolError(id, "Invalid params", &ArgumentError{Field: "id", Problem: "must not be empty"})
+	}
+
+	chunk, err := s.vectorStore.GetChunk(ctx, chunkID)
+	if err != nil {
+		return toolError(id, "Failed to get chunk", err)
+	}
+	if chunk.Code == "" && s.metaStore != nil {
+		results := []vectorstore.SearchResult{{Chunk: *chunk}}
+		app.CodeReader(s.metaStore)(ctx, results)
+		chunk = &results[0].Chunk
 	}
 
+	text := fmt.Sprintf("Project: %s\n", chunk.Project)
+	text += fmt.Sprintf("File: %s:%d-%d\n", chunk.FilePath, chunk.LineStart, chunk.LineEnd)
+	text += fmt.Sprintf("Type: %s %s\n", chunk.ChunkType, chunk.QualifiedName())
+	if chunk.Summary != "" {
+		text += fmt.Sprintf("Summary: %s\n", chunk.Summary)
+	}
+	if chunk.DocString != "" {
+		text += fmt.Sprintf("Documentation:\n%s\n", chunk.DocString)
+	}
+	if args.ShowEmbedding {
+		vec, err := s.vectorStore.GetEmbedding(ctx, chunkID)
+		if err != nil {
+			return toolError(id, "Failed to get embedding", err)
+		}
+		text += fmt.Sprintf("Embedding: %s\n", embedder.Summarize(vec, 8))
+	}
+	text += fmt.Sprintf("\nCode:\n```%s\n%s\n```\n", chunk.Language, chunk.Code)
+
 	return NewSuccessResponse(id, map[string]interface{}{
 		"content": []map[string]interface{}{
 			{
 				"type": "text",
-				"text": formatSearchResults(results),
+				"text": text,
 			},
 		},
 	})
 }
 
-func (s *Server) handleListProjects(id interface{}) *JSONRPCResponse {
-	ctx := context.Background()
+func (s *Server) handleListProjects(ctx context.Context, id interface{}) *JSONRPCResponse {
 	projects, err := s.vectorStore.ListProjects(ctx)
 	if err != nil {
-		return NewErrorResponse(id, -32603, fmt.Sprintf("Failed to list projects: %v", err))
+		return toolError(id, "Failed to list projects", err)
 	}
 
 	var text string
@@ -285,22 +886,32 @@ func (s *Server) handleListProjects(id interface{}) *JSONRPCResponse {
 	})
 }
 
-func formatSearchResults(results []vectorstore.SearchResult) string {
+// defaultMaxCodeChars keeps search_code responses within agents' context budgets
+const defaultMaxCodeChars = 2000
+
+// truncateCode shortens code to at most maxChars bytes on a UTF-8 boundary.
+// It reports whether the code was truncated. maxChars <= 0 disables truncation.
+func truncateCode(code string, maxChars int) (string, bool) {
+	if maxChars <= 0 || len(code) <= maxChars {
+		return code, false
+	}
+	return chunker.Truncate(code, maxChars), true
+}
+
+// formatSearchResults renders a page of results, numbering them from offset+1
+func formatSearchResults(formatter *query.ResultFormatter, results []vectorstore.SearchResult, offset, maxCodeChars int) string {
 	if len(results) == 0 {
 		return "No results found."
 	}
 
-	output := fmt.Sprintf("Found %d results:\n\n", len(results))
+	var output strings.Builder
+	fmt.Fprintf(&output, "Found %d results:\n\n", len(results))
 	for i, result := range results {
-		chunk := result.Chunk
-		output += fmt.Sprintf("=== Result %d (Score: %.4f) ===\n", i+1, result.Score)
-		output += fmt.Sprintf("Project: %s\n", chunk.Project)
-		output += fmt.Sprintf("File: %s:%d-%d\n", chunk.FilePath, chunk.LineStart, chunk.LineEnd)
-		output += fmt.Sprintf("Type: %s %s\n", chunk.ChunkType, chunk.Name)
-		if chunk.DocString != "" {
-			output += fmt.Sprintf("Documentation:\n%s\n", chunk.DocString)
+		view := query.NewResultView(offset+i+1, result)
+		view.Code, view.Truncated = truncateCode(result.Chunk.Code, maxCodeChars)
+		if err := formatter.Format(&output, view); err != nil {
+			fmt.Fprintf(&output, "(failed to format result %d: %v)\n\n", i+1, err)
 		}
-		output += fmt.Sprintf("\nCode:\n```%s\n%s\n```\n\n", chunk.Language, chunk.Code)
 	}
-	return output
+	return output.String()
 }
@@ -0,0 +1,198 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/config"
+	"github.com/jayzheng/vectcode/pkg/query"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// fakeEmbedder embeds each text as a short vector derived from its length
+type fakeEmbedder struct {
+	calls atomic.Int64
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	e.calls.Add(1)
+	return []float64{float64(len(text)), 1, 0}, nil
+}
+
+func (e *fakeEmbedder) EmbedBatch(ctx context.Context, texts []string) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i], _ = e.Embed(ctx, text)
+	}
+	return vectors, nil
+}
+
+func (e *fakeEmbedder) Dimensions() int { return 3 }
+
+// fakeStore answers every search with the same results. Methods the tests
+// do not reach panic through the nil embedded interface.
+type fakeStore struct {
+	vectorstore.VectorStore
+	searches atomic.Int64
+}
+
+func (s *fakeStore) Search(ctx context.Context, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	s.searches.Add(1)
+	results := make([]vectorstore.SearchResult, 3)
+	for i := range results {
+		results[i] = vectorstore.SearchResult{
+			Chunk: chunker.CodeChunk{
+				ID:        fmt.Sprintf("chunk-%d", i),
+				Project:   "demo",
+				FilePath:  "pkg/demo/demo.go",
+				Package:   "demo",
+				Language:  "go",
+				ChunkType: chunker.ChunkTypeFunction,
+				Name:      fmt.Sprintf("Func%d", i),
+				Code:      fmt.Sprintf("func Func%d() {}", i),
+				LineStart: i*10 + 1,
+				LineEnd:   i*10 + 3,
+			},
+			Score: 0.9 - float64(i)/10,
+		}
+	}
+	return results, nil
+}
+
+func (s *fakeStore) GetChunksByName(ctx context.Context, projectName string, names []string) ([]chunker.CodeChunk, error) {
+	return nil, nil
+}
+
+func (s *fakeStore) ListProjects(ctx context.Context) ([]string, error) {
+	return []string{"demo"}, nil
+}
+
+func (s *fakeStore) Close() error { return nil }
+
+func newTestServer(t *testing.T, emb *fakeEmbedder, store *fakeStore) *Server {
+	formatter, err := query.NewResultFormatter(query.AgentResultTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{
+		config:          config.DefaultConfig(),
+		embedder:        emb,
+		vectorStore:     store,
+		queryEngine:     query.NewEngine(emb, store, query.WithCache(8, time.Minute)),
+		resultFormatter: formatter,
+		toolTimeout:     DefaultToolTimeout,
+	}
+}
+
+func searchRequest(id int, args map[string]interface{}) (*JSONRPCRequest, error) {
+	params, err := json.Marshal(map[string]interface{}{"name": "search_code", "arguments": args})
+	if err != nil {
+		return nil, err
+	}
+	return &JSONRPCRequest{JSONRPC: "2.0", ID: id, Method: "tools/call", Params: params}, nil
+}
+
+// TestHandleRequestConcurrentSearches fires search_code calls from many
+// goroutines at once; run with -race to check handleRequest shares no
+// unguarded state across calls.
+func TestHandleRequestConcurrentSearches(t *testing.T) {
+	emb := &fakeEmbedder{}
+	store := &fakeStore{}
+	server := newTestServer(t, emb, store)
+	defer server.Close()
+
+	const goroutines = 16
+	const callsEach = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*callsEach)
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for c := 0; c < callsEach; c++ {
+				id := g*callsEach + c
+				args := map[string]interface{}{
+					// A few distinct queries, so calls both hit and fill the cache
+					"query":      fmt.Sprintf("parse config %d", id%5),
+					"limit":      2,
+					"structured": id%2 == 0,
+				}
+				req, err := searchRequest(id, args)
+				if err != nil {
+					errs <- err
+					continue
+				}
+				ctx, ok := server.track(context.Background(), id)
+				if !ok {
+					errs <- fmt.Errorf("call %d: ID already in flight", id)
+					continue
+				}
+				resp := server.handleRequest(ctx, req)
+				server.untrack(id)
+
+				switch {
+				case resp == nil:
+					errs <- fmt.Errorf("call %d: no response", id)
+				case resp.Error != nil:
+					errs <- fmt.Errorf("call %d: %s", id, resp.Error.Message)
+				case resp.ID != id:
+					errs <- fmt.Errorf("call %d: answered with ID %v", id, resp.ID)
+				default:
+					data, err := json.Marshal(resp.Result)
+					if err != nil {
+						errs <- fmt.Errorf("call %d: %v", id, err)
+					} else if !strings.Contains(string(data), "Func0") {
+						errs <- fmt.Errorf("call %d: results missing from %s", id, data)
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if got := store.searches.Load(); got == 0 {
+		t.Errorf("store searched %d times, want at least 1", got)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.inflight) != 0 {
+		t.Errorf("%d requests still tracked as in flight", len(server.inflight))
+	}
+}
+
+// TestTrackRejectsDuplicateID checks that a request reusing the ID of a
+// running one cannot take over its cancellation.
+func TestTrackRejectsDuplicateID(t *testing.T) {
+	server := &Server{}
+
+	ctx, ok := server.track(context.Background(), 1)
+	if !ok {
+		t.Fatal("first request with ID 1 was rejected")
+	}
+	if _, ok := server.track(context.Background(), 1); ok {
+		t.Fatal("second request with ID 1 was accepted while the first is running")
+	}
+
+	server.cancelRequest(1)
+	if ctx.Err() == nil {
+		t.Fatal("cancelling ID 1 did not cancel the first request")
+	}
+	server.untrack(1)
+
+	if _, ok := server.track(context.Background(), 1); !ok {
+		t.Fatal("ID 1 was rejected after its request finished")
+	}
+}
@@ -2,9 +2,18 @@ package metadata
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 )
 
+// Errors returned (wrapped) by Store implementations; test with errors.Is
+var (
+	ErrGroupNotFound   = errors.New("group not found")
+	ErrProjectNotFound = errors.New("project not found")
+	ErrFileNotFound    = errors.New("file not found")
+)
+
 // Group represents a logical grouping of projects
 type Group struct {
 	ID          int64
@@ -19,6 +28,7 @@ type Project struct {
 	ID             int64
 	Name           string
 	Path           string
+	Paths          []string
 	Language       string
 	Description    string
 	GroupID        *int64     // NULL if not in a group
@@ -28,6 +38,65 @@ type Project struct {
 	LastModifiedAt *time.Time // NULL if unknown
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
+
+	// Embedding model the project was indexed with
+	EmbeddingProvider   string
+	EmbeddingModel      string
+	EmbeddingDimensions int
+
+	// ChunkTypes the project was restricted to when indexed; empty means all
+	ChunkTypes []string
+
+	// Root is the directory chunk and file paths are relative to; empty for
+	// projects indexed when chunks recorded the paths as walked
+	Root string
+
+	// Collection is the vector store collection holding the project's chunks;
+	// empty for projects indexed before it was recorded
+	Collection string
+
+	// Repository the project was cloned from by index --repo, the ref
+	// requested (empty for the default branch), and the commit indexed
+	RepoURL    string
+	RepoRef    string
+	RepoCommit string
+
+	// TextContext lists the context embedded with each chunk's text beyond
+	// the chunk itself, e.g. TextContextProject; empty means none
+	TextContext []string
+}
+
+// Context a project's chunks can be embedded with, as recorded in
+// Project.TextContext
+const (
+	TextContextProject = "project" // group and description (embeddings.project_context)
+	TextContextFile    = "file"    // the file's declarations (index.file_context)
+)
+
+// RootPath returns Root, or the first project path for projects indexed
+// before Root was recorded
+func (p *Project) RootPath() string {
+	if p.Root != "" {
+		return p.Root
+	}
+	return p.AllPaths()[0]
+}
+
+// AllPaths returns every directory indexed into the project. Path is the
+// first of Paths; projects recorded before multi-path indexing only have Path.
+func (p *Project) AllPaths() []string {
+	if len(p.Paths) > 0 {
+		return p.Paths
+	}
+	return []string{p.Path}
+}
+
+// QualifiedName is the name a project is recorded under when another group
+// already has a project of the same name, e.g. "team-b/api". Project names
+// are unique across groups, and the name also identifies a project's chunks
+// in the vector store, so the group keeps the two apart.
+func QualifiedName(group, name string) string {
+	return group + "/" + name
 }
 
 // File represents a source file in a project
@@ -41,11 +110,39 @@ type File struct {
 	FileHash       string // SHA256 hash
 }
 
+// ProjectSort orders listed projects
+type ProjectSort string
+
+const (
+	SortByName   ProjectSort = "name"   // alphabetically (default)
+	SortByRecent ProjectSort = "recent" // most recently indexed first
+	SortByChunks ProjectSort = "chunks" // most chunks first
+)
+
+// ProjectSorts lists the valid orders, for flag help and validation
+var ProjectSorts = []ProjectSort{SortByName, SortByRecent, SortByChunks}
+
+// ParseProjectSort validates an order name; empty means SortByName
+func ParseProjectSort(name string) (ProjectSort, error) {
+	if name == "" {
+		return SortByName, nil
+	}
+	for _, by := range ProjectSorts {
+		if ProjectSort(name) == by {
+			return by, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sort order %q (expected one of name, recent, chunks)", name)
+}
+
 // ProjectFilter for querying projects
 type ProjectFilter struct {
 	GroupID   *int64
 	GroupName string
 	Name      string
+
+	// Sort orders the projects; ties and the default are by name
+	Sort ProjectSort
 }
 
 // Store is the interface for metadata storage
@@ -77,4 +174,8 @@ type Store interface {
 	// Helpers
 	GetProjectsByGroup(ctx context.Context, groupName string) ([]Project, error)
 	GetStaleFiles(ctx context.Context, projectID int64) ([]File, error) // Files where last_modified_at > last_indexed_at
+
+	// Maintenance
+	Vacuum(ctx context.Context) error                       // Rebuild the database file and refresh planner statistics
+	DeleteOrphanedFiles(ctx context.Context) (int64, error) // Remove files whose project no longer exists
 }
@@ -1,5 +1,10 @@
 package metadata
 
+import (
+	"database/sql"
+	"fmt"
+)
+
 const schema = `
 -- Groups table
 CREATE TABLE IF NOT EXISTS groups (
@@ -45,3 +50,60 @@ CREATE TABLE IF NOT EXISTS files (
 CREATE INDEX IF NOT EXISTS idx_files_project ON files(project_id);
 CREATE INDEX IF NOT EXISTS idx_files_modified ON files(project_id, last_modified_at);
 `
+
+// migrations run in order after schema. The database's user_version records
+// how many have been applied, so existing databases pick up new columns.
+var migrations = []string{
+	// 1: embedding model used to index each project
+	`ALTER TABLE projects ADD COLUMN embedding_provider TEXT;
+	 ALTER TABLE projects ADD COLUMN embedding_model TEXT;
+	 ALTER TABLE projects ADD COLUMN embedding_dimensions INTEGER DEFAULT 0;`,
+
+	// 2: every directory indexed into a project, as a JSON array
+	`ALTER TABLE projects ADD COLUMN paths TEXT;`,
+
+	// 3: chunk types a project was restricted to, as a JSON array
+	`ALTER TABLE projects ADD COLUMN chunk_types TEXT;`,
+
+	// 4: directory chunk file paths are relative to
+	`ALTER TABLE projects ADD COLUMN root TEXT;`,
+
+	// 5: vector store collection holding a project's chunks
+	`ALTER TABLE projects ADD COLUMN collection TEXT;`,
+
+	// 6: remote repository a project was cloned from, and the commit indexed
+	`ALTER TABLE projects ADD COLUMN repo_url TEXT;
+	 ALTER TABLE projects ADD COLUMN repo_ref TEXT;
+	 ALTER TABLE projects ADD COLUMN repo_commit TEXT;`,
+
+	// 7: context embedded with each chunk's text, as a JSON array
+	`ALTER TABLE projects ADD COLUMN text_context TEXT;`,
+}
+
+// migrate applies any migrations newer than the database's user_version
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -3,12 +3,29 @@ package metadata
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
+// busyTimeout is how long a statement waits for another process's write
+// lock before failing with SQLITE_BUSY
+const busyTimeout = 5 * time.Second
+
+// busyRetries is how many more times a write failing with SQLITE_BUSY is
+// attempted. The busy timeout covers most contention, but SQLite returns
+// SQLITE_BUSY at once when waiting could deadlock.
+const busyRetries = 3
+
+// busyRetryDelay is the wait before the first retry, doubled for each next
+const busyRetryDelay = 100 * time.Millisecond
+
 // SQLiteStore implements Store using SQLite
 type SQLiteStore struct {
 	db *sql.DB
@@ -16,15 +33,18 @@ type SQLiteStore struct {
 
 // NewSQLiteStore creates a new SQLite metadata store
 func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", sqliteDSN(dbPath))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	// One connection serializes this process's writes, so only other
+	// processes contend for the write lock
+	db.SetMaxOpenConns(1)
 
-	// Enable foreign keys
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+	// Connect now so a bad path or pragma fails here
+	if err := db.Ping(); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
 	// Run migrations
@@ -32,10 +52,55 @@ func NewSQLiteStore(dbPath string) (*SQLiteStore, error) {
 		db.Close()
 		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
 
 	return &SQLiteStore{db: db}, nil
 }
 
+// sqliteDSN adds the pragmas every connection needs to a database path:
+// foreign keys, a busy timeout so concurrent vectcode processes wait for each
+// other instead of failing with "database is locked", and WAL journaling so
+// readers do not block a writer
+func sqliteDSN(dbPath string) string {
+	sep := "?"
+	if strings.Contains(dbPath, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)",
+		dbPath, sep, busyTimeout.Milliseconds())
+}
+
+// isBusy reports whether err is SQLite's SQLITE_BUSY or SQLITE_LOCKED
+func isBusy(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	code := sqliteErr.Code() & 0xff // strip the extended result code
+	return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+}
+
+// exec runs a write statement, retrying it with backoff while the database
+// is busy
+func (s *SQLiteStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	delay := busyRetryDelay
+	for attempt := 0; ; attempt++ {
+		result, err := s.db.ExecContext(ctx, query, args...)
+		if err == nil || attempt == busyRetries || !isBusy(err) {
+			return result, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
 // Close closes the database connection
 func (s *SQLiteStore) Close() error {
 	return s.db.Close()
@@ -43,7 +108,7 @@ func (s *SQLiteStore) Close() error {
 
 // CreateGroup creates a new group
 func (s *SQLiteStore) CreateGroup(ctx context.Context, name, description string) (*Group, error) {
-	result, err := s.db.ExecContext(ctx,
+	result, err := s.exec(ctx,
 		"INSERT INTO groups (name, description) VALUES (?, ?)",
 		name, description)
 	if err != nil {
@@ -65,7 +130,7 @@ func (s *SQLiteStore) GetGroup(ctx context.Context, name string) (*Group, error)
 		"SELECT id, name, description, created_at, updated_at FROM groups WHERE name = ?",
 		name).Scan(&group.ID, &group.Name, &group.Description, &group.CreatedAt, &group.UpdatedAt)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("group not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get group: %w", err)
@@ -107,7 +172,7 @@ func (s *SQLiteStore) ListGroups(ctx context.Context) ([]Group, error) {
 
 // UpdateGroup updates a group's description
 func (s *SQLiteStore) UpdateGroup(ctx context.Context, name, description string) error {
-	result, err := s.db.ExecContext(ctx,
+	result, err := s.exec(ctx,
 		"UPDATE groups SET description = ?, updated_at = CURRENT_TIMESTAMP WHERE name = ?",
 		description, name)
 	if err != nil {
@@ -119,7 +184,7 @@ func (s *SQLiteStore) UpdateGroup(ctx context.Context, name, description string)
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("group not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, name)
 	}
 
 	return nil
@@ -127,7 +192,7 @@ func (s *SQLiteStore) UpdateGroup(ctx context.Context, name, description string)
 
 // DeleteGroup deletes a group (sets projects' group_id to NULL)
 func (s *SQLiteStore) DeleteGroup(ctx context.Context, name string) error {
-	result, err := s.db.ExecContext(ctx, "DELETE FROM groups WHERE name = ?", name)
+	result, err := s.exec(ctx, "DELETE FROM groups WHERE name = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete group: %w", err)
 	}
@@ -137,7 +202,7 @@ func (s *SQLiteStore) DeleteGroup(ctx context.Context, name string) error {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("group not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, name)
 	}
 
 	return nil
@@ -145,11 +210,16 @@ func (s *SQLiteStore) DeleteGroup(ctx context.Context, name string) error {
 
 // CreateProject creates a new project
 func (s *SQLiteStore) CreateProject(ctx context.Context, project *Project) error {
-	result, err := s.db.ExecContext(ctx,
-		`INSERT INTO projects (name, path, language, description, group_id, chunk_count, last_indexed_at, last_modified_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	result, err := s.exec(ctx,
+		`INSERT INTO projects (name, path, language, description, group_id, chunk_count, last_indexed_at, last_modified_at,
+		                       embedding_provider, embedding_model, embedding_dimensions, paths, chunk_types, root, collection,
+		                       repo_url, repo_ref, repo_commit, text_context)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		project.Name, project.Path, project.Language, project.Description,
-		project.GroupID, project.ChunkCount, project.LastIndexedAt, project.LastModifiedAt)
+		project.GroupID, project.ChunkCount, project.LastIndexedAt, project.LastModifiedAt,
+		project.EmbeddingProvider, project.EmbeddingModel, project.EmbeddingDimensions,
+		encodeList(project.Paths), encodeList(project.ChunkTypes), project.Root, project.Collection,
+		project.RepoURL, project.RepoRef, project.RepoCommit, encodeList(project.TextContext))
 	if err != nil {
 		return fmt.Errorf("failed to create project: %w", err)
 	}
@@ -166,28 +236,33 @@ func (s *SQLiteStore) CreateProject(ctx context.Context, project *Project) error
 	return nil
 }
 
-// GetProject retrieves a project by name
-func (s *SQLiteStore) GetProject(ctx context.Context, name string) (*Project, error) {
+// projectColumns is the column list read by scanProject
+const projectColumns = `p.id, p.name, p.path, p.language, p.description, p.group_id, g.name,
+	p.chunk_count, p.last_indexed_at, p.last_modified_at, p.created_at, p.updated_at,
+	COALESCE(p.embedding_provider, ''), COALESCE(p.embedding_model, ''), COALESCE(p.embedding_dimensions, 0),
+	p.paths, p.chunk_types, COALESCE(p.root, ''), COALESCE(p.collection, ''),
+	COALESCE(p.repo_url, ''), COALESCE(p.repo_ref, ''), COALESCE(p.repo_commit, ''), p.text_context`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanProject scans a row selected with projectColumns
+func scanProject(row rowScanner) (*Project, error) {
 	var project Project
 	var groupID sql.NullInt64
 	var groupName sql.NullString
 	var lastIndexedAt, lastModifiedAt sql.NullTime
+	var paths, chunkTypes, textContext sql.NullString
 
-	err := s.db.QueryRowContext(ctx,
-		`SELECT p.id, p.name, p.path, p.language, p.description, p.group_id, g.name,
-		        p.chunk_count, p.last_indexed_at, p.last_modified_at, p.created_at, p.updated_at
-		 FROM projects p
-		 LEFT JOIN groups g ON p.group_id = g.id
-		 WHERE p.name = ?`,
-		name).Scan(&project.ID, &project.Name, &project.Path, &project.Language, &project.Description,
-		&groupID, &groupName, &project.ChunkCount, &lastIndexedAt, &lastModifiedAt,
-		&project.CreatedAt, &project.UpdatedAt)
-
-	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("project not found: %s", name)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to get project: %w", err)
+	if err := row.Scan(&project.ID, &project.Name, &project.Path, &project.Language,
+		&project.Description, &groupID, &groupName, &project.ChunkCount,
+		&lastIndexedAt, &lastModifiedAt, &project.CreatedAt, &project.UpdatedAt,
+		&project.EmbeddingProvider, &project.EmbeddingModel, &project.EmbeddingDimensions,
+		&paths, &chunkTypes, &project.Root, &project.Collection,
+		&project.RepoURL, &project.RepoRef, &project.RepoCommit, &textContext); err != nil {
+		return nil, err
 	}
 
 	if groupID.Valid {
@@ -200,14 +275,59 @@ func (s *SQLiteStore) GetProject(ctx context.Context, name string) (*Project, er
 	if lastModifiedAt.Valid {
 		project.LastModifiedAt = &lastModifiedAt.Time
 	}
+	if err := decodeList(paths, &project.Paths); err != nil {
+		return nil, fmt.Errorf("invalid paths for project %s: %w", project.Name, err)
+	}
+	if err := decodeList(chunkTypes, &project.ChunkTypes); err != nil {
+		return nil, fmt.Errorf("invalid chunk types for project %s: %w", project.Name, err)
+	}
+	if err := decodeList(textContext, &project.TextContext); err != nil {
+		return nil, fmt.Errorf("invalid text context for project %s: %w", project.Name, err)
+	}
 
 	return &project, nil
 }
 
+// encodeList stores a list column (paths, chunk types, text context) as a
+// JSON array, or NULL if empty
+func encodeList(list []string) sql.NullString {
+	if len(list) == 0 {
+		return sql.NullString{}
+	}
+	data, _ := json.Marshal(list)
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// decodeList reads a column written by encodeList
+func decodeList(value sql.NullString, list *[]string) error {
+	if !value.Valid || value.String == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(value.String), list)
+}
+
+// GetProject retrieves a project by name
+func (s *SQLiteStore) GetProject(ctx context.Context, name string) (*Project, error) {
+	project, err := scanProject(s.db.QueryRowContext(ctx,
+		`SELECT `+projectColumns+`
+		 FROM projects p
+		 LEFT JOIN groups g ON p.group_id = g.id
+		 WHERE p.name = ?`,
+		name))
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrProjectNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get project: %w", err)
+	}
+
+	return project, nil
+}
+
 // ListProjects retrieves all projects with optional filtering
 func (s *SQLiteStore) ListProjects(ctx context.Context, filter *ProjectFilter) ([]Project, error) {
-	query := `SELECT p.id, p.name, p.path, p.language, p.description, p.group_id, g.name,
-	                 p.chunk_count, p.last_indexed_at, p.last_modified_at, p.created_at, p.updated_at
+	query := `SELECT ` + projectColumns + `
 	          FROM projects p
 	          LEFT JOIN groups g ON p.group_id = g.id
 	          WHERE 1=1`
@@ -238,45 +358,57 @@ func (s *SQLiteStore) ListProjects(ctx context.Context, filter *ProjectFilter) (
 
 	var projects []Project
 	for rows.Next() {
-		var project Project
-		var groupID sql.NullInt64
-		var groupName sql.NullString
-		var lastIndexedAt, lastModifiedAt sql.NullTime
-
-		if err := rows.Scan(&project.ID, &project.Name, &project.Path, &project.Language,
-			&project.Description, &groupID, &groupName, &project.ChunkCount,
-			&lastIndexedAt, &lastModifiedAt, &project.CreatedAt, &project.UpdatedAt); err != nil {
+		project, err := scanProject(rows)
+		if err != nil {
 			return nil, fmt.Errorf("failed to scan project: %w", err)
 		}
+		projects = append(projects, *project)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-		if groupID.Valid {
-			project.GroupID = &groupID.Int64
-			project.GroupName = groupName.String
-		}
-		if lastIndexedAt.Valid {
-			project.LastIndexedAt = &lastIndexedAt.Time
-		}
-		if lastModifiedAt.Valid {
-			project.LastModifiedAt = &lastModifiedAt.Time
-		}
-
-		projects = append(projects, project)
+	if filter != nil {
+		sortProjects(projects, filter.Sort)
 	}
+	return projects, nil
+}
 
-	return projects, rows.Err()
+// sortProjects reorders projects, already sorted by name, as by says. Recency
+// is compared in Go, as timestamps are stored in a text form SQLite cannot
+// order by; projects never completely indexed come last.
+func sortProjects(projects []Project, by ProjectSort) {
+	switch by {
+	case SortByRecent:
+		sort.SliceStable(projects, func(i, j int) bool {
+			a, b := projects[i].LastIndexedAt, projects[j].LastIndexedAt
+			if a == nil || b == nil {
+				return a != nil && b == nil
+			}
+			return a.After(*b)
+		})
+	case SortByChunks:
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].ChunkCount > projects[j].ChunkCount
+		})
+	}
 }
 
 // UpdateProject updates a project
 func (s *SQLiteStore) UpdateProject(ctx context.Context, project *Project) error {
-	result, err := s.db.ExecContext(ctx,
+	result, err := s.exec(ctx,
 		`UPDATE projects
 		 SET path = ?, language = ?, description = ?, group_id = ?,
 		     chunk_count = ?, last_indexed_at = ?, last_modified_at = ?,
-		     updated_at = CURRENT_TIMESTAMP
+		     embedding_provider = ?, embedding_model = ?, embedding_dimensions = ?,
+		     paths = ?, chunk_types = ?, root = ?, collection = ?,
+		     repo_url = ?, repo_ref = ?, repo_commit = ?, text_context = ?, updated_at = CURRENT_TIMESTAMP
 		 WHERE name = ?`,
 		project.Path, project.Language, project.Description, project.GroupID,
 		project.ChunkCount, project.LastIndexedAt, project.LastModifiedAt,
-		project.Name)
+		project.EmbeddingProvider, project.EmbeddingModel, project.EmbeddingDimensions,
+		encodeList(project.Paths), encodeList(project.ChunkTypes), project.Root, project.Collection,
+		project.RepoURL, project.RepoRef, project.RepoCommit, encodeList(project.TextContext), project.Name)
 	if err != nil {
 		return fmt.Errorf("failed to update project: %w", err)
 	}
@@ -286,7 +418,7 @@ func (s *SQLiteStore) UpdateProject(ctx context.Context, project *Project) error
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("project not found: %s", project.Name)
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, project.Name)
 	}
 
 	return nil
@@ -294,7 +426,7 @@ func (s *SQLiteStore) UpdateProject(ctx context.Context, project *Project) error
 
 // DeleteProject deletes a project and all its files
 func (s *SQLiteStore) DeleteProject(ctx context.Context, name string) error {
-	result, err := s.db.ExecContext(ctx, "DELETE FROM projects WHERE name = ?", name)
+	result, err := s.exec(ctx, "DELETE FROM projects WHERE name = ?", name)
 	if err != nil {
 		return fmt.Errorf("failed to delete project: %w", err)
 	}
@@ -304,7 +436,7 @@ func (s *SQLiteStore) DeleteProject(ctx context.Context, name string) error {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("project not found: %s", name)
+		return fmt.Errorf("%w: %s", ErrProjectNotFound, name)
 	}
 
 	return nil
@@ -312,7 +444,7 @@ func (s *SQLiteStore) DeleteProject(ctx context.Context, name string) error {
 
 // UpsertFile inserts or updates a file
 func (s *SQLiteStore) UpsertFile(ctx context.Context, file *File) error {
-	result, err := s.db.ExecContext(ctx,
+	result, err := s.exec(ctx,
 		`INSERT INTO files (project_id, file_path, last_modified_at, last_indexed_at, chunk_count, file_hash)
 		 VALUES (?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(project_id, file_path) DO UPDATE SET
@@ -348,7 +480,7 @@ func (s *SQLiteStore) GetFile(ctx context.Context, projectID int64, filePath str
 		&lastModifiedAt, &lastIndexedAt, &file.ChunkCount, &file.FileHash)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("file not found: %s", filePath)
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get file: %w", err)
@@ -400,7 +532,7 @@ func (s *SQLiteStore) ListFiles(ctx context.Context, projectID int64) ([]File, e
 
 // DeleteFile deletes a specific file
 func (s *SQLiteStore) DeleteFile(ctx context.Context, projectID int64, filePath string) error {
-	result, err := s.db.ExecContext(ctx,
+	result, err := s.exec(ctx,
 		"DELETE FROM files WHERE project_id = ? AND file_path = ?",
 		projectID, filePath)
 	if err != nil {
@@ -412,7 +544,7 @@ func (s *SQLiteStore) DeleteFile(ctx context.Context, projectID int64, filePath
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 	if rows == 0 {
-		return fmt.Errorf("file not found: %s", filePath)
+		return fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
 	}
 
 	return nil
@@ -420,7 +552,7 @@ func (s *SQLiteStore) DeleteFile(ctx context.Context, projectID int64, filePath
 
 // DeleteProjectFiles deletes all files for a project
 func (s *SQLiteStore) DeleteProjectFiles(ctx context.Context, projectID int64) error {
-	_, err := s.db.ExecContext(ctx, "DELETE FROM files WHERE project_id = ?", projectID)
+	_, err := s.exec(ctx, "DELETE FROM files WHERE project_id = ?", projectID)
 	if err != nil {
 		return fmt.Errorf("failed to delete project files: %w", err)
 	}
@@ -468,3 +600,32 @@ func (s *SQLiteStore) GetStaleFiles(ctx context.Context, projectID int64) ([]Fil
 
 	return files, rows.Err()
 }
+
+// Vacuum rebuilds the database file to reclaim space left by deleted rows,
+// then refreshes the query planner's statistics
+func (s *SQLiteStore) Vacuum(ctx context.Context) error {
+	if _, err := s.exec(ctx, "VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	if _, err := s.exec(ctx, "ANALYZE"); err != nil {
+		return fmt.Errorf("failed to analyze database: %w", err)
+	}
+	return nil
+}
+
+// DeleteOrphanedFiles removes files rows whose project no longer exists.
+// The foreign key cascade normally prevents these, but foreign_keys is a
+// per-connection setting, so rows written elsewhere may have slipped through.
+func (s *SQLiteStore) DeleteOrphanedFiles(ctx context.Context) (int64, error) {
+	result, err := s.exec(ctx,
+		"DELETE FROM files WHERE project_id NOT IN (SELECT id FROM projects)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete orphaned files: %w", err)
+	}
+
+	count, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxSuggestions bounds the names a ProjectNotFoundError suggests
+const maxSuggestions = 3
+
+// ProjectNotFoundError reports a project name with no exact match, along
+// with the close matches among existing projects, if any. It matches
+// ErrProjectNotFound.
+type ProjectNotFoundError struct {
+	Name        string
+	Suggestions []string
+}
+
+func (e *ProjectNotFoundError) Error() string {
+	msg := fmt.Sprintf("%v: %s", ErrProjectNotFound, e.Name)
+	if len(e.Suggestions) == 0 {
+		return msg
+	}
+	quoted := make([]string, len(e.Suggestions))
+	for i, name := range e.Suggestions {
+		quoted[i] = "'" + name + "'"
+	}
+	return fmt.Sprintf("%s (did you mean %s?)", msg, strings.Join(quoted, " or "))
+}
+
+func (e *ProjectNotFoundError) Unwrap() error {
+	return ErrProjectNotFound
+}
+
+// SuggestNames returns the names close to name, closest first: those equal
+// ignoring case, those it is a prefix of or that are a prefix of it, and
+// those within a small edit distance for its length. Comparisons ignore
+// case.
+func SuggestNames(name string, names []string) []string {
+	target := strings.ToLower(name)
+	if target == "" {
+		return nil
+	}
+	maxDistance := max(1, len(target)/3)
+
+	type match struct {
+		name     string
+		distance int
+	}
+	var matches []match
+	for _, candidate := range names {
+		lower := strings.ToLower(candidate)
+		distance := levenshtein(target, lower)
+		switch {
+		case distance <= maxDistance:
+		case strings.HasPrefix(lower, target), strings.HasPrefix(target, lower):
+			// A prefix ranks after typos of the same length
+			distance = maxDistance + 1
+		default:
+			continue
+		}
+		matches = append(matches, match{name: candidate, distance: distance})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+	if len(matches) > maxSuggestions {
+		matches = matches[:maxSuggestions]
+	}
+	suggestions := make([]string, len(matches))
+	for i, m := range matches {
+		suggestions[i] = m.name
+	}
+	return suggestions
+}
+
+// levenshtein returns the number of single-rune insertions, deletions, and
+// substitutions turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,68 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// maxFileContextNames bounds the types, functions, and imports listed in a
+// file context, so a large file does not swamp each chunk's text
+const maxFileContextNames = 12
+
+// withFileContext sets the FileContext of each chunk of one file to a
+// summary of the file's package, its other types and functions, and its
+// imports, so chunks of the same file embed closer together
+func withFileContext(chunks []chunker.CodeChunk) {
+	for i := range chunks {
+		chunks[i].FileContext = fileContext(chunks, i)
+	}
+}
+
+// fileContext summarizes the chunks of a file other than chunks[self]
+func fileContext(chunks []chunker.CodeChunk, self int) string {
+	var types, funcs, imports []string
+	seenImports := make(map[string]bool)
+	for i, chunk := range chunks {
+		for _, imp := range chunk.Imports {
+			if !seenImports[imp] {
+				seenImports[imp] = true
+				imports = append(imports, imp)
+			}
+		}
+		if i == self || chunk.Name == "" {
+			continue
+		}
+		switch chunk.ChunkType {
+		case chunker.ChunkTypeFunction, chunker.ChunkTypeMethod:
+			funcs = append(funcs, chunk.QualifiedName())
+		case chunker.ChunkTypePackage, chunker.ChunkTypeFile, chunker.ChunkTypeMethodSet:
+		default:
+			types = append(types, chunk.Name)
+		}
+	}
+
+	var parts []string
+	if len(chunks) > 0 && chunks[self].Package != "" {
+		parts = append(parts, "package "+chunks[self].Package)
+	}
+	if len(types) > 0 {
+		parts = append(parts, "types: "+listNames(types))
+	}
+	if len(funcs) > 0 {
+		parts = append(parts, "functions: "+listNames(funcs))
+	}
+	if len(imports) > 0 {
+		parts = append(parts, "imports: "+listNames(imports))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// listNames joins names, cutting the list at maxFileContextNames
+func listNames(names []string) string {
+	if len(names) <= maxFileContextNames {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxFileContextNames], ", "), len(names)-maxFileContextNames)
+}
@@ -5,25 +5,48 @@ import (
 	"context"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/printer"
 	"go/token"
-	"os"
+	"io"
+	"io/fs"
+	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 	
 	"github.com/jayzheng/vectcode/pkg/chunker"
 )
 
+func init() {
+	Register([]string{".go"}, func(opts Options) Parser {
+		return NewGoParserWithOptions(opts)
+	})
+}
+
 // GoParser implements Parser for Go language
-type GoParser struct{}
+type GoParser struct {
+	opts   Options
+	report Report
+}
 
 // NewGoParser creates a new Go parser
 func NewGoParser() *GoParser {
 	return &GoParser{}
 }
 
+// NewGoParserWithOptions creates a new Go parser with the given options
+func NewGoParserWithOptions(opts Options) *GoParser {
+	return &GoParser{opts: opts}
+}
+
+// Report returns a summary of skipped and failed files from the last Parse
+func (p *GoParser) Report() Report {
+	return p.report
+}
+
 // Language returns "go"
 func (p *GoParser) Language() string {
 	return "go"
@@ -32,36 +55,16 @@ func (p *GoParser) Language() string {
 // Parse parses a Go project and extracts code chunks
 func (p *GoParser) Parse(ctx context.Context, projectPath string, projectName string) ([]chunker.CodeChunk, error) {
 	var chunks []chunker.CodeChunk
+	p.report = Report{}
+	buildCtx := p.buildContext()
 	
-	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if info.IsDir() {
-			name := info.Name()
-			// Skip vendor, node_modules, and hidden directories (but not "." or "..")
-			if name == "vendor" || name == "node_modules" {
-				return filepath.SkipDir
-			}
-			// Skip hidden directories, but allow "." and ".."
-			if len(name) > 1 && strings.HasPrefix(name, ".") {
-				return filepath.SkipDir
-			}
-			return nil
-		}
-		
-		if !strings.HasSuffix(path, ".go") {
-			return nil
-		}
-		
-		fileChunks, err := p.parseFile(path, projectName)
-		if err != nil {
-			fmt.Printf("Warning: failed to parse %s: %v\n", path, err)
-			return nil
-		}
-		
-		chunks = append(chunks, fileChunks...)
+	ignore, err := LoadIgnoreFile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	
+	err = walkFiles(projectPath, p.opts, skipDir, ignore, func(file walkedFile) error {
+		chunks = append(chunks, p.parseWalked(&buildCtx, file, ignore, projectName)...)
 		return nil
 	})
 	
@@ -69,18 +72,133 @@ func (p *GoParser) Parse(ctx context.Context, projectPath string, projectName st
 		return nil, fmt.Errorf("failed to walk project directory: %w", err)
 	}
 	
-	return chunks, nil
+	return p.withMethodSets(chunks, projectName), nil
 }
 
-// parseFile parses a single Go file
-func (p *GoParser) parseFile(filePath string, projectName string) ([]chunker.CodeChunk, error) {
-	fset := token.NewFileSet()
-	node, err := parser.ParseFile(fset, filePath, nil, parser.ParseComments)
+// ParseFS parses a Go project held in fsys and extracts code chunks. Build
+// constraints are read from fsys too.
+func (p *GoParser) ParseFS(ctx context.Context, fsys fs.FS, projectName string) ([]chunker.CodeChunk, error) {
+	var chunks []chunker.CodeChunk
+	p.report = Report{}
+	buildCtx := p.buildContext()
+	buildCtx.JoinPath = path.Join
+	buildCtx.OpenFile = func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+	
+	ignore, err := LoadIgnoreFileFS(fsys)
 	if err != nil {
 		return nil, err
 	}
 	
-	fileInfo, err := os.Stat(filePath)
+	err = walkFS(fsys, skipDir, ignore, func(file walkedFile) error {
+		chunks = append(chunks, p.parseWalked(&buildCtx, file, ignore, projectName)...)
+		return nil
+	})
+	
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk project files: %w", err)
+	}
+	
+	return p.withMethodSets(chunks, projectName), nil
+}
+
+// parseWalked parses a walked file if it is a Go file that is not ignored,
+// excluded by build constraints, or generated, counting those it skips and
+// those that fail in the report
+func (p *GoParser) parseWalked(buildCtx *build.Context, file walkedFile, ignore *IgnoreRules, projectName string) []chunker.CodeChunk {
+	if !handles(p, file.rel) {
+		return nil
+	}
+	if ignore.Ignored(file.rel, false) {
+		p.report.SkippedByIgnore++
+		return nil
+	}
+	
+	if !p.opts.AllPlatforms {
+		match, err := buildCtx.MatchFile(filepath.Dir(file.path), filepath.Base(file.path))
+		if err != nil {
+			p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+			return nil
+		}
+		if !match {
+			p.report.SkippedByBuild++
+			return nil
+		}
+	}
+	
+	src, err := file.read()
+	if err != nil {
+		p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+		return nil
+	}
+	
+	if !p.opts.IncludeGenerated && IsGenerated(src) {
+		p.report.SkippedGenerated++
+		return nil
+	}
+	
+	modTime, err := file.modTime()
+	if err != nil {
+		p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+		return nil
+	}
+	
+	chunks, err := p.ParseSource(src, file.display, projectName, modTime)
+	if err != nil {
+		p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+		return nil
+	}
+	if p.opts.FileContext {
+		withFileContext(chunks)
+	}
+	return chunks
+}
+
+// withMethodSets adds a method set chunk per type when Options.MethodSets
+// is set
+func (p *GoParser) withMethodSets(chunks []chunker.CodeChunk, projectName string) []chunker.CodeChunk {
+	if p.opts.MethodSets {
+		for _, set := range GroupMethodsByType(chunks) {
+			chunks = append(chunks, set.Chunk(projectName))
+		}
+	}
+	return chunks
+}
+
+// buildContext returns the build context used to evaluate build constraints.
+// Cgo is assumed enabled so cgo files count as part of the current platform.
+func (p *GoParser) buildContext() build.Context {
+	ctx := build.Default
+	ctx.CgoEnabled = true
+	return ctx
+}
+
+// generatedRe matches the standard generated-code marker
+// (https://go.dev/s/generatedcode)
+var generatedRe = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGenerated reports whether Go source carries the generated-code marker
+// before its first non-comment, non-blank line
+func IsGenerated(src []byte) bool {
+	for _, line := range bytes.Split(src, []byte("\n")) {
+		line = bytes.TrimRight(line, "\r")
+		if generatedRe.Match(line) {
+			return true
+		}
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) > 0 && !bytes.HasPrefix(trimmed, []byte("//")) {
+			return false
+		}
+	}
+	return false
+}
+
+// ParseSource parses Go source held in memory and extracts code chunks.
+// filePath is only used for chunk IDs, positions, and display; it is never read.
+func (p *GoParser) ParseSource(src []byte, filePath, projectName string, modTime time.Time) ([]chunker.CodeChunk, error) {
+	fset := token.NewFileSet()
+	node, err := parser.ParseFile(fset, filePath, src, parser.ParseComments)
 	if err != nil {
 		return nil, err
 	}
@@ -88,18 +206,28 @@ func (p *GoParser) parseFile(filePath string, projectName string) ([]chunker.Cod
 	var chunks []chunker.CodeChunk
 	packageName := node.Name.Name
 	imports := p.extractImports(node)
+	importNames := p.extractImportNames(node)
+	testFile := strings.HasSuffix(filePath, "_test.go")
+	testing := testingName(node)
+	
+	if p.opts.PackageDocs && node.Doc != nil {
+		chunks = append(chunks, p.extractPackageDoc(fset, node, filePath, projectName, modTime))
+	}
 	
 	ast.Inspect(node, func(n ast.Node) bool {
 		switch x := n.(type) {
 		case *ast.FuncDecl:
-			chunk := p.extractFunction(fset, x, filePath, projectName, packageName, imports, fileInfo.ModTime())
+			chunk := p.extractFunction(fset, x, filePath, projectName, packageName, imports, importNames, modTime)
+			if testFile {
+				chunk.TestKind = testKind(x, testing)
+			}
 			chunks = append(chunks, chunk)
 			
 		case *ast.GenDecl:
 			if x.Tok == token.TYPE {
 				for _, spec := range x.Specs {
 					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
-						chunk := p.extractType(fset, x, typeSpec, filePath, projectName, packageName, fileInfo.ModTime())
+						chunk := p.extractType(fset, x, typeSpec, filePath, projectName, packageName, modTime)
 						if chunk != nil {
 							chunks = append(chunks, *chunk)
 						}
@@ -113,7 +241,7 @@ func (p *GoParser) parseFile(filePath string, projectName string) ([]chunker.Cod
 	return chunks, nil
 }
 
-func (p *GoParser) extractFunction(fset *token.FileSet, fn *ast.FuncDecl, filePath, projectName, packageName string, imports []string, modTime time.Time) chunker.CodeChunk {
+func (p *GoParser) extractFunction(fset *token.FileSet, fn *ast.FuncDecl, filePath, projectName, packageName string, imports []string, importNames map[string]bool, modTime time.Time) chunker.CodeChunk {
 	var buf bytes.Buffer
 	printer.Fprint(&buf, fset, fn)
 
@@ -147,13 +275,18 @@ func (p *GoParser) extractFunction(fset *token.FileSet, fn *ast.FuncDecl, filePa
 		chunk.ChunkType = chunker.ChunkTypeFunction
 	}
 	
+	chunk.Signature = p.extractSignature(fset, fn)
+	chunk.Params = p.extractFieldTypes(fn.Type.Params)
+	chunk.Returns = p.extractFieldTypes(fn.Type.Results)
+	
 	if fn.Doc != nil {
 		chunk.DocString = fn.Doc.Text()
 	}
 	
 	if fn.Body != nil {
-		chunk.HTTPEndpoints = p.extractHTTPEndpoints(fn)
+		chunk.HTTPEndpoints = p.extractHTTPEndpoints(fn, imports)
 		chunk.HTTPCalls = p.extractHTTPCalls(fn)
+		chunk.Calls = p.extractCalls(fn, importNames)
 	}
 	
 	return chunk
@@ -192,6 +325,26 @@ func (p *GoParser) extractType(fset *token.FileSet, genDecl *ast.GenDecl, typeSp
 	return chunk
 }
 
+// extractPackageDoc returns the package chunk for a file's package doc
+// comment. Its code is the package clause; the overview is the DocString.
+func (p *GoParser) extractPackageDoc(fset *token.FileSet, node *ast.File, filePath, projectName string, modTime time.Time) chunker.CodeChunk {
+	packageName := node.Name.Name
+	return chunker.CodeChunk{
+		ID:           generateID(projectName, filePath, packageName+".package"),
+		Project:      projectName,
+		FilePath:     filePath,
+		Package:      packageName,
+		Language:     "go",
+		ChunkType:    chunker.ChunkTypePackage,
+		Name:         packageName,
+		Code:         "package " + packageName,
+		DocString:    node.Doc.Text(),
+		LineStart:    fset.Position(node.Doc.Pos()).Line,
+		LineEnd:      fset.Position(node.Name.End()).Line,
+		LastModified: modTime,
+	}
+}
+
 func (p *GoParser) extractImports(node *ast.File) []string {
 	var imports []string
 	for _, imp := range node.Imports {
@@ -203,6 +356,80 @@ func (p *GoParser) extractImports(node *ast.File) []string {
 	return imports
 }
 
+// extractImportNames returns the names imported packages are referred to by
+// in a file: the alias if given, otherwise the package name guessed from the
+// import path (ignoring a trailing major version such as /v2 or .v3)
+func (p *GoParser) extractImportNames(node *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, imp := range node.Imports {
+		if imp.Name != nil {
+			if imp.Name.Name != "_" && imp.Name.Name != "." {
+				names[imp.Name.Name] = true
+			}
+			continue
+		}
+		
+		path := strings.Trim(imp.Path.Value, `"`)
+		name := path[strings.LastIndex(path, "/")+1:]
+		if majorVersionRe.MatchString(name) && strings.Contains(path, "/") {
+			trimmed := path[:strings.LastIndex(path, "/")]
+			name = trimmed[strings.LastIndex(trimmed, "/")+1:]
+		}
+		if i := strings.Index(name, ".v"); i > 0 {
+			name = name[:i]
+		}
+		names[strings.ReplaceAll(name, "-", "_")] = true
+	}
+	return names
+}
+
+// majorVersionRe matches a module major version path element such as "v2"
+var majorVersionRe = regexp.MustCompile(`^v[0-9]+$`)
+
+// goBuiltins are predeclared functions, which are never recorded as calls
+var goBuiltins = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+// extractCalls lists the functions and methods a function calls, in order of
+// first call. Package-qualified calls keep their qualifier ("strings.Join");
+// plain function calls and method calls are recorded by name alone.
+func (p *GoParser) extractCalls(fn *ast.FuncDecl, importNames map[string]bool) []string {
+	var calls []string
+	seen := make(map[string]bool)
+	
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		
+		var name string
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			if !goBuiltins[fun.Name] {
+				name = fun.Name
+			}
+		case *ast.SelectorExpr:
+			name = fun.Sel.Name
+			if pkg, ok := fun.X.(*ast.Ident); ok && importNames[pkg.Name] {
+				name = pkg.Name + "." + fun.Sel.Name
+			}
+		}
+		
+		if name != "" && !seen[name] {
+			seen[name] = true
+			calls = append(calls, name)
+		}
+		return true
+	})
+	
+	return calls
+}
+
 func (p *GoParser) extractReceiverType(recv *ast.FieldList) string {
 	if len(recv.List) == 0 {
 		return ""
@@ -214,28 +441,39 @@ func (p *GoParser) extractReceiverType(recv *ast.FieldList) string {
 	return buf.String()
 }
 
-func (p *GoParser) extractHTTPEndpoints(fn *ast.FuncDecl) []string {
-	var endpoints []string
+// extractFieldTypes returns the type of each parameter or result in a list,
+// once per name: (a, b int, opts ...Option) -> ["int", "int", "...Option"]
+func (p *GoParser) extractFieldTypes(fields *ast.FieldList) []string {
+	if fields == nil {
+		return nil
+	}
 	
-	ast.Inspect(fn.Body, func(n ast.Node) bool {
-		if call, ok := n.(*ast.CallExpr); ok {
-			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
-				method := sel.Sel.Name
-				if isHTTPMethod(method) {
-					if len(call.Args) > 0 {
-						if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
-							path := strings.Trim(lit.Value, `"`)
-							endpoint := fmt.Sprintf("%s %s", method, path)
-							endpoints = append(endpoints, endpoint)
-						}
-					}
-				}
-			}
+	var types []string
+	for _, field := range fields.List {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, token.NewFileSet(), field.Type)
+		typ := buf.String()
+		
+		names := len(field.Names)
+		if names == 0 {
+			names = 1
 		}
-		return true
-	})
+		for i := 0; i < names; i++ {
+			types = append(types, typ)
+		}
+	}
+	return types
+}
+
+// extractSignature prints a function declaration without its doc comment or body
+func (p *GoParser) extractSignature(fset *token.FileSet, fn *ast.FuncDecl) string {
+	decl := *fn
+	decl.Doc = nil
+	decl.Body = nil
 	
-	return endpoints
+	var buf bytes.Buffer
+	printer.Fprint(&buf, fset, &decl)
+	return buf.String()
 }
 
 func (p *GoParser) extractHTTPCalls(fn *ast.FuncDecl) []string {
@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the file in a project path listing paths not to index,
+// one gitignore-style pattern per line
+const IgnoreFileName = ".vectcodeignore"
+
+// IgnoreRules are the patterns of an ignore file. The last pattern matching a
+// path decides, so a later "!pattern" re-includes what an earlier one
+// ignored. A nil *IgnoreRules ignores nothing.
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+// ignorePattern is one line of an ignore file
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes matching paths
+	dirOnly bool // "pattern/" only matches directories
+}
+
+// LoadIgnoreFile reads the IgnoreFileName in dir. It returns nil rules if
+// dir is not a directory or has no ignore file.
+func LoadIgnoreFile(dir string) (*IgnoreRules, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	return loadIgnoreFile(os.DirFS(dir), dir)
+}
+
+// LoadIgnoreFileFS reads the IgnoreFileName at the root of fsys. It returns
+// nil rules if there is none.
+func LoadIgnoreFileFS(fsys fs.FS) (*IgnoreRules, error) {
+	return loadIgnoreFile(fsys, "the project root")
+}
+
+// loadIgnoreFile reads the ignore file at the root of fsys, naming it by
+// where in errors
+func loadIgnoreFile(fsys fs.FS, where string) (*IgnoreRules, error) {
+	data, err := fs.ReadFile(fsys, IgnoreFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IgnoreFileName, err)
+	}
+	rules, err := ParseIgnoreRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s in %s: %w", IgnoreFileName, where, err)
+	}
+	return rules, nil
+}
+
+// ParseIgnoreRules parses the patterns of an ignore file, which follow
+// gitignore: blank lines and "#" comments are skipped, "!" negates, a
+// trailing "/" matches directories only, a pattern containing another "/"
+// is relative to the project path while one without matches at any depth,
+// "*" and "?" do not cross "/", and "**" matches any number of directories.
+func ParseIgnoreRules(data []byte) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var pattern ignorePattern
+		if strings.HasPrefix(line, "!") {
+			pattern.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			pattern.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		re, err := compileIgnorePattern(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		pattern.re = re
+		rules.patterns = append(rules.patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// compileIgnorePattern translates a glob into a regexp over slash-separated
+// paths relative to the project path
+func compileIgnorePattern(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	if !strings.Contains(glob, "/") {
+		b.WriteString("(?:.*/)?")
+	}
+	glob = strings.TrimPrefix(glob, "/")
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [ in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			b.WriteString(regexp.QuoteMeta(glob[i+1 : i+2]))
+			i++
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+
+	b.WriteString("$")
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", glob, err)
+	}
+	return re, nil
+}
+
+// Ignored reports whether a path relative to the project path is ignored,
+// either itself or because a directory on the way to it is. As in git, a
+// file inside an ignored directory cannot be re-included.
+func (r *IgnoreRules) Ignored(relPath string, isDir bool) bool {
+	if r == nil || len(r.patterns) == 0 {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if r.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return r.match(relPath, isDir)
+}
+
+// match applies the patterns to a single path; the last match decides
+func (r *IgnoreRules) match(relPath string, isDir bool) bool {
+	ignored := false
+	for _, pattern := range r.patterns {
+		if pattern.dirOnly && !isDir {
+			continue
+		}
+		if pattern.re.MatchString(relPath) {
+			ignored = !pattern.negate
+		}
+	}
+	return ignored
+}
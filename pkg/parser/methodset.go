@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// MethodSet groups a type with the methods declared on it, which may be
+// spread across several files of the same package
+type MethodSet struct {
+	Dir      string             // package directory
+	Package  string             // package name
+	TypeName string             // receiver type name without pointer or type parameters
+	Type     *chunker.CodeChunk // type declaration, nil if not found among the chunks
+	Methods  []chunker.CodeChunk
+}
+
+// GroupMethodsByType collects method chunks by receiver type within each
+// package directory. Types without methods are not included.
+func GroupMethodsByType(chunks []chunker.CodeChunk) []MethodSet {
+	sets := make(map[string]*MethodSet)
+	types := make(map[string]*chunker.CodeChunk)
+
+	for i := range chunks {
+		chunk := &chunks[i]
+		dir := filepath.Dir(chunk.FilePath)
+
+		switch chunk.ChunkType {
+		case chunker.ChunkTypeStruct, chunker.ChunkTypeInterface:
+			types[dir+"\x00"+chunk.Name] = chunk
+		case chunker.ChunkTypeMethod:
+			typeName := ReceiverTypeName(chunk.Receiver)
+			key := dir + "\x00" + typeName
+			set, ok := sets[key]
+			if !ok {
+				set = &MethodSet{Dir: dir, Package: chunk.Package, TypeName: typeName}
+				sets[key] = set
+			}
+			set.Methods = append(set.Methods, *chunk)
+		}
+	}
+
+	result := make([]MethodSet, 0, len(sets))
+	for key, set := range sets {
+		set.Type = types[key]
+		sort.Slice(set.Methods, func(a, b int) bool {
+			if set.Methods[a].FilePath != set.Methods[b].FilePath {
+				return set.Methods[a].FilePath < set.Methods[b].FilePath
+			}
+			return set.Methods[a].LineStart < set.Methods[b].LineStart
+		})
+		result = append(result, *set)
+	}
+
+	sort.Slice(result, func(a, b int) bool {
+		if result[a].Dir != result[b].Dir {
+			return result[a].Dir < result[b].Dir
+		}
+		return result[a].TypeName < result[b].TypeName
+	})
+
+	return result
+}
+
+// Chunk builds a synthetic method_set chunk containing the type declaration
+// (when known) followed by the signatures of all its methods
+func (m MethodSet) Chunk(projectName string) chunker.CodeChunk {
+	anchor := m.Methods[0]
+	if m.Type != nil {
+		anchor = *m.Type
+	}
+
+	var code strings.Builder
+	if m.Type != nil {
+		code.WriteString(m.Type.Code)
+		code.WriteString("\n\n")
+	}
+	code.WriteString("// Methods:\n")
+	for _, method := range m.Methods {
+		code.WriteString(method.Signature)
+		code.WriteString("\n")
+	}
+
+	chunk := chunker.CodeChunk{
+		ID:           generateID(projectName, anchor.FilePath, m.TypeName+".methods"),
+		Project:      projectName,
+		FilePath:     anchor.FilePath,
+		Package:      m.Package,
+		Language:     anchor.Language,
+		Code:         code.String(),
+		ChunkType:    chunker.ChunkTypeMethodSet,
+		Name:         m.TypeName,
+		LineStart:    anchor.LineStart,
+		LineEnd:      anchor.LineEnd,
+		LastModified: anchor.LastModified,
+	}
+	if m.Type != nil {
+		chunk.DocString = m.Type.DocString
+	}
+
+	return chunk
+}
+
+// ReceiverTypeName strips the pointer and any type parameters from a
+// receiver expression, e.g. "*Cache[K, V]" -> "Cache"
+func ReceiverTypeName(receiver string) string {
+	return chunker.ReceiverTypeName(receiver)
+}
@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// AutoLanguage selects every supported language, each file going to the
+// parser for its extension
+const AutoLanguage = "auto"
+
+// MultiParser parses a mixed-language project with one parser per registered
+// language. Each parser only visits files with its own extensions, so every
+// chunk carries the language of the file it came from. A single file is
+// parsed by its extension's parser alone.
+type MultiParser struct {
+	parsers []Parser
+	report  Report
+	found   map[string]bool
+}
+
+// NewMultiParser creates a parser for every supported language with the
+// given options
+func NewMultiParser(opts Options) *MultiParser {
+	p := &MultiParser{found: make(map[string]bool)}
+	for _, language := range registry {
+		p.parsers = append(p.parsers, language.factory(opts))
+	}
+	return p
+}
+
+// parsersFor returns the parsers to run over projectPath: every one for a
+// directory, and only the one for its extension, if any, for a file
+func (p *MultiParser) parsersFor(projectPath string) []Parser {
+	if info, err := os.Stat(projectPath); err != nil || info.IsDir() {
+		return p.parsers
+	}
+	for _, lp := range p.parsers {
+		if handles(lp, projectPath) {
+			return []Parser{lp}
+		}
+	}
+	return nil
+}
+
+// Report returns the combined report of every language from the last Parse
+func (p *MultiParser) Report() Report {
+	return p.report
+}
+
+// Language returns the languages found by every Parse so far, sorted and
+// comma-separated (e.g. "go,rust"), or "auto" before any code was found
+func (p *MultiParser) Language() string {
+	if len(p.found) == 0 {
+		return AutoLanguage
+	}
+	found := make([]string, 0, len(p.found))
+	for language := range p.found {
+		found = append(found, language)
+	}
+	sort.Strings(found)
+	return strings.Join(found, ",")
+}
+
+// Parse runs each language's parser over the project and combines the chunks
+func (p *MultiParser) Parse(ctx context.Context, projectPath string, projectName string) ([]chunker.CodeChunk, error) {
+	var chunks []chunker.CodeChunk
+	p.report = Report{}
+
+	for _, lp := range p.parsersFor(projectPath) {
+		langChunks, err := lp.Parse(ctx, projectPath, projectName)
+		if reporter, ok := lp.(Reporter); ok {
+			p.report.Add(reporter.Report())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range langChunks {
+			p.found[chunk.Language] = true
+		}
+		chunks = append(chunks, langChunks...)
+	}
+
+	return chunks, nil
+}
+
+// ParseFS runs each language's parser over the project in fsys and combines
+// the chunks
+func (p *MultiParser) ParseFS(ctx context.Context, fsys fs.FS, projectName string) ([]chunker.CodeChunk, error) {
+	var chunks []chunker.CodeChunk
+	p.report = Report{}
+
+	for _, lp := range p.parsers {
+		fsParser, ok := lp.(FSParser)
+		if !ok {
+			return nil, fmt.Errorf("the %s parser cannot parse an fs.FS", lp.Language())
+		}
+		langChunks, err := fsParser.ParseFS(ctx, fsys, projectName)
+		if reporter, ok := lp.(Reporter); ok {
+			p.report.Add(reporter.Report())
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range langChunks {
+			p.found[chunk.Language] = true
+		}
+		chunks = append(chunks, langChunks...)
+	}
+
+	return chunks, nil
+}
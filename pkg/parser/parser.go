@@ -2,7 +2,12 @@ package parser
 
 import (
 	"context"
-	
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/jayzheng/vectcode/pkg/chunker"
 )
 
@@ -10,7 +15,185 @@ import (
 type Parser interface {
 	// Parse analyzes a project directory and extracts code chunks
 	Parse(ctx context.Context, projectPath string, projectName string) ([]chunker.CodeChunk, error)
-	
+
 	// Language returns the programming language this parser handles
 	Language() string
 }
+
+// FSParser is implemented by parsers that can also parse a project held in
+// an fs.FS, such as an in-memory tree, a tarball, or a git tree, rather than
+// a local directory. The FS root is the project root, and paths within it
+// are recorded in chunks as they are; Options.Root and FollowSymlinks only
+// apply to Parse. Every parser in this package implements it.
+type FSParser interface {
+	Parser
+	ParseFS(ctx context.Context, fsys fs.FS, projectName string) ([]chunker.CodeChunk, error)
+}
+
+// Options controls which files a parser visits
+type Options struct {
+	// AllPlatforms includes files excluded by build constraints for the
+	// current GOOS/GOARCH (e.g. foo_windows.go when running on linux)
+	AllPlatforms bool
+
+	// MethodSets emits an extra method_set chunk per type listing the type
+	// together with every method declared on it, across files
+	MethodSets bool
+
+	// PackageDocs emits a package chunk for each package doc comment, such
+	// as a doc.go file's, so comment-only files are searchable too
+	PackageDocs bool
+
+	// FollowSymlinks walks into symlinked directories. Cycles are detected
+	// by tracking visited real paths, so a link to an ancestor is safe.
+	FollowSymlinks bool
+
+	// IncludeGenerated parses files marked with the standard
+	// "// Code generated ... DO NOT EDIT." header, which are skipped by default
+	IncludeGenerated bool
+
+	// FileContext adds a summary of each chunk's file (package, sibling
+	// types and functions, imports) to the chunk, and so to its embedded
+	// text
+	FileContext bool
+
+	// Root is the directory chunk file paths are reported relative to, so
+	// paths and IDs do not depend on how a project was reached. Empty uses
+	// the path passed to Parse (a single file's directory).
+	Root string
+}
+
+// ProjectRoot returns the directory the paths of a project are reported
+// relative to: the path itself for a single path, and the deepest directory
+// containing all of them otherwise
+func ProjectRoot(projectPaths []string) string {
+	var root []string
+	for i, path := range projectPaths {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		parts := strings.Split(filepath.Clean(path), string(filepath.Separator))
+		if i == 0 {
+			root = parts
+			continue
+		}
+		n := 0
+		for n < len(root) && n < len(parts) && root[n] == parts[n] {
+			n++
+		}
+		root = root[:n]
+	}
+
+	joined := strings.Join(root, string(filepath.Separator))
+	if joined == "" && len(root) > 0 {
+		return string(filepath.Separator)
+	}
+	return joined
+}
+
+// RelativePath returns path relative to root with forward slashes, as chunk
+// file paths are recorded. A path outside root, or a relative path that
+// cannot be compared with an absolute root, is returned as it is.
+func RelativePath(root, path string) string {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// displayRoot returns the absolute directory chunk paths of a Parse of
+// projectPath are relative to
+func (o Options) displayRoot(projectPath string) string {
+	root := o.Root
+	if root == "" {
+		root = projectPath
+		if info, err := os.Stat(projectPath); err == nil && !info.IsDir() {
+			root = filepath.Dir(projectPath)
+		}
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	return root
+}
+
+// displayPath returns a walked path relative to the absolute root
+func displayPath(root, path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return RelativePath(root, path)
+}
+
+// New creates the parser for the given registered language, or a
+// MultiParser for AutoLanguage
+func New(language string, opts Options) (Parser, error) {
+	if language == AutoLanguage {
+		return NewMultiParser(opts), nil
+	}
+	registered, ok := lookupLanguage(language)
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s", language)
+	}
+	return registered.factory(opts), nil
+}
+
+// SourceFile reports whether a path relative to a project root is one the
+// language's parser would visit: it has one of the language's extensions and
+// no directory on the way to it is skipped (vendor, hidden, target for Rust,
+// ...). Build constraints and generated-file headers are checked when
+// parsing. For AutoLanguage, any registered language's file qualifies.
+func SourceFile(language, relPath string) bool {
+	registered, ok := languageForFile(relPath)
+	if !ok || (language != AutoLanguage && language != registered.Name) {
+		return false
+	}
+
+	skip := skipDir
+	if skipper, ok := registered.factory(Options{}).(dirSkipper); ok {
+		skip = skipper.skipDir
+	}
+	dirs := strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/")
+	for _, dir := range dirs {
+		if dir != "." && skip(dir) {
+			return false
+		}
+	}
+	return true
+}
+
+// FileError records a source file that could not be parsed
+type FileError struct {
+	Path string
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// Report summarizes the files skipped or rejected during the last Parse
+type Report struct {
+	Errors           []*FileError // files that failed to parse
+	SkippedByBuild   int          // files excluded by build constraints
+	SkippedGenerated int          // generated files skipped
+	SkippedByIgnore  int          // files matching the ignore file
+}
+
+// Add merges another report into r
+func (r *Report) Add(other Report) {
+	r.Errors = append(r.Errors, other.Errors...)
+	r.SkippedByBuild += other.SkippedByBuild
+	r.SkippedGenerated += other.SkippedGenerated
+	r.SkippedByIgnore += other.SkippedByIgnore
+}
+
+// Reporter is implemented by parsers that keep a Report of their last Parse
+type Reporter interface {
+	Report() Report
+}
@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// Factory creates a language's parser with the given options
+type Factory func(opts Options) Parser
+
+// Language is a registered language: its name, as returned by its parser's
+// Language method and accepted by New, and the file extensions its parser
+// handles
+type Language struct {
+	Name       string
+	Extensions []string
+
+	factory Factory
+}
+
+// registry holds the registered languages in the order MultiParser runs
+// them, which is registration order
+var registry []Language
+
+// Register makes a language's parser available to New, ForFile,
+// SourceFile, and MultiParser for files with the given extensions (e.g.
+// ".go"). It is meant to be called from the init function of the file
+// implementing the parser, and panics if a language or extension is
+// registered twice.
+func Register(extensions []string, factory Factory) {
+	name := factory(Options{}).Language()
+	for _, language := range registry {
+		if language.Name == name {
+			panic(fmt.Sprintf("parser: language %s registered twice", name))
+		}
+		for _, ext := range extensions {
+			if slices.Contains(language.Extensions, ext) {
+				panic(fmt.Sprintf("parser: extension %s registered for both %s and %s", ext, language.Name, name))
+			}
+		}
+	}
+	registry = append(registry, Language{Name: name, Extensions: extensions, factory: factory})
+}
+
+// Languages returns the registered languages in registration order
+func Languages() []Language {
+	return slices.Clone(registry)
+}
+
+// lookupLanguage returns the registered language with the given name
+func lookupLanguage(name string) (Language, bool) {
+	for _, language := range registry {
+		if language.Name == name {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// languageForFile returns the registered language handling a file's
+// extension
+func languageForFile(path string) (Language, bool) {
+	ext := filepath.Ext(path)
+	for _, language := range registry {
+		if slices.Contains(language.Extensions, ext) {
+			return language, true
+		}
+	}
+	return Language{}, false
+}
+
+// handles reports whether path has one of the extensions registered for p's
+// language
+func handles(p Parser, path string) bool {
+	language, ok := languageForFile(path)
+	return ok && language.Name == p.Language()
+}
+
+// ForFile creates the parser for a file's extension with the given options,
+// reporting false if no registered language handles it
+func ForFile(path string, opts Options) (Parser, bool) {
+	language, ok := languageForFile(path)
+	if !ok {
+		return nil, false
+	}
+	return language.factory(opts), true
+}
+
+// dirSkipper is implemented by parsers that skip more directories than
+// skipDir, such as Rust's target
+type dirSkipper interface {
+	skipDir(name string) bool
+}
@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// routerImports are the packages whose route registrations the Go parser
+// recognizes. Functions in files importing none of them are not searched
+// for endpoints, so a cache's Get("/key") is not taken for one.
+var routerImports = []string{
+	"net/http",
+	"github.com/go-chi/chi",
+	"github.com/gin-gonic/gin",
+	"github.com/labstack/echo",
+	"github.com/gorilla/mux",
+	"github.com/gofiber/fiber",
+	"github.com/julienschmidt/httprouter",
+}
+
+// anyMethod is the method recorded for routes that accept every method,
+// e.g. "ANY /health" for http.HandleFunc("/health", h)
+const anyMethod = "ANY"
+
+// importsRouter reports whether a file imports one of routerImports
+func importsRouter(imports []string) bool {
+	for _, imp := range imports {
+		for _, router := range routerImports {
+			if imp == router || strings.HasPrefix(imp, router+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractHTTPEndpoints lists the routes a function registers as "METHOD
+// /path", covering:
+//
+//   - method-named calls: r.Get("/x", h), e.GET("/x", h), r.Any("/x", h)
+//   - Handle and HandleFunc with a pattern, including Go 1.22's "POST /x",
+//     and gorilla's .Methods("POST") chained after them
+//   - a leading method argument: Handle("GET", "/x", h), chi's
+//     Method("GET", "/x", h), fiber's Add("GET", "/x", h)
+//
+// Paths are prefixed by the route groups they are registered on within the
+// function: variables assigned from Group("/api") or gorilla's
+// PathPrefix("/api").Subrouter(), and the router passed to chi's
+// Route("/api", func(r chi.Router) {...}).
+func (p *GoParser) extractHTTPEndpoints(fn *ast.FuncDecl, imports []string) []string {
+	if !importsRouter(imports) {
+		return nil
+	}
+	w := &routeWalker{seen: make(map[string]bool), handled: make(map[*ast.CallExpr]bool)}
+	w.walk(fn.Body, make(map[string]string))
+	return w.endpoints
+}
+
+// routeWalker collects the endpoints registered in a function body
+type routeWalker struct {
+	endpoints []string
+	seen      map[string]bool
+	handled   map[*ast.CallExpr]bool // registrations already recorded with their .Methods
+}
+
+// walk records the routes registered under node. prefixes maps the names of
+// router variables in scope to their path prefix.
+func (w *routeWalker) walk(node ast.Node, prefixes map[string]string) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if len(n.Lhs) == len(n.Rhs) {
+				for i, lhs := range n.Lhs {
+					w.assign(lhs, n.Rhs[i], prefixes)
+				}
+			}
+		case *ast.ValueSpec:
+			if len(n.Names) == len(n.Values) {
+				for i, name := range n.Names {
+					w.assign(name, n.Values[i], prefixes)
+				}
+			}
+		case *ast.CallExpr:
+			return w.call(n, prefixes)
+		}
+		return true
+	})
+}
+
+// assign tracks a router variable assigned from a route group
+func (w *routeWalker) assign(lhs, rhs ast.Expr, prefixes map[string]string) {
+	ident, ok := lhs.(*ast.Ident)
+	if !ok || ident.Name == "_" {
+		return
+	}
+	if prefix, ok := groupPrefix(rhs, prefixes); ok {
+		prefixes[ident.Name] = prefix
+	} else {
+		delete(prefixes, ident.Name)
+	}
+}
+
+// call records the route a call registers, if any. A chi Route or Group
+// callback is walked with its router parameter bound to the group's prefix,
+// and the call is not inspected further.
+func (w *routeWalker) call(call *ast.CallExpr, prefixes map[string]string) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return true
+	}
+	prefix := receiverPrefix(sel.X, prefixes)
+	method := sel.Sel.Name
+
+	switch {
+	case method == "Methods":
+		// gorilla: r.HandleFunc("/x", h).Methods("GET", "POST")
+		inner, ok := sel.X.(*ast.CallExpr)
+		if !ok {
+			break
+		}
+		innerSel, ok := inner.Fun.(*ast.SelectorExpr)
+		if !ok || (innerSel.Sel.Name != "Handle" && innerSel.Sel.Name != "HandleFunc") || len(inner.Args) == 0 {
+			break
+		}
+		path, ok := stringLit(inner.Args[0])
+		if !ok {
+			break
+		}
+		for _, arg := range call.Args {
+			if m, ok := stringLit(arg); ok && isHTTPMethod(m) {
+				w.add(m, receiverPrefix(innerSel.X, prefixes), path)
+				w.handled[inner] = true
+			}
+		}
+
+	case method == "Route" && len(call.Args) == 2:
+		// chi: r.Route("/api", func(r chi.Router) {...})
+		path, ok := stringLit(call.Args[0])
+		fn, isFunc := call.Args[1].(*ast.FuncLit)
+		if ok && isFunc {
+			w.walkCallback(fn, joinRoute(prefix, path), prefixes)
+			return false
+		}
+
+	case method == "Group" && len(call.Args) == 1:
+		// chi: r.Group(func(r chi.Router) {...}) shares the prefix
+		if fn, ok := call.Args[0].(*ast.FuncLit); ok {
+			w.walkCallback(fn, prefix, prefixes)
+			return false
+		}
+
+	case (method == "Handle" || method == "HandleFunc") && !w.handled[call]:
+		if len(call.Args) >= 3 {
+			if m, ok := stringLit(call.Args[0]); ok && isHTTPMethod(m) {
+				if path, ok := stringLit(call.Args[1]); ok {
+					w.add(m, prefix, path)
+				}
+				break
+			}
+		}
+		if len(call.Args) > 0 {
+			if pattern, ok := stringLit(call.Args[0]); ok {
+				m, path := splitPattern(pattern)
+				w.add(m, prefix, path)
+			}
+		}
+
+	case method == "Method" || method == "MethodFunc" || method == "Add" || method == "HandlerFunc" || method == "Handler":
+		// chi Method/MethodFunc, fiber and echo Add, httprouter
+		// Handler/HandlerFunc: a leading method argument
+		if len(call.Args) >= 2 {
+			m, okMethod := stringLit(call.Args[0])
+			path, okPath := stringLit(call.Args[1])
+			if okMethod && okPath && isHTTPMethod(m) {
+				w.add(m, prefix, path)
+			}
+		}
+
+	case isHTTPMethod(method) || method == "Any":
+		if len(call.Args) > 0 {
+			if path, ok := stringLit(call.Args[0]); ok {
+				w.add(method, prefix, path)
+			}
+		}
+	}
+	return true
+}
+
+// walkCallback walks a route callback with its first parameter, the
+// router, bound to prefix
+func (w *routeWalker) walkCallback(fn *ast.FuncLit, prefix string, prefixes map[string]string) {
+	scope := make(map[string]string, len(prefixes)+1)
+	for name, p := range prefixes {
+		scope[name] = p
+	}
+	if params := fn.Type.Params.List; len(params) > 0 && len(params[0].Names) > 0 {
+		scope[params[0].Names[0].Name] = prefix
+	}
+	w.walk(fn.Body, scope)
+}
+
+// add records an endpoint whose path is a rooted route; a method-named
+// call on anything else, such as Get("key"), is not one
+func (w *routeWalker) add(method, prefix, path string) {
+	if !strings.HasPrefix(path, "/") {
+		return
+	}
+	endpoint := strings.ToUpper(method) + " " + joinRoute(prefix, path)
+	if !w.seen[endpoint] {
+		w.seen[endpoint] = true
+		w.endpoints = append(w.endpoints, endpoint)
+	}
+}
+
+// groupPrefix returns the path prefix of a route group expression:
+// x.Group("/api") (gin, echo, fiber) or x.PathPrefix("/api").Subrouter()
+// (gorilla), nested within x's own prefix
+func groupPrefix(expr ast.Expr, prefixes map[string]string) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", false
+	}
+	switch sel.Sel.Name {
+	case "Group", "PathPrefix":
+		if len(call.Args) == 0 {
+			return "", false
+		}
+		path, ok := stringLit(call.Args[0])
+		if !ok || !strings.HasPrefix(path, "/") {
+			return "", false
+		}
+		return joinRoute(receiverPrefix(sel.X, prefixes), path), true
+	case "Subrouter":
+		return groupPrefix(sel.X, prefixes)
+	}
+	return "", false
+}
+
+// receiverPrefix returns the prefix of the router a route is registered on:
+// a tracked variable or an inline group such as r.Group("/api").GET(...)
+func receiverPrefix(expr ast.Expr, prefixes map[string]string) string {
+	if ident, ok := expr.(*ast.Ident); ok {
+		return prefixes[ident.Name]
+	}
+	prefix, _ := groupPrefix(expr, prefixes)
+	return prefix
+}
+
+// splitPattern splits a net/http pattern into its method, anyMethod if it
+// has none, and path, dropping a host: "POST example.com/x" -> "POST", "/x"
+func splitPattern(pattern string) (method, path string) {
+	method = anyMethod
+	if m, rest, ok := strings.Cut(pattern, " "); ok && isHTTPMethod(m) {
+		method, pattern = m, strings.TrimSpace(rest)
+	}
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+	return method, pattern
+}
+
+// joinRoute appends a route path to a group prefix
+func joinRoute(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	if path == "/" || path == "" {
+		return prefix
+	}
+	return prefix + path
+}
+
+// stringLit returns the value of a string literal
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseSourceRoutes(t *testing.T) {
+	tests := []struct {
+		name string
+		imp  string // the file's only import
+		body string // the body of func Routes(r Router)
+		want []string
+	}{
+		{
+			name: "net/http patterns",
+			imp:  "net/http",
+			body: `
+	http.HandleFunc("/health", health)
+	http.HandleFunc("POST /users", createUser)
+	http.Handle("GET example.com/items", items)`,
+			want: []string{"ANY /health", "POST /users", "GET /items"},
+		},
+		{
+			name: "gin groups",
+			imp:  "github.com/gin-gonic/gin",
+			body: `
+	api := r.Group("/api")
+	api.GET("/users", listUsers)
+	v1 := api.Group("/v1")
+	v1.POST("/items", createItem)
+	r.Group("/admin").DELETE("/cache", clearCache)`,
+			want: []string{"GET /api/users", "POST /api/v1/items", "DELETE /admin/cache"},
+		},
+		{
+			name: "gorilla subrouter and methods",
+			imp:  "github.com/gorilla/mux",
+			body: `
+	s := r.PathPrefix("/api").Subrouter()
+	s.HandleFunc("/users", users).Methods("GET", "POST")
+	r.HandleFunc("/health", health)`,
+			want: []string{"GET /api/users", "POST /api/users", "ANY /health"},
+		},
+		{
+			name: "chi route callbacks",
+			imp:  "github.com/go-chi/chi/v5",
+			body: `
+	r.Route("/api", func(r chi.Router) {
+		r.Get("/users", listUsers)
+		r.Route("/admin", func(r chi.Router) {
+			r.Delete("/users/{id}", deleteUser)
+		})
+	})
+	r.Group(func(r chi.Router) {
+		r.Post("/login", login)
+	})
+	r.Method("PUT", "/settings", settings)`,
+			want: []string{"GET /api/users", "DELETE /api/admin/users/{id}", "POST /login", "PUT /settings"},
+		},
+		{
+			name: "leading method argument",
+			imp:  "github.com/julienschmidt/httprouter",
+			body: `
+	r.Handle("GET", "/items/:id", getItem)
+	r.HandlerFunc("PATCH", "/items/:id", patchItem)`,
+			want: []string{"GET /items/:id", "PATCH /items/:id"},
+		},
+		{
+			name: "unrooted path with a router import",
+			imp:  "github.com/go-chi/chi/v5",
+			body: `
+	cache.Get("key")`,
+			want: nil,
+		},
+		{
+			name: "no router import",
+			imp:  "example.com/cache",
+			body: `
+	c.Get("/key")
+	c.Delete("/key")`,
+			want: nil,
+		},
+	}
+
+	p := NewGoParser()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := "package routes\n\nimport \"" + tt.imp + "\"\n\nfunc Routes(r Router) {" + tt.body + "\n}\n"
+			chunks, err := p.ParseSource([]byte(src), "routes.go", "demo", time.Time{})
+			if err != nil {
+				t.Fatalf("ParseSource: %v", err)
+			}
+
+			var got []string
+			found := false
+			for _, chunk := range chunks {
+				if chunk.Name == "Routes" {
+					got, found = chunk.HTTPEndpoints, true
+				}
+			}
+			if !found {
+				t.Fatal("no chunk for func Routes")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("endpoints = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,658 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+func init() {
+	Register([]string{".rs"}, func(opts Options) Parser {
+		return NewRustParserWithOptions(opts)
+	})
+}
+
+// RustParser implements Parser for Rust with a lightweight scanner rather
+// than a full grammar. It extracts fn, struct, enum, trait, and impl items,
+// descending into impl blocks and inline modules but not into function or
+// macro bodies.
+type RustParser struct {
+	opts   Options
+	report Report
+}
+
+// NewRustParser creates a new Rust parser
+func NewRustParser() *RustParser {
+	return &RustParser{}
+}
+
+// NewRustParserWithOptions creates a new Rust parser with the given options
+func NewRustParserWithOptions(opts Options) *RustParser {
+	return &RustParser{opts: opts}
+}
+
+// Report returns a summary of failed files from the last Parse
+func (p *RustParser) Report() Report {
+	return p.report
+}
+
+// Language returns "rust"
+func (p *RustParser) Language() string {
+	return "rust"
+}
+
+// skipRustDir also skips cargo's target directory
+func skipRustDir(name string) bool {
+	return name == "target" || skipDir(name)
+}
+
+// skipDir reports whether a directory is skipped, with skipRustDir
+func (p *RustParser) skipDir(name string) bool {
+	return skipRustDir(name)
+}
+
+// Parse parses a Rust project and extracts code chunks
+func (p *RustParser) Parse(ctx context.Context, projectPath string, projectName string) ([]chunker.CodeChunk, error) {
+	var chunks []chunker.CodeChunk
+	p.report = Report{}
+
+	ignore, err := LoadIgnoreFile(projectPath)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkFiles(projectPath, p.opts, skipRustDir, ignore, func(file walkedFile) error {
+		chunks = append(chunks, p.parseWalked(file, ignore, projectName)...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk project directory: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// ParseFS parses a Rust project held in fsys and extracts code chunks
+func (p *RustParser) ParseFS(ctx context.Context, fsys fs.FS, projectName string) ([]chunker.CodeChunk, error) {
+	var chunks []chunker.CodeChunk
+	p.report = Report{}
+
+	ignore, err := LoadIgnoreFileFS(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkFS(fsys, skipRustDir, ignore, func(file walkedFile) error {
+		chunks = append(chunks, p.parseWalked(file, ignore, projectName)...)
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk project files: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// parseWalked parses a walked file if it is a Rust file that is not
+// ignored, counting ignored files and failures in the report. The module
+// path comes from the walked path, whose src directory may lie above the
+// recorded one.
+func (p *RustParser) parseWalked(file walkedFile, ignore *IgnoreRules, projectName string) []chunker.CodeChunk {
+	if !handles(p, file.rel) {
+		return nil
+	}
+	if ignore.Ignored(file.rel, false) {
+		p.report.SkippedByIgnore++
+		return nil
+	}
+
+	src, err := file.read()
+	if err != nil {
+		p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+		return nil
+	}
+	modTime, err := file.modTime()
+	if err != nil {
+		p.report.Errors = append(p.report.Errors, &FileError{Path: file.path, Err: err})
+		return nil
+	}
+
+	chunks := p.parseSource(src, file.display, rustModulePath(file.path), projectName, modTime)
+	if p.opts.FileContext {
+		withFileContext(chunks)
+	}
+	return chunks
+}
+
+// ParseSource parses Rust source held in memory and extracts code chunks.
+// filePath is used for chunk IDs, the module path, and display; it is never read.
+func (p *RustParser) ParseSource(src []byte, filePath, projectName string, modTime time.Time) ([]chunker.CodeChunk, error) {
+	return p.parseSource(src, filePath, rustModulePath(filePath), projectName, modTime), nil
+}
+
+// parseSource scans a file recorded as filePath whose items belong to module
+func (p *RustParser) parseSource(src []byte, filePath, module, projectName string, modTime time.Time) []chunker.CodeChunk {
+	s := &rustScanner{
+		src:         src,
+		masked:      maskRust(src),
+		filePath:    filePath,
+		projectName: projectName,
+		modTime:     modTime,
+		seen:        make(map[string]bool),
+	}
+	s.lines = append(s.lines, 0)
+	for i, c := range src {
+		if c == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+
+	s.scan(0, len(src), module, "")
+	return s.chunks
+}
+
+// rustItemRe matches the start of an item at the beginning of a masked line,
+// capturing its keyword
+var rustItemRe = regexp.MustCompile(`^[ \t]*(?:pub(?:[ \t]*\([^)]*\))?[ \t]+)?(?:(?:default|const|async|unsafe|extern(?:[ \t]+"[^"\n]*")?)[ \t]+)*(fn|struct|enum|trait|impl|mod)\b`)
+
+// rustScanner walks the masked source of one file. Offsets are shared between
+// src and masked, which differ only in comment and literal contents.
+type rustScanner struct {
+	src         []byte
+	masked      []byte
+	lines       []int // byte offset of each line start
+	filePath    string
+	projectName string
+	modTime     time.Time
+	seen        map[string]bool
+	chunks      []chunker.CodeChunk
+}
+
+// scan extracts items from masked[start:end]. receiver is the type name when
+// scanning the body of an impl block.
+func (s *rustScanner) scan(start, end int, module, receiver string) {
+	for off := start; off < end; {
+		lineEnd := off
+		for lineEnd < end && s.masked[lineEnd] != '\n' {
+			lineEnd++
+		}
+
+		m := rustItemRe.FindSubmatchIndex(s.masked[off:lineEnd])
+		if m == nil {
+			off = s.skipLine(off, end)
+			continue
+		}
+
+		kind := string(s.masked[off+m[2] : off+m[3]])
+		body, itemEnd := s.itemExtent(off+m[3], end)
+		s.item(kind, off, off+m[3], body, itemEnd, module, receiver)
+
+		off = itemEnd
+		for off < end && s.masked[off-1] != '\n' {
+			off++
+		}
+	}
+}
+
+// skipLine returns the start of the next line, jumping over any brace groups
+// opened on this one (e.g. macro invocations or const initializers)
+func (s *rustScanner) skipLine(off, end int) int {
+	i := off
+	for i < end && s.masked[i] != '\n' {
+		if s.masked[i] == '{' {
+			i = s.matchBrace(i, end)
+		}
+		i++
+	}
+	return i + 1
+}
+
+// matchBrace returns the offset of the '}' closing the '{' at open, or
+// end-1 if it is unbalanced
+func (s *rustScanner) matchBrace(open, end int) int {
+	depth := 0
+	for i := open; i < end; i++ {
+		switch s.masked[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return end - 1
+}
+
+// itemExtent finds where an item ends: after its closing brace, or after a
+// terminating ';' for bodiless items. body is the offset of the opening brace,
+// or -1 if there is none.
+func (s *rustScanner) itemExtent(from, end int) (body, itemEnd int) {
+	depth := 0
+	for i := from; i < end; i++ {
+		switch s.masked[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ';':
+			if depth == 0 {
+				return -1, i + 1
+			}
+		case '{':
+			if depth == 0 {
+				return i, s.matchBrace(i, end) + 1
+			}
+		}
+	}
+	return -1, end
+}
+
+// item records the chunk for an item whose line starts at lineOff and whose
+// keyword ends at afterKeyword, recursing into impl blocks and inline modules
+func (s *rustScanner) item(kind string, lineOff, afterKeyword, body, itemEnd int, module, receiver string) {
+	declEnd := itemEnd
+	if body >= 0 {
+		declEnd = body
+	}
+
+	switch kind {
+	case "mod":
+		if body >= 0 {
+			s.scan(body+1, itemEnd-1, module+"::"+rustIdent(s.masked[afterKeyword:declEnd]), "")
+		}
+		return
+
+	case "impl":
+		if body < 0 {
+			return
+		}
+		header := strings.Join(strings.Fields(string(s.masked[afterKeyword:body])), " ")
+		_, typeName := splitImplHeader(header)
+		signature := "impl " + header
+		if strings.HasPrefix(header, "<") {
+			signature = "impl" + header
+		}
+
+		chunk := s.newChunk(lineOff, itemEnd, module)
+		chunk.ID = s.id(signature, chunk.LineStart)
+		chunk.ChunkType = chunker.ChunkTypeImpl
+		chunk.Name = typeName
+		chunk.Receiver = typeName
+		chunk.Signature = signature
+		s.chunks = append(s.chunks, chunk)
+
+		s.scan(body+1, itemEnd-1, module, typeName)
+		return
+	}
+
+	name := rustIdent(s.masked[afterKeyword:declEnd])
+	if name == "" {
+		return
+	}
+
+	chunk := s.newChunk(lineOff, itemEnd, module)
+	chunk.Name = name
+
+	switch kind {
+	case "fn":
+		chunk.Signature = strings.TrimSuffix(strings.TrimSpace(dedent(string(s.src[lineOff:declEnd]))), ";")
+		if receiver != "" {
+			chunk.ChunkType = chunker.ChunkTypeMethod
+			chunk.Receiver = receiver
+			chunk.ID = s.id(receiver+"."+name, chunk.LineStart)
+		} else {
+			chunk.ChunkType = chunker.ChunkTypeFunction
+			chunk.ID = s.id(name, chunk.LineStart)
+		}
+	case "struct":
+		chunk.ChunkType = chunker.ChunkTypeStruct
+		chunk.ID = s.id(name, chunk.LineStart)
+	case "enum":
+		chunk.ChunkType = chunker.ChunkTypeEnum
+		chunk.ID = s.id(name, chunk.LineStart)
+	case "trait":
+		chunk.ChunkType = chunker.ChunkTypeTrait
+		chunk.ID = s.id(name, chunk.LineStart)
+	}
+
+	s.chunks = append(s.chunks, chunk)
+}
+
+// newChunk builds a chunk spanning the item's attributes through itemEnd,
+// with its preceding /// doc comment
+func (s *rustScanner) newChunk(lineOff, itemEnd int, module string) chunker.CodeChunk {
+	line := s.lineOf(lineOff)
+
+	// Attributes such as #[derive(...)] belong to the item; doc comments may
+	// sit above or between them
+	first := line
+	var docs []string
+	for l := line - 1; l >= 1; l-- {
+		text := strings.TrimSpace(string(s.src[s.lines[l-1]:s.lineEndOffset(l)]))
+		if strings.HasPrefix(text, "///") {
+			doc := strings.TrimPrefix(text, "///")
+			docs = append(docs, strings.TrimPrefix(doc, " "))
+			continue
+		}
+		if strings.HasPrefix(text, "#[") {
+			if len(docs) == 0 {
+				first = l
+			}
+			continue
+		}
+		break
+	}
+
+	chunk := chunker.CodeChunk{
+		Project:      s.projectName,
+		FilePath:     s.filePath,
+		Package:      module,
+		Language:     "rust",
+		Code:         dedent(string(s.src[s.lines[first-1]:itemEnd])),
+		LineStart:    first,
+		LineEnd:      s.lineOf(itemEnd - 1),
+		LastModified: s.modTime,
+	}
+
+	if len(docs) > 0 {
+		for i, j := 0, len(docs)-1; i < j; i, j = i+1, j-1 {
+			docs[i], docs[j] = docs[j], docs[i]
+		}
+		chunk.DocString = strings.Join(docs, "\n") + "\n"
+	}
+
+	return chunk
+}
+
+// id generates a chunk ID, disambiguating repeated names (e.g. the same
+// method in two trait impls) by line number
+func (s *rustScanner) id(name string, line int) string {
+	id := generateID(s.projectName, s.filePath, name)
+	if s.seen[id] {
+		id = fmt.Sprintf("%s#L%d", id, line)
+	}
+	s.seen[id] = true
+	return id
+}
+
+// lineOf returns the 1-based line number containing offset
+func (s *rustScanner) lineOf(offset int) int {
+	return sort.SearchInts(s.lines, offset+1)
+}
+
+// lineEndOffset returns the offset of the newline ending the 1-based line
+func (s *rustScanner) lineEndOffset(line int) int {
+	if line < len(s.lines) {
+		return s.lines[line] - 1
+	}
+	return len(s.src)
+}
+
+// rustIdent returns the identifier at the start of s, after whitespace
+func rustIdent(s []byte) string {
+	s = bytes.TrimLeft(s, " \t\r\n")
+	s = bytes.TrimPrefix(s, []byte("r#"))
+	end := 0
+	for end < len(s) && isRustIdentByte(s[end]) {
+		end++
+	}
+	return string(s[:end])
+}
+
+func isRustIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c >= utf8.RuneSelf
+}
+
+// splitImplHeader splits the text after "impl" into the trait (empty for
+// inherent impls) and the base name of the implementing type, e.g.
+// "<T> fmt::Display for Wrapper<T> where T: Debug" -> "fmt::Display", "Wrapper"
+func splitImplHeader(header string) (trait, typeName string) {
+	h := strings.TrimSpace(header)
+	if strings.HasPrefix(h, "<") {
+		h = strings.TrimSpace(h[closingAngle(h)+1:])
+	}
+
+	// Split on " where " and " for " only outside of generic arguments
+	depth := 0
+	forAt := -1
+	for i := 0; i < len(h); i++ {
+		switch h[i] {
+		case '<':
+			depth++
+		case '>':
+			if i > 0 && h[i-1] != '-' {
+				depth--
+			}
+		case ' ':
+			if depth != 0 {
+				continue
+			}
+			if strings.HasPrefix(h[i:], " where ") {
+				h = h[:i]
+			} else if forAt < 0 && strings.HasPrefix(h[i:], " for ") {
+				forAt = i
+			}
+		}
+	}
+
+	typ := h
+	if forAt >= 0 && forAt < len(h) {
+		trait = strings.TrimPrefix(h[:forAt], "!")
+		typ = h[forAt+len(" for "):]
+	}
+	return trait, rustTypeName(typ)
+}
+
+// closingAngle returns the index of the '>' matching the '<' at s[0]
+func closingAngle(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '<':
+			depth++
+		case '>':
+			if i > 0 && s[i-1] == '-' {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s) - 1
+}
+
+// rustTypeName reduces a type to its base name, e.g. "&'a mut std::vec::Vec<T>" -> "Vec"
+func rustTypeName(typ string) string {
+	t := strings.TrimSpace(typ)
+	for {
+		trimmed := strings.TrimLeft(t, "&* ")
+		if strings.HasPrefix(trimmed, "'") {
+			if sp := strings.IndexByte(trimmed, ' '); sp >= 0 {
+				trimmed = trimmed[sp+1:]
+			}
+		}
+		for _, prefix := range []string{"mut ", "const ", "dyn "} {
+			trimmed = strings.TrimPrefix(trimmed, prefix)
+		}
+		if trimmed == t {
+			break
+		}
+		t = trimmed
+	}
+
+	if idx := strings.Index(t, "<"); idx >= 0 {
+		t = t[:idx]
+	}
+	if idx := strings.LastIndex(t, "::"); idx >= 0 {
+		t = t[idx+2:]
+	}
+	return strings.TrimSpace(t)
+}
+
+// rustModulePath derives a module path from a file's location under the
+// crate's src directory, e.g. "src/net/client.rs" -> "crate::net::client"
+func rustModulePath(filePath string) string {
+	parts := strings.Split(filepath.ToSlash(filePath), "/")
+	dirs := parts[:len(parts)-1]
+
+	start := len(dirs)
+	for i, dir := range dirs {
+		if dir == "src" {
+			start = i + 1
+		}
+	}
+
+	segments := []string{"crate"}
+	segments = append(segments, dirs[start:]...)
+	switch stem := strings.TrimSuffix(parts[len(parts)-1], ".rs"); stem {
+	case "mod", "lib", "main":
+	default:
+		segments = append(segments, stem)
+	}
+	return strings.Join(segments, "::")
+}
+
+// dedent removes the first line's indentation from every line of code
+func dedent(code string) string {
+	indent := code[:len(code)-len(strings.TrimLeft(code, " \t"))]
+	if indent == "" {
+		return code
+	}
+
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, indent)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maskRust returns a copy of src with comments and the contents of string and
+// char literals blanked to spaces. Newlines and byte offsets are preserved, so
+// braces and keywords can be found without a full tokenizer.
+func maskRust(src []byte) []byte {
+	out := make([]byte, len(src))
+	copy(out, src)
+	blank := func(from, to int) {
+		for k := from; k < to && k < len(out); k++ {
+			if out[k] != '\n' {
+				out[k] = ' '
+			}
+		}
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+
+		switch {
+		case c == '/' && next == '/':
+			end := bytes.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src)
+			} else {
+				end += i
+			}
+			blank(i, end)
+			i = end
+
+		case c == '/' && next == '*':
+			// Block comments nest in Rust
+			depth := 1
+			j := i + 2
+			for j < len(src) && depth > 0 {
+				switch {
+				case src[j] == '/' && j+1 < len(src) && src[j+1] == '*':
+					depth++
+					j += 2
+				case src[j] == '*' && j+1 < len(src) && src[j+1] == '/':
+					depth--
+					j += 2
+				default:
+					j++
+				}
+			}
+			blank(i, j)
+			i = j
+
+		case (c == 'r' || c == 'b') && (i == 0 || !isRustIdentByte(src[i-1])):
+			// Raw strings: r"...", r#"..."#, br"..."
+			j := i
+			if src[j] == 'b' {
+				j++
+			}
+			if j < len(src) && src[j] == 'r' {
+				k := j + 1
+				for k < len(src) && src[k] == '#' {
+					k++
+				}
+				if k < len(src) && src[k] == '"' {
+					closing := "\"" + strings.Repeat("#", k-j-1)
+					end := bytes.Index(src[k+1:], []byte(closing))
+					if end < 0 {
+						blank(k+1, len(src))
+						i = len(src)
+					} else {
+						blank(k+1, k+1+end)
+						i = k + 1 + end + len(closing)
+					}
+					continue
+				}
+			}
+			// An identifier, or b"..." / b'.' handled by the next iteration
+			i++
+
+		case c == '"':
+			j := i + 1
+			for j < len(src) && src[j] != '"' {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			blank(i+1, j)
+			i = j + 1
+
+		case c == '\'':
+			// A char literal, or else a lifetime/label which is left alone
+			if next == '\\' {
+				j := i + 3
+				for j < len(src) && src[j] != '\'' && src[j] != '\n' {
+					j++
+				}
+				blank(i+1, j)
+				i = j + 1
+				continue
+			}
+			_, size := utf8.DecodeRune(src[i+1:])
+			if i+1+size < len(src) && src[i+1+size] == '\'' {
+				blank(i+1, i+1+size)
+				i += 2 + size
+				continue
+			}
+			i++
+
+		default:
+			i++
+		}
+	}
+
+	return out
+}
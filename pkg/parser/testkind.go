@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+)
+
+// testKindPrefixes maps the name prefixes go test recognizes to the kind of
+// function each marks and the testing type of its one parameter, if any
+var testKindPrefixes = []struct {
+	prefix string
+	kind   chunker.TestKind
+	param  string
+}{
+	{"Test", chunker.TestKindTest, "T"},
+	{"Benchmark", chunker.TestKindBenchmark, "B"},
+	{"Fuzz", chunker.TestKindFuzz, "F"},
+	{"Example", chunker.TestKindExample, ""},
+}
+
+// testingName returns the name a file refers to the testing package by, or
+// "" if it does not import it
+func testingName(node *ast.File) string {
+	for _, imp := range node.Imports {
+		if path, _ := strconv.Unquote(imp.Path.Value); path != "testing" {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name
+		}
+		return "testing"
+	}
+	return ""
+}
+
+// testKind classifies a function of a _test.go file the way go test does:
+// TestXxx(*testing.T), BenchmarkXxx(*testing.B), and FuzzXxx(*testing.F)
+// with no results, and ExampleXxx with neither parameters nor results. The
+// prefix must be followed by the end of the name or a rune that is not a
+// lower-case letter, so "Testify" is not a test. testing is the name the
+// file imports the testing package by, if any; examples need not import it.
+func testKind(fn *ast.FuncDecl, testing string) chunker.TestKind {
+	if fn.Recv != nil || fn.Type.TypeParams != nil || fn.Type.Results.NumFields() > 0 {
+		return ""
+	}
+	for _, candidate := range testKindPrefixes {
+		rest, ok := strings.CutPrefix(fn.Name.Name, candidate.prefix)
+		if !ok {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(rest); rest != "" && unicode.IsLower(r) {
+			return ""
+		}
+
+		params := fn.Type.Params.NumFields()
+		if candidate.param == "" {
+			if params == 0 {
+				return candidate.kind
+			}
+			return ""
+		}
+		if params == 1 && isTestingPointer(fn.Type.Params.List[0].Type, testing, candidate.param) {
+			return candidate.kind
+		}
+		return ""
+	}
+	return ""
+}
+
+// isTestingPointer reports whether expr is *testing.<name>, with the testing
+// package referred to as testing (or dot-imported, when testing is ".")
+func isTestingPointer(expr ast.Expr, testing, name string) bool {
+	star, ok := expr.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	switch x := star.X.(type) {
+	case *ast.SelectorExpr:
+		pkg, ok := x.X.(*ast.Ident)
+		return ok && pkg.Name == testing && x.Sel.Name == name
+	case *ast.Ident:
+		return testing == "." && x.Name == name
+	}
+	return false
+}
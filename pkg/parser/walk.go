@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// skipDir reports whether a directory should not be descended into:
+// vendor, node_modules, and hidden directories (but not "." or "..")
+func skipDir(name string) bool {
+	if name == "vendor" || name == "node_modules" {
+		return true
+	}
+	return len(name) > 1 && strings.HasPrefix(name, ".")
+}
+
+// walkedFile is a file found by walkFiles on disk or by walkFS in an fs.FS
+type walkedFile struct {
+	path    string // path in the walked filesystem, for build constraints and errors
+	rel     string // slash-separated path relative to the walk root, for ignore rules
+	display string // path recorded in the file's chunks
+	read    func() ([]byte, error)
+	modTime func() (time.Time, error)
+}
+
+// walkFiles calls fn for every non-directory entry under root, skipping
+// directories rejected by skip (usually skipDir) or ignored by the root's
+// ignore file. Files are not checked against ignore, so callers can count
+// them. A root that is itself a symlink is always resolved. Symlinks inside
+// the tree are passed to fn as-is by default.
+//
+// With opts.FollowSymlinks, symlinked directories are walked as well. Paths
+// passed to fn stay under the link's location rather than the target's. Each
+// real directory is walked at most once, under whichever path the walk
+// reaches first, so cyclic symlinks (e.g. a link to a parent directory)
+// terminate and a link to a directory inside the tree indexes no duplicates.
+func walkFiles(root string, opts Options, skip func(name string) bool, ignore *IgnoreRules, fn func(file walkedFile) error) error {
+	realRoot := root
+	if info, err := os.Lstat(root); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		resolved, err := filepath.EvalSymlinks(root)
+		if err != nil {
+			return err
+		}
+		realRoot = resolved
+	}
+
+	visited := make(map[string]bool)
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		visited[resolved] = true
+	}
+
+	skipPath := func(path, name string) bool {
+		if skip(name) {
+			return true
+		}
+		rel := RelativePath(root, path)
+		return rel != "." && ignore.Ignored(rel, true)
+	}
+	displayRoot := opts.displayRoot(root)
+	return walkTree(root, realRoot, opts, skipPath, visited, func(path string, info os.FileInfo) error {
+		// A root that is a single file is relative to its directory
+		rel := RelativePath(root, path)
+		if rel == "." {
+			rel = filepath.Base(path)
+		}
+		return fn(walkedFile{
+			path:    path,
+			rel:     rel,
+			display: displayPath(displayRoot, path),
+			read:    func() ([]byte, error) { return os.ReadFile(path) },
+			modTime: func() (time.Time, error) {
+				info, err := os.Stat(path)
+				if err != nil {
+					return time.Time{}, err
+				}
+				return info.ModTime(), nil
+			},
+		})
+	})
+}
+
+// walkFS is walkFiles for an fs.FS, whose root is the project root: it
+// calls fn for every regular file, skipping directories rejected by skip or
+// ignored. Paths within fsys are recorded in chunks as they are. Symlinks
+// are not followed.
+func walkFS(fsys fs.FS, skip func(name string) bool, ignore *IgnoreRules, fn func(file walkedFile) error) error {
+	return fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if name != "." && (skip(d.Name()) || ignore.Ignored(name, true)) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		return fn(walkedFile{
+			path:    name,
+			rel:     name,
+			display: name,
+			read:    func() ([]byte, error) { return fs.ReadFile(fsys, name) },
+			modTime: func() (time.Time, error) {
+				info, err := d.Info()
+				if err != nil {
+					return time.Time{}, err
+				}
+				return info.ModTime(), nil
+			},
+		})
+	})
+}
+
+// walkTree walks realRoot, reporting paths relocated under displayRoot
+func walkTree(displayRoot, realRoot string, opts Options, skip func(path, name string) bool, visited map[string]bool, fn func(path string, info os.FileInfo) error) error {
+	return filepath.Walk(realRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		display := path
+		if displayRoot != realRoot {
+			rel, err := filepath.Rel(realRoot, path)
+			if err != nil {
+				return err
+			}
+			display = filepath.Join(displayRoot, rel)
+		}
+
+		if info.IsDir() {
+			if skip(display, info.Name()) {
+				return filepath.SkipDir
+			}
+			// A directory a followed symlink already reached, such as a
+			// sibling linked from elsewhere in the tree, is not walked again
+			if opts.FollowSymlinks && path != realRoot {
+				real, err := filepath.EvalSymlinks(path)
+				if err != nil {
+					return err
+				}
+				if visited[real] {
+					return filepath.SkipDir
+				}
+				visited[real] = true
+			}
+			return nil
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 && opts.FollowSymlinks {
+			target, err := filepath.EvalSymlinks(path)
+			if err != nil {
+				return nil // dangling link
+			}
+			targetInfo, err := os.Stat(target)
+			if err != nil {
+				return nil
+			}
+
+			if targetInfo.IsDir() {
+				if skip(display, info.Name()) || visited[target] {
+					return nil
+				}
+				visited[target] = true
+				return walkTree(display, target, opts, skip, visited, fn)
+			}
+			return fn(display, targetInfo)
+		}
+
+		return fn(display, info)
+	})
+}
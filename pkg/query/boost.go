@@ -0,0 +1,76 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// boostOverfetch is how many times the limit is fetched when boosting
+// packages, so results of a boosted package can rise into the page
+const boostOverfetch = 3
+
+// ParsePackageBoosts converts "package=factor" pairs, e.g. "core=1.5", into
+// SearchOptions.PackageBoosts. Factors must be positive; below 1 demotes.
+func ParsePackageBoosts(pairs []string) (map[string]float64, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+	boosts := make(map[string]float64, len(pairs))
+	for _, pair := range pairs {
+		pkg, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || pkg == "" {
+			return nil, fmt.Errorf("invalid package boost %q (expected package=factor, e.g. core=1.5)", pair)
+		}
+		factor, err := strconv.ParseFloat(value, 64)
+		if err != nil || factor <= 0 {
+			return nil, fmt.Errorf("invalid factor in package boost %q (expected a positive number)", pair)
+		}
+		boosts[pkg] = factor
+	}
+	return boosts, nil
+}
+
+// searchBoosted fetches boostOverfetch times the results up to the
+// requested page, boosts them by package, and returns the page
+func (q *Engine) searchBoosted(ctx context.Context, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	fetch := opts
+	fetch.Limit = (opts.Offset + opts.EffectiveLimit()) * boostOverfetch
+	fetch.Offset = 0
+
+	results, err := q.fetch(ctx, queryEmbedding, fetch)
+	if err != nil {
+		return nil, err
+	}
+	boostPackages(results, opts.PackageBoosts)
+	return page(results, opts), nil
+}
+
+// boostPackages multiplies the score of each result in a boosted package by
+// its factor and reorders the results by score
+func boostPackages(results []vectorstore.SearchResult, boosts map[string]float64) {
+	if len(boosts) == 0 {
+		return
+	}
+	for i := range results {
+		if factor, ok := boosts[results[i].Chunk.Package]; ok {
+			results[i].Score *= factor
+		}
+	}
+	sortResults(results)
+}
+
+// page returns the results of the page opts ask for
+func page(results []vectorstore.SearchResult, opts vectorstore.SearchOptions) []vectorstore.SearchResult {
+	if opts.Offset >= len(results) {
+		return nil
+	}
+	results = results[opts.Offset:]
+	if limit := opts.EffectiveLimit(); len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// DefaultCacheTTL is how long cached results stay valid when no TTL is given
+const DefaultCacheTTL = 5 * time.Minute
+
+// resultCache is a least-recently-used cache of search results with a TTL.
+// Entries are not invalidated by re-indexing, which usually happens in
+// another process, so the TTL bounds how stale a hit can be.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is most recently used
+	entries map[string]*list.Element
+}
+
+// cacheEntry is one cached query
+type cacheEntry struct {
+	key     string
+	results []vectorstore.SearchResult
+	expires time.Time
+}
+
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// cacheKey identifies a query by its whitespace-normalized text and options
+func cacheKey(queryText string, opts vectorstore.SearchOptions) string {
+	return fmt.Sprintf("%q %#v", strings.Join(strings.Fields(queryText), " "), opts)
+}
+
+// get returns a copy of the cached results for key, if present and not expired
+func (c *resultCache) get(key string) ([]vectorstore.SearchResult, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return copyResults(entry.results), true
+}
+
+// put stores a copy of results under key, evicting the least recently used
+// entry when the cache is full
+func (c *resultCache) put(key string, results []vectorstore.SearchResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{key: key, results: copyResults(results), expires: time.Now().Add(c.ttl)}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).key)
+	}
+}
+
+// copyResults copies results and their callees so callers may modify them
+// (e.g. clearing code) without changing the cache
+func copyResults(results []vectorstore.SearchResult) []vectorstore.SearchResult {
+	if results == nil {
+		return nil
+	}
+	copied := make([]vectorstore.SearchResult, len(results))
+	copy(copied, results)
+	for i := range copied {
+		if copied[i].Callees != nil {
+			copied[i].Callees = append(copied[i].Callees[:0:0], copied[i].Callees...)
+		}
+	}
+	return copied
+}
@@ -0,0 +1,102 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// maxCallees caps the callees attached to one result, since common method
+// names (String, Close, ...) can match many chunks
+const maxCallees = 20
+
+// resolveCallees sets Callees on each result to the chunks of the functions
+// it calls, looked up by name within the result's project. A qualified call
+// ("pkg.Func") only matches functions in that package; a plain name prefers
+// functions in the caller's own package and otherwise matches any function or
+// method with that name.
+func (q *Engine) resolveCallees(ctx context.Context, results []vectorstore.SearchResult) error {
+	// One lookup per project, for every name called by its results
+	names := make(map[string]map[string]bool)
+	for _, result := range results {
+		project := result.Chunk.Project
+		for _, call := range result.Chunk.Calls {
+			if names[project] == nil {
+				names[project] = make(map[string]bool)
+			}
+			names[project][calleeName(call)] = true
+		}
+	}
+
+	byName := make(map[string]map[string][]chunker.CodeChunk)
+	for project, set := range names {
+		list := make([]string, 0, len(set))
+		for name := range set {
+			list = append(list, name)
+		}
+
+		chunks, err := q.vectorStore.GetChunksByName(ctx, project, list)
+		if err != nil {
+			return fmt.Errorf("failed to resolve calls: %w", err)
+		}
+
+		byName[project] = make(map[string][]chunker.CodeChunk)
+		for _, chunk := range chunks {
+			if chunk.ChunkType == chunker.ChunkTypeFunction || chunk.ChunkType == chunker.ChunkTypeMethod {
+				byName[project][chunk.Name] = append(byName[project][chunk.Name], chunk)
+			}
+		}
+	}
+
+	for i := range results {
+		caller := results[i].Chunk
+		var callees []chunker.CodeChunk
+		for _, call := range caller.Calls {
+			callees = append(callees, matchCallees(caller, call, byName[caller.Project][calleeName(call)])...)
+			if len(callees) >= maxCallees {
+				callees = callees[:maxCallees]
+				break
+			}
+		}
+		results[i].Callees = callees
+	}
+
+	return nil
+}
+
+// calleeName strips the package qualifier from a recorded call
+func calleeName(call string) string {
+	return call[strings.LastIndex(call, ".")+1:]
+}
+
+// matchCallees picks the candidates a call from caller can refer to
+func matchCallees(caller chunker.CodeChunk, call string, candidates []chunker.CodeChunk) []chunker.CodeChunk {
+	var matches []chunker.CodeChunk
+	if i := strings.LastIndex(call, "."); i >= 0 {
+		pkg := call[:i]
+		for _, candidate := range candidates {
+			if candidate.ChunkType == chunker.ChunkTypeFunction && candidate.Package == pkg {
+				matches = append(matches, candidate)
+			}
+		}
+		return matches
+	}
+
+	for _, candidate := range candidates {
+		if candidate.ChunkType == chunker.ChunkTypeFunction && candidate.Package == caller.Package && candidate.ID != caller.ID {
+			matches = append(matches, candidate)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+	for _, candidate := range candidates {
+		if candidate.ID != caller.ID {
+			matches = append(matches, candidate)
+		}
+	}
+	return matches
+}
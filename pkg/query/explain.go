@@ -0,0 +1,91 @@
+package query
+
+import (
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// stopWords are query words too common to explain a match
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "do": true, "does": true, "for": true, "from": true,
+	"how": true, "in": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "that": true, "the": true, "this": true, "to": true, "we": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true,
+	"why": true, "with": true,
+}
+
+// explainMatches sets the MatchedTerms of each result to the words of
+// queryText found in its chunk. Words are compared case-insensitively after
+// splitting identifiers ("GetUser" -> get, user) and trimming common
+// suffixes, so "tokens" matches refreshToken.
+func explainMatches(queryText string, results []vectorstore.SearchResult) {
+	terms := queryTerms(queryText)
+	for i := range results {
+		results[i].MatchedTerms = matchTerms(terms, results[i].Chunk)
+	}
+}
+
+// queryTerms returns the distinct words of a query that are not stop words
+func queryTerms(queryText string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, word := range chunker.SplitIdentifier(queryText) {
+		word = strings.ToLower(word)
+		if len(word) < 2 || stopWords[word] || seen[stem(word)] {
+			continue
+		}
+		seen[stem(word)] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// matchTerms lists the terms found in each field of a chunk
+func matchTerms(terms []string, chunk chunker.CodeChunk) []vectorstore.TermMatch {
+	fields := []struct {
+		name  string
+		stems map[string]bool
+	}{
+		{"name", wordStems(chunk.QualifiedName())},
+		{"doc", wordStems(chunk.DocString + " " + chunk.Summary)},
+		{"code", wordStems(chunk.Code)},
+		{"path", wordStems(chunk.FilePath)},
+	}
+
+	var matches []vectorstore.TermMatch
+	for _, term := range terms {
+		match := vectorstore.TermMatch{Term: term}
+		for _, field := range fields {
+			if field.stems[stem(term)] {
+				match.Fields = append(match.Fields, field.name)
+			}
+		}
+		if len(match.Fields) > 0 {
+			matches = append(matches, match)
+		}
+	}
+	return matches
+}
+
+// wordStems returns the stems of the words in text, splitting identifiers
+func wordStems(text string) map[string]bool {
+	stems := make(map[string]bool)
+	for _, word := range chunker.SplitIdentifier(text) {
+		stems[stem(strings.ToLower(word))] = true
+	}
+	return stems
+}
+
+// stem trims a plural or verb suffix from a lower-case word, keeping at
+// least three letters: "tokens" -> "token", "refreshed" -> "refresh"
+func stem(word string) string {
+	for _, suffix := range []string{"ing", "ed", "es", "s"} {
+		if strings.HasSuffix(word, suffix) && len(word)-len(suffix) >= 3 {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+	return word
+}
@@ -0,0 +1,200 @@
+package query
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// DefaultResultTemplate is the built-in layout for one search result
+const DefaultResultTemplate = `=== Result {{.Index}} (Score: {{printf "%.4f" .Score}}{{if .ShowDistance}}, Distance: {{printf "%.4f" .Distance}}{{end}}) ===
+Project: {{.Chunk.Project}}
+File: {{.Chunk.FilePath}}:{{.Chunk.LineStart}}-{{.Chunk.LineEnd}}
+Type: {{.Chunk.ChunkType}} {{.Chunk.QualifiedName}}{{if .Chunk.TestKind}} ({{.Chunk.TestKind}}){{end}}
+{{if .ShowMetadata}}{{if .Chunk.Package}}Package: {{.Chunk.Package}}
+{{end}}{{if .Chunk.Imports}}Imports: {{join .Chunk.Imports ", "}}
+{{end}}{{if .Chunk.HTTPEndpoints}}HTTP endpoints: {{join .Chunk.HTTPEndpoints ", "}}
+{{end}}{{if .Chunk.HTTPCalls}}HTTP calls: {{join .Chunk.HTTPCalls ", "}}
+{{end}}{{if .Chunk.GRPCMethods}}gRPC methods: {{join .Chunk.GRPCMethods ", "}}
+{{end}}{{end}}{{if .Chunk.Author}}Author: {{.Chunk.Author}} (last commit {{.Chunk.LastCommit.Format "2006-01-02"}})
+{{end}}{{if .Chunk.Summary}}Summary: {{.Chunk.Summary}}
+{{end}}{{if .Chunk.DocString}}Docs: {{.Chunk.DocString}}
+{{end}}{{if .MatchedTerms}}Matched: {{range $i, $m := .MatchedTerms}}{{if $i}}, {{end}}{{$m}}{{end}}
+{{end}}{{if .Embedding}}Embedding: {{summarize .Embedding}}
+{{end}}{{if .Callees}}Calls:
+{{range .Callees}}  {{.ChunkType}} {{.QualifiedName}}  {{.FilePath}}:{{.LineStart}}
+{{end}}{{end}}
+{{.Code}}
+
+`
+
+// CompactResultTemplate is a one-line layout listing where a result is,
+// without its code
+const CompactResultTemplate = `{{.Index}}. {{printf "%.4f" .Score}}{{if .ShowDistance}} ({{printf "%.4f" .Distance}}){{end}}  {{.Chunk.Project}}  {{.Chunk.FilePath}}:{{.Chunk.LineStart}}-{{.Chunk.LineEnd}}  {{.Chunk.ChunkType}} {{.Chunk.QualifiedName}}
+`
+
+// MarkdownResultTemplate renders a result as a markdown section, for
+// pasting into docs and pull requests
+const MarkdownResultTemplate = `### {{.Chunk.Project}} / {{.Chunk.FilePath}}:{{.Chunk.LineStart}} — {{.Chunk.QualifiedName}}
+
+{{if .Chunk.Summary}}{{.Chunk.Summary}}
+
+{{end}}{{if .Chunk.DocString}}{{quote .Chunk.DocString}}
+
+{{end}}{{if .Code}}{{$fence := fence .Code}}{{$fence}}{{.Chunk.Language}}
+{{.Code}}
+{{$fence}}
+
+{{end}}`
+
+// AgentResultTemplate lays out a result for LLM clients, as the MCP server
+// answers search_code, fencing the code and pointing at get_chunk when it
+// was truncated
+const AgentResultTemplate = `=== Result {{.Index}} (Score: {{printf "%.4f" .Score}}) ===
+Project: {{.Chunk.Project}}
+File: {{.Chunk.FilePath}}:{{.Chunk.LineStart}}-{{.Chunk.LineEnd}}
+Type: {{.Chunk.ChunkType}} {{.Chunk.QualifiedName}}
+{{if .Chunk.Summary}}Summary: {{.Chunk.Summary}}
+{{end}}{{if .Chunk.DocString}}Documentation:
+{{.Chunk.DocString}}
+{{end}}{{if .Callees}}Calls:
+{{range .Callees}}- {{if .Signature}}{{.Signature}}{{else}}{{.ChunkType}} {{.QualifiedName}}{{end}} ({{.FilePath}}:{{.LineStart}}, id {{printf "%q" .ID}})
+{{end}}{{end}}
+Code:
+` + "```" + `{{.Chunk.Language}}
+{{.Code}}
+{{if .Truncated}}…
+{{end}}` + "```" + `
+{{if .Truncated}}(code truncated to {{len .Code}} of {{len .Chunk.Code}} characters; call get_chunk with id {{printf "%q" .Chunk.ID}} for the full code)
+{{end}}
+`
+
+// ResultView is the data passed to result templates. SearchResult fields
+// (.Chunk, .Score, .Distance, .Tokens, .Embedding, .Callees, .MatchedTerms)
+// are available directly.
+type ResultView struct {
+	vectorstore.SearchResult
+
+	Index     int    // 1-based position in the result list
+	Code      string // code to display, possibly truncated
+	Truncated bool   // whether Code was cut short of Chunk.Code
+
+	// ShowDistance asks for the raw distance next to the score, as set by
+	// ResultFormatter.ShowDistance
+	ShowDistance bool
+
+	// ShowMetadata asks for the chunk's package, imports, and HTTP and gRPC
+	// metadata, as set by ResultFormatter.ShowMetadata
+	ShowMetadata bool
+}
+
+// NewResultView wraps a result for templates, displaying its full code
+func NewResultView(index int, result vectorstore.SearchResult) ResultView {
+	return ResultView{
+		SearchResult: result,
+		Index:        index,
+		Code:         result.Chunk.Code,
+	}
+}
+
+// ResultFormatter renders search results with a text/template
+type ResultFormatter struct {
+	tmpl *template.Template
+
+	// ShowDistance has the built-in templates show each result's raw
+	// distance next to its score, for comparing embedding models
+	ShowDistance bool
+
+	// ShowMetadata has DefaultResultTemplate list each result's package,
+	// imports, HTTP endpoints and calls, and gRPC methods
+	ShowMetadata bool
+}
+
+// NewResultFormatter parses a result template. An empty text uses
+// DefaultResultTemplate.
+func NewResultFormatter(text string) (*ResultFormatter, error) {
+	if text == "" {
+		text = DefaultResultTemplate
+	}
+
+	tmpl, err := template.New("result").Funcs(template.FuncMap{
+		"summarize": func(vec []float64) string {
+			return embedder.Summarize(vec, 8)
+		},
+		"join":  strings.Join,
+		"quote": markdownQuote,
+		"fence": markdownFence,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid result template: %w", err)
+	}
+
+	return &ResultFormatter{tmpl: tmpl}, nil
+}
+
+// LoadResultFormatter reads a result template from a file. An empty path
+// uses DefaultResultTemplate.
+func LoadResultFormatter(path string) (*ResultFormatter, error) {
+	if path == "" {
+		return NewResultFormatter("")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result template: %w", err)
+	}
+	return NewResultFormatter(string(data))
+}
+
+// Format writes a single result
+func (f *ResultFormatter) Format(w io.Writer, view ResultView) error {
+	return f.tmpl.Execute(w, view)
+}
+
+// FormatAll writes every result in order, numbering them from 1
+func (f *ResultFormatter) FormatAll(w io.Writer, results []vectorstore.SearchResult) error {
+	return f.FormatAllFrom(w, results, 1)
+}
+
+// FormatAllFrom writes every result in order, numbering them from first
+// (e.g. offset+1 for a later page)
+func (f *ResultFormatter) FormatAllFrom(w io.Writer, results []vectorstore.SearchResult, first int) error {
+	for i, result := range results {
+		view := NewResultView(first+i, result)
+		view.ShowDistance = f.ShowDistance
+		view.ShowMetadata = f.ShowMetadata
+		if err := f.Format(w, view); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownQuote prefixes every line of text with "> "
+func markdownQuote(text string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownFence returns a code fence longer than any run of backticks in
+// code, so code containing its own fences renders intact
+func markdownFence(code string) string {
+	longest, run := 0, 0
+	for _, c := range code {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return strings.Repeat("`", max(3, longest+1))
+}
@@ -2,74 +2,250 @@ package query
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 	
 	"github.com/jayzheng/vectcode/pkg/embedder"
+	"github.com/jayzheng/vectcode/pkg/llm"
 	"github.com/jayzheng/vectcode/pkg/vectorstore"
 )
 
+// ErrEmptyQuery is returned for query text that is empty or only whitespace,
+// which would embed into a meaningless vector
+var ErrEmptyQuery = errors.New("query text is empty")
+
 // Engine handles queries against the code knowledge base
 type Engine struct {
 	embedder    embedder.Embedder
 	vectorStore vectorstore.VectorStore
-	llmConfig   LLMConfig
+	llm         llm.Client
+	cache       *resultCache
+	
+	// reranker rescores the top rerankOverfetch times the limit results
+	reranker        Reranker
+	rerankOverfetch int
+
+	// readCode fills in the code of results stored without it
+	readCode CodeReader
 }
 
-// LLMConfig holds LLM configuration
-type LLMConfig struct {
-	Provider  string `yaml:"provider"`
-	Model     string `yaml:"model"`
-	APIKeyEnv string `yaml:"api_key_env"`
+// Option configures an Engine
+type Option func(*Engine)
+
+// WithCache keeps the results of up to size recent queries for ttl
+// (DefaultCacheTTL if zero), so a repeated query with the same options skips
+// embedding and search. A size of zero disables the cache.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(q *Engine) {
+		if size > 0 {
+			q.cache = newResultCache(size, ttl)
+		}
+	}
 }
 
-func New(e embedder.Embedder, vs vectorstore.VectorStore, llmConfig LLMConfig) *Engine {
-	return &Engine{
-		embedder:    e,
-		vectorStore: vs,
-		llmConfig:   llmConfig,
+// CodeReader fills in the code of results whose chunks were stored without
+// it (vector_store.options.store_code: false), e.g. from the files on disk
+type CodeReader func(ctx context.Context, results []vectorstore.SearchResult)
+
+// WithCodeReader has the engine fill in missing code with fn before results
+// are reranked, so a Reranker reads the code, and before they are returned
+func WithCodeReader(fn CodeReader) Option {
+	return func(q *Engine) {
+		q.readCode = fn
 	}
 }
 
-// NewEngine creates a query engine without LLM config (for basic queries)
-func NewEngine(e embedder.Embedder, vs vectorstore.VectorStore) *Engine {
-	return &Engine{
+// New creates a query engine that can also Summarize results with an LLM
+func New(e embedder.Embedder, vs vectorstore.VectorStore, client llm.Client, opts ...Option) *Engine {
+	q := &Engine{
 		embedder:    e,
 		vectorStore: vs,
+		llm:         client,
+	}
+	for _, opt := range opts {
+		opt(q)
 	}
+	return q
+}
+
+// NewEngine creates a query engine without an LLM (for basic queries)
+func NewEngine(e embedder.Embedder, vs vectorstore.VectorStore, opts ...Option) *Engine {
+	return New(e, vs, nil, opts...)
+}
+
+// QueryStats records where a query spent its time
+type QueryStats struct {
+	Embed  time.Duration // embedding the query text
+	Search time.Duration // searching the vector store, including any post-filtering and call resolution
+	Cached bool          // results came from the cache, so nothing was embedded or searched
+}
+
+// Total returns the combined embed and search time
+func (s QueryStats) Total() time.Duration {
+	return s.Embed + s.Search
+}
+
+// Query embeds the query text and searches the vector store
+func (q *Engine) Query(ctx context.Context, queryText string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	results, _, err := q.QueryWithStats(ctx, queryText, opts)
+	return results, err
 }
 
-func (q *Engine) Query(ctx context.Context, queryText string, limit int, filters map[string]interface{}) ([]vectorstore.SearchResult, error) {
+// QueryWithStats runs Query and also reports how long each stage took.
+// Surrounding whitespace is trimmed from queryText; nothing left is
+// ErrEmptyQuery.
+func (q *Engine) QueryWithStats(ctx context.Context, queryText string, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, QueryStats, error) {
+	var stats QueryStats
+	queryText = strings.TrimSpace(queryText)
+	if queryText == "" {
+		return nil, stats, ErrEmptyQuery
+	}
+	
+	var key string
+	if q.cache != nil {
+		key = cacheKey(queryText, opts)
+		if results, ok := q.cache.get(key); ok {
+			stats.Cached = true
+			return results, stats, nil
+		}
+	}
+	
+	start := time.Now()
+	queryEmbedding, err := q.Embed(ctx, queryText)
+	stats.Embed = time.Since(start)
+	if err != nil {
+		return nil, stats, err
+	}
+	
+	start = time.Now()
+	results, err := q.search(ctx, queryText, queryEmbedding, opts)
+	stats.Search = time.Since(start)
+	if err != nil {
+		return nil, stats, err
+	}
+	if opts.Explain {
+		explainMatches(queryText, results)
+	}
+	
+	if q.cache != nil {
+		q.cache.put(key, results)
+	}
+	return results, stats, nil
+}
+
+// Embed computes the query vector for a query text. Callers paging through
+// results embed once and pass the vector to QueryVector for each page.
+func (q *Engine) Embed(ctx context.Context, queryText string) ([]float64, error) {
 	queryEmbedding, err := q.embedder.Embed(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
-	
-	results, err := q.vectorStore.Search(ctx, queryEmbedding, limit, filters)
+	return queryEmbedding, nil
+}
+
+// QueryVector searches with an already computed query vector. Use
+// opts.Offset to fetch later pages without re-embedding the query.
+// Without the query text, results are not reranked.
+func (q *Engine) QueryVector(ctx context.Context, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	return q.search(ctx, "", queryEmbedding, opts)
+}
+
+// search runs QueryVector, reranking the results against queryText when
+// the engine has a Reranker and queryText is set, and boosting them by
+// opts.PackageBoosts
+func (q *Engine) search(ctx context.Context, queryText string, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	var results []vectorstore.SearchResult
+	var err error
+	switch {
+	case q.reranker != nil && queryText != "":
+		results, err = q.searchReranked(ctx, queryText, queryEmbedding, opts)
+	case len(opts.PackageBoosts) > 0:
+		results, err = q.searchBoosted(ctx, queryEmbedding, opts)
+	default:
+		results, err = q.fetch(ctx, queryEmbedding, opts)
+	}
+	if err == nil {
+		sortResults(results)
+	}
+	if err == nil && opts.PreferRecent {
+		preferRecent(results)
+	}
+	if err == nil && opts.IncludeCallees {
+		err = q.resolveCallees(ctx, results)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to search vector store: %w", err)
+		return nil, err
 	}
 	
+	if q.readCode != nil {
+		q.readCode(ctx, results)
+	}
+	for i := range results {
+		results[i].Tokens = results[i].Chunk.EstimateTokens()
+	}
 	return results, nil
 }
 
-func (q *Engine) QueryWithLLM(ctx context.Context, queryText string, limit int, filters map[string]interface{}) (string, error) {
-	results, err := q.Query(ctx, queryText, limit, filters)
+// fetch searches the vector store, post-filtering when opts need it
+func (q *Engine) fetch(ctx context.Context, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	if opts.PostFiltered() {
+		return q.searchPostFiltered(ctx, queryEmbedding, opts)
+	}
+	results, err := q.vectorStore.Search(ctx, queryEmbedding, opts)
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to search vector store: %w", err)
 	}
+	return results, nil
+}
+
+// postFilterOverfetch is how many times the limit is fetched when
+// post-filtering by path prefix or types
+const postFilterOverfetch = 10
+
+// searchPostFiltered over-fetches and keeps results matching opts.PathPrefix,
+// ParamTypes, and ReturnTypes, since vector stores cannot filter metadata by
+// prefix or list membership
+func (q *Engine) searchPostFiltered(ctx context.Context, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	limit := opts.EffectiveLimit()
+	fetch := opts
+	fetch.Limit = (opts.Offset + limit) * postFilterOverfetch
+	fetch.Offset = 0
 	
-	if len(results) == 0 {
-		return "No relevant code found for your query.", nil
+	results, err := q.vectorStore.Search(ctx, queryEmbedding, fetch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search vector store: %w", err)
 	}
+	sortResults(results)
 	
-	response := "Found relevant code:\n\n"
-	for i, result := range results {
-		response += fmt.Sprintf("--- Result %d (Score: %.2f) ---\n", i+1, result.Score)
-		response += fmt.Sprintf("Project: %s\n", result.Chunk.Project)
-		response += fmt.Sprintf("File: %s\n", result.Chunk.FilePath)
-		response += fmt.Sprintf("Name: %s\n", result.Chunk.Name)
-		response += fmt.Sprintf("\n%s\n\n", result.Chunk.Code)
+	filtered := make([]vectorstore.SearchResult, 0, limit)
+	skip := opts.Offset
+	for _, result := range results {
+		if !opts.Matches(result.Chunk) {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		filtered = append(filtered, result)
+		if len(filtered) == limit {
+			break
+		}
 	}
 	
-	return response, nil
+	return filtered, nil
+}
+
+// QueryWithFilters runs Query using the legacy filter map. Unknown keys
+// and wrongly typed values fail with vectorstore.ErrInvalidFilter.
+//
+// Deprecated: use Query with a vectorstore.SearchOptions.
+func (q *Engine) QueryWithFilters(ctx context.Context, queryText string, limit int, filters map[string]interface{}) ([]vectorstore.SearchResult, error) {
+	opts, err := vectorstore.ParseSearchFilters(limit, filters)
+	if err != nil {
+		return nil, err
+	}
+	return q.Query(ctx, queryText, opts)
 }
@@ -0,0 +1,30 @@
+package query
+
+import (
+	"sort"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// RecencyEpsilon is how close two scores must be for PreferRecent to treat
+// the results as equally relevant
+const RecencyEpsilon = 0.01
+
+// preferRecent reorders results, sorted by score, so that each run of
+// results scoring within RecencyEpsilon of the run's first lists the most
+// recently modified chunk first. Chunks without a modification time keep
+// their place after the dated ones of their run.
+func preferRecent(results []vectorstore.SearchResult) {
+	for start := 0; start < len(results); {
+		end := start + 1
+		for end < len(results) && results[start].Score-results[end].Score <= RecencyEpsilon {
+			end++
+		}
+
+		run := results[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return run[i].Chunk.LastModified.After(run[j].Chunk.LastModified)
+		})
+		start = end
+	}
+}
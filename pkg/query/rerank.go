@@ -0,0 +1,59 @@
+package query
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// DefaultRerankOverfetch is how many times the limit is fetched from the
+// vector store for a Reranker to choose from
+const DefaultRerankOverfetch = 3
+
+// Reranker rescores search results against the query text, typically with
+// a cross-encoder that reads the query and each chunk together, which ranks
+// more precisely than comparing their embeddings
+type Reranker interface {
+	// Rerank returns results ordered by relevance to query, best first,
+	// with each Score replaced by the reranker's. Distance is kept.
+	Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error)
+}
+
+// WithReranker has Query fetch overfetch (DefaultRerankOverfetch if zero)
+// times the limit from the vector store and return the page of them ranked
+// best by r. Results of QueryVector, which has no query text, are not
+// reranked.
+func WithReranker(r Reranker, overfetch int) Option {
+	return func(q *Engine) {
+		if overfetch <= 0 {
+			overfetch = DefaultRerankOverfetch
+		}
+		q.reranker = r
+		q.rerankOverfetch = overfetch
+	}
+}
+
+// searchReranked fetches rerankOverfetch times the results up to the
+// requested page, reranks them, boosts them by package, and returns the page
+func (q *Engine) searchReranked(ctx context.Context, queryText string, queryEmbedding []float64, opts vectorstore.SearchOptions) ([]vectorstore.SearchResult, error) {
+	limit := opts.EffectiveLimit()
+	fetch := opts
+	fetch.Limit = (opts.Offset + limit) * q.rerankOverfetch
+	fetch.Offset = 0
+
+	candidates, err := q.fetch(ctx, queryEmbedding, fetch)
+	if err != nil || len(candidates) == 0 {
+		return candidates, err
+	}
+	// The reranker reads each chunk's code, so it must be there
+	if q.readCode != nil {
+		q.readCode(ctx, candidates)
+	}
+	results, err := q.reranker.Rerank(ctx, queryText, candidates)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank results: %w", err)
+	}
+	boostPackages(results, opts.PackageBoosts)
+	return page(results, opts), nil
+}
@@ -0,0 +1,30 @@
+package query
+
+import (
+	"sort"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// sortResults orders results by score, highest first, rather than trusting
+// the order a vector store returned them in. Equal scores are ordered by
+// project, file, line, and ID, so the same search lists its results the same
+// way on every run.
+func sortResults(results []vectorstore.SearchResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Chunk.Project != b.Chunk.Project {
+			return a.Chunk.Project < b.Chunk.Project
+		}
+		if a.Chunk.FilePath != b.Chunk.FilePath {
+			return a.Chunk.FilePath < b.Chunk.FilePath
+		}
+		if a.Chunk.LineStart != b.Chunk.LineStart {
+			return a.Chunk.LineStart < b.Chunk.LineStart
+		}
+		return a.Chunk.ID < b.Chunk.ID
+	})
+}
@@ -0,0 +1,54 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/llm"
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// maxSummaryCodeChars caps the code of each result sent to the LLM, keeping
+// the prompt small enough for local models
+const maxSummaryCodeChars = 1500
+
+// summarySystemPrompt instructs the LLM to answer only from the results
+const summarySystemPrompt = `You answer questions about a codebase using only the code excerpts provided.
+Reply in a few sentences of plain prose. Cite every excerpt you rely on inline as [path:line].
+If the excerpts do not answer the question, say so instead of guessing.`
+
+// Summarize asks the engine's LLM for a short answer to queryText, drawn
+// from results and citing them as [path:line]. It fails with
+// llm.ErrNotConfigured if the engine was created without an LLM.
+func (q *Engine) Summarize(ctx context.Context, queryText string, results []vectorstore.SearchResult) (string, error) {
+	if q.llm == nil {
+		return "", llm.ErrNotConfigured
+	}
+	if len(results) == 0 {
+		return "No relevant code found for your query.", nil
+	}
+
+	answer, err := q.llm.Complete(ctx, summarySystemPrompt, summaryPrompt(queryText, results))
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize results: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+// summaryPrompt lists each result with the location to cite, followed by the
+// question
+func summaryPrompt(queryText string, results []vectorstore.SearchResult) string {
+	var b strings.Builder
+	for i, result := range results {
+		chunk := result.Chunk
+		fmt.Fprintf(&b, "Excerpt %d: %s %s [%s:%d]\n", i+1, chunk.ChunkType, chunk.Name, chunk.FilePath, chunk.LineStart)
+		if chunk.DocString != "" {
+			fmt.Fprintf(&b, "%s\n", chunk.DocString)
+		}
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", chunker.TruncateCode(chunk.Code, maxSummaryCodeChars))
+	}
+	fmt.Fprintf(&b, "Question: %s\n", queryText)
+	return b.String()
+}
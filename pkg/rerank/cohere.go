@@ -0,0 +1,104 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// CohereReranker implements Reranker with the Cohere-style /v1/rerank API,
+// which llama.cpp's server, vLLM, and Infinity also serve for local
+// cross-encoders such as bge-reranker-v2-m3
+type CohereReranker struct {
+	httpClient *http.Client
+	endpoint   string
+	model      string
+	apiKey     string
+}
+
+// cohereRerankRequest represents the request to the /v1/rerank API
+type cohereRerankRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+}
+
+// cohereRerankResponse represents the response from the /v1/rerank API
+type cohereRerankResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+func NewCohereReranker(config Config) (*CohereReranker, error) {
+	var apiKey string
+	if config.APIKeyEnv != "" {
+		apiKey = os.Getenv(config.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("API key not found in environment variable %s", config.APIKeyEnv)
+		}
+	}
+
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:8080"
+	}
+
+	return &CohereReranker{
+		httpClient: &http.Client{},
+		endpoint:   endpoint,
+		model:      config.Model,
+		apiKey:     apiKey,
+	}, nil
+}
+
+func (r *CohereReranker) Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	jsonData, err := json.Marshal(cohereRerankRequest{Model: r.model, Query: query, Documents: documents(results)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/rerank", r.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if r.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+r.apiKey)
+	}
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to the reranker: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("reranker API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var rerankResp cohereRerankResponse
+	if err := json.NewDecoder(resp.Body).Decode(&rerankResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	scores := make(map[int]float64, len(rerankResp.Results))
+	for _, s := range rerankResp.Results {
+		scores[s.Index] = s.RelevanceScore
+	}
+	return applyScores(results, scores)
+}
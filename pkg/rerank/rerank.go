@@ -0,0 +1,103 @@
+// Package rerank rescores search results with a cross-encoder reranker
+// model served over HTTP, for query.WithReranker.
+package rerank
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// Errors returned (wrapped) by Reranker implementations; test with errors.Is
+var (
+	// ErrUnavailable means the reranking service could not be reached
+	ErrUnavailable = errors.New("reranker unavailable")
+
+	// ErrNotConfigured means no rerank section was configured
+	ErrNotConfigured = errors.New("no reranker configured (set rerank.provider in the config file)")
+)
+
+// Reranker scores search results against a query; it satisfies
+// query.Reranker
+type Reranker interface {
+	Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error)
+}
+
+// Config holds reranker configuration. Reranking is off unless Provider is
+// set.
+type Config struct {
+	// Provider is the API the reranker is served with: "tei" for Hugging
+	// Face text-embeddings-inference, or "cohere" for the Cohere-style
+	// /v1/rerank API of llama.cpp, vLLM, Infinity, and hosted services
+	Provider  string `yaml:"provider"`
+	Model     string `yaml:"model"`
+	APIKeyEnv string `yaml:"api_key_env"`
+	Endpoint  string `yaml:"endpoint"`
+
+	// Overfetch is how many times the query limit is fetched from the
+	// vector store for the reranker to choose from; zero uses
+	// query.DefaultRerankOverfetch
+	Overfetch int `yaml:"overfetch"`
+}
+
+// Validate checks Provider and Overfetch
+func (c Config) Validate() error {
+	switch c.Provider {
+	case "", "tei", "cohere":
+	default:
+		return fmt.Errorf("unsupported rerank provider: %s (expected tei or cohere)", c.Provider)
+	}
+	if c.Overfetch < 0 {
+		return fmt.Errorf("invalid overfetch %d (expected a positive integer)", c.Overfetch)
+	}
+	return nil
+}
+
+// New creates a reranker based on the provider in the config
+func New(config Config) (Reranker, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case "tei":
+		return NewTEIReranker(config)
+	default:
+		return NewCohereReranker(config)
+	}
+}
+
+// documents returns the text a reranker reads for each result
+func documents(results []vectorstore.SearchResult) []string {
+	texts := make([]string, len(results))
+	for i, result := range results {
+		texts[i] = result.Chunk.ToText()
+	}
+	return texts
+}
+
+// applyScores sets each result's Score to the reranker's score of it and
+// returns them best first. Every result must be scored.
+func applyScores(results []vectorstore.SearchResult, scores map[int]float64) ([]vectorstore.SearchResult, error) {
+	if len(scores) != len(results) {
+		return nil, fmt.Errorf("reranker scored %d of %d results", len(scores), len(results))
+	}
+	reranked := make([]vectorstore.SearchResult, len(results))
+	copy(reranked, results)
+	for i := range reranked {
+		score, ok := scores[i]
+		if !ok {
+			return nil, fmt.Errorf("reranker did not score result %d", i)
+		}
+		reranked[i].Score = score
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Score > reranked[j].Score
+	})
+	return reranked, nil
+}
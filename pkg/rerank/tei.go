@@ -0,0 +1,87 @@
+package rerank
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jayzheng/vectcode/pkg/vectorstore"
+)
+
+// TEIReranker implements Reranker with the /rerank API of Hugging Face
+// text-embeddings-inference serving a cross-encoder such as
+// BAAI/bge-reranker-v2-m3. The model is chosen when the server starts.
+type TEIReranker struct {
+	httpClient *http.Client
+	endpoint   string
+}
+
+// teiRerankRequest represents the request to the /rerank API
+type teiRerankRequest struct {
+	Query    string   `json:"query"`
+	Texts    []string `json:"texts"`
+	Truncate bool     `json:"truncate"`
+}
+
+// teiRerankResult is one scored text of the /rerank response
+type teiRerankResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+func NewTEIReranker(config Config) (*TEIReranker, error) {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		endpoint = "http://localhost:8080"
+	}
+
+	return &TEIReranker{
+		httpClient: &http.Client{},
+		endpoint:   endpoint,
+	}, nil
+}
+
+func (r *TEIReranker) Rerank(ctx context.Context, query string, results []vectorstore.SearchResult) ([]vectorstore.SearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	// Over-long chunks are truncated to the model's input length
+	jsonData, err := json.Marshal(teiRerankRequest{Query: query, Texts: documents(results), Truncate: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/rerank", r.endpoint)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to the reranker: %w: %w", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("reranker API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var scored []teiRerankResult
+	if err := json.NewDecoder(resp.Body).Decode(&scored); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	scores := make(map[int]float64, len(scored))
+	for _, s := range scored {
+		scores[s.Index] = s.Score
+	}
+	return applyScores(results, scores)
+}
@@ -3,45 +3,87 @@ package vectorstore
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	chroma "github.com/amikos-tech/chroma-go/pkg/api/v2"
+	chhttp "github.com/amikos-tech/chroma-go/pkg/commons/http"
 	"github.com/amikos-tech/chroma-go/pkg/embeddings"
 	"github.com/jayzheng/vectcode/pkg/chunker"
 )
 
-// ChromaStore implements VectorStore for ChromaDB
+// ChromaStore implements VectorStore for ChromaDB. It is safe for concurrent
+// use: queries and gets share the client freely, while writes are serialized
+// because chroma-go's first write runs an unsynchronized pre-flight check
+// that mutates the client.
 type ChromaStore struct {
 	config     Config
 	client     chroma.Client
 	collection chroma.Collection
+	metric     Metric
+	dimension  int // vector length the collection accepts; 0 if not yet known
+	batchSize  int
+	storeCode  bool            // false stores empty documents (store_code option)
+	fields     map[string]bool // optional metadata stored; nil for all (metadata_fields option)
+	writeMu    sync.Mutex
 }
 
 // NewChromaStore creates a new ChromaDB vector store
 func NewChromaStore(config Config) (*ChromaStore, error) {
 	// Parse endpoint URL
-	endpoint := parseEndpoint(config)
+	endpoint := config.Endpoint()
 
 	// Create ChromaDB client
-	client, err := chroma.NewHTTPClient(chroma.WithBaseURL(endpoint))
+	options, err := clientOptions(config)
+	if err != nil {
+		return nil, err
+	}
+	client, err := chroma.NewHTTPClient(append([]chroma.ClientOption{chroma.WithBaseURL(endpoint)}, options...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ChromaDB client: %w\n\nMake sure ChromaDB is running:\n  docker run -p 8000:8000 chromadb/chroma", err)
 	}
 
-	// Get collection name
-	collectionName := config.Collection
-	if collectionName == "" {
-		collectionName = "vectcode"
+	collectionName, err := config.CollectionName()
+	if err != nil {
+		return nil, err
 	}
 
-	// Get or create collection with cosine similarity
-	// Set HNSW space to cosine in metadata
-	metadata := chroma.NewMetadata(
-		chroma.NewStringAttribute("hnsw:space", "cosine"),
-	)
+	// Distance metric for new collections (default cosine)
+	metric, err := ParseMetric(config.Options["metric"])
+	if err != nil {
+		return nil, err
+	}
+
+	batchSize, err := config.InsertBatchSize()
+	if err != nil {
+		return nil, err
+	}
+
+	storeCode, err := config.StoreCode()
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := config.MetadataFields()
+	if err != nil {
+		return nil, err
+	}
+
+	// Get or create collection, setting the HNSW space in metadata. Chroma
+	// only fixes a collection's dimension on first insert, so record the
+	// expected one to catch a wrong-dimension first insert.
+	attributes := []*chroma.MetaAttribute{
+		chroma.NewStringAttribute("hnsw:space", string(metric)),
+	}
+	if config.Dimensions > 0 {
+		attributes = append(attributes, chroma.NewIntAttribute("dimension", int64(config.Dimensions)))
+	}
+	metadata := chroma.NewMetadata(attributes...)
 
 	collection, err := client.GetOrCreateCollection(
 		context.Background(),
@@ -49,35 +91,141 @@ func NewChromaStore(config Config) (*ChromaStore, error) {
 		chroma.WithCollectionMetadataCreate(metadata),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get or create collection '%s': %w", collectionName, err)
+		return nil, fmt.Errorf("failed to get or create collection '%s': %w", collectionName, chromaError(err))
+	}
+
+	// An existing collection keeps the space it was created with
+	if space := collectionSpace(collection); space != "" {
+		if actual, err := ParseMetric(space); err == nil {
+			metric = actual
+		}
+	}
+
+	dimension := collectionDimension(collection)
+	if dimension > 0 && config.Dimensions > 0 && dimension != config.Dimensions {
+		return nil, fmt.Errorf("%w: collection '%s' holds %d-dimensional vectors but the embedder produces %d; use another collection or the original embedding model",
+			ErrDimensionMismatch, collectionName, dimension, config.Dimensions)
+	}
+	if dimension == 0 {
+		dimension = config.Dimensions
 	}
 
 	return &ChromaStore{
 		config:     config,
 		client:     client,
 		collection: collection,
+		metric:     metric,
+		dimension:  dimension,
+		batchSize:  batchSize,
+		storeCode:  storeCode,
+		fields:     fields,
 	}, nil
 }
 
+// collectionDimension returns the vector length a collection holds: the one
+// Chroma fixed on first insert, else the one recorded at creation, else 0
+func collectionDimension(collection chroma.Collection) int {
+	if dimension := collection.Dimension(); dimension > 0 {
+		return dimension
+	}
+	if metadata := collection.Metadata(); metadata != nil {
+		if dimension, ok := metadata.GetInt("dimension"); ok {
+			return int(dimension)
+		}
+	}
+	return 0
+}
+
+// checkDimension rejects a vector the collection would not accept
+func (c *ChromaStore) checkDimension(vector []float64) error {
+	if c.dimension > 0 && len(vector) != c.dimension {
+		return &DimensionError{Collection: c.collection.Name(), Got: len(vector), Want: c.dimension}
+	}
+	return nil
+}
+
+// chromaError tags a chroma-go error with the sentinel error for its failure
+// mode, so callers can tell them apart with errors.Is. chroma-go flattens
+// transport errors into a ChromaError without a status code.
+func chromaError(err error) error {
+	var apiErr *chhttp.ChromaError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.ErrorCode == 0:
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	case strings.Contains(message, "dimension"):
+		return fmt.Errorf("%w: %w", ErrDimensionMismatch, err)
+	case apiErr.ErrorCode == 404 || strings.Contains(message, "does not exist"):
+		return fmt.Errorf("%w: %w", ErrCollectionMissing, err)
+	default:
+		return err
+	}
+}
+
+// collectionSpace returns the HNSW space recorded on a collection, checking
+// the legacy metadata key before the collection configuration
+func collectionSpace(collection chroma.Collection) string {
+	if metadata := collection.Metadata(); metadata != nil {
+		if space, ok := metadata.GetString("hnsw:space"); ok {
+			return space
+		}
+	}
+	if configuration := collection.Configuration(); configuration != nil {
+		if hnsw, ok := configuration.GetRaw("hnsw"); ok {
+			if hnswMap, ok := hnsw.(map[string]interface{}); ok {
+				if space, ok := hnswMap["space"].(string); ok {
+					return space
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Metric returns the distance metric used to score results
+func (c *ChromaStore) Metric() Metric {
+	return c.metric
+}
+
 // Insert inserts a single code chunk with its embedding
 func (c *ChromaStore) Insert(ctx context.Context, chunk chunker.CodeChunk, embedding []float64) error {
-	metadata := chunkToMetadata(chunk)
+	if err := c.checkDimension(embedding); err != nil {
+		return fmt.Errorf("failed to insert chunk %s: %w", chunk.ID, err)
+	}
+
+	metadata := chunkToMetadata(chunk, c.fields)
 	emb := embeddings.NewEmbeddingFromFloat64(embedding)
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
 	err := c.collection.Upsert(
 		ctx,
 		chroma.WithIDs(chroma.DocumentID(chunk.ID)),
-		chroma.WithTexts(chunk.Code),
+		chroma.WithTexts(c.document(chunk)),
 		chroma.WithMetadatas(metadata),
 		chroma.WithEmbeddings(emb),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to insert chunk %s: %w", chunk.ID, err)
+		return fmt.Errorf("failed to insert chunk %s: %w", chunk.ID, chromaError(err))
 	}
 
 	return nil
 }
 
+// document returns the text stored for a chunk: its code, or nothing when
+// store_code is false
+func (c *ChromaStore) document(chunk chunker.CodeChunk) string {
+	if !c.storeCode {
+		return ""
+	}
+	return chunk.Code
+}
+
 // InsertBatch inserts multiple code chunks with their embeddings in batches
 func (c *ChromaStore) InsertBatch(ctx context.Context, chunks []chunker.CodeChunk, embs [][]float64) error {
 	if len(chunks) != len(embs) {
@@ -88,8 +236,25 @@ func (c *ChromaStore) InsertBatch(ctx context.Context, chunks []chunker.CodeChun
 		return nil
 	}
 
-	// Process in batches of 1000 to avoid memory issues
-	batchSize := 1000
+	// Check every vector before sending any, so a model change fails here
+	// rather than partway through the upserts. A collection that holds no
+	// vectors yet takes the first one's length.
+	want := c.dimension
+	if want == 0 {
+		want = len(embs[0])
+	}
+	for i, emb := range embs {
+		if len(emb) != want {
+			return fmt.Errorf("failed to insert chunk %s: %w", chunks[i].ID,
+				&DimensionError{Collection: c.collection.Name(), Got: len(emb), Want: want})
+		}
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	// Process in batches (insert_batch_size, default 1000) to bound request size
+	batchSize := c.batchSize
 	for i := 0; i < len(chunks); i += batchSize {
 		end := i + batchSize
 		if end > len(chunks) {
@@ -107,8 +272,8 @@ func (c *ChromaStore) InsertBatch(ctx context.Context, chunks []chunker.CodeChun
 
 		for j, chunk := range batchChunks {
 			ids[j] = chroma.DocumentID(chunk.ID)
-			documents[j] = chunk.Code
-			metadatas[j] = chunkToMetadata(chunk)
+			documents[j] = c.document(chunk)
+			metadatas[j] = chunkToMetadata(chunk, c.fields)
 			embeddingsList[j] = embeddings.NewEmbeddingFromFloat64(batchEmbeddings[j])
 		}
 
@@ -121,35 +286,40 @@ func (c *ChromaStore) InsertBatch(ctx context.Context, chunks []chunker.CodeChun
 			chroma.WithEmbeddings(embeddingsList...),
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert batch [%d:%d]: %w", i, end, err)
+			return fmt.Errorf("failed to insert batch [%d:%d]: %w", i, end, chromaError(err))
 		}
 	}
 
 	return nil
 }
 
-// Search performs semantic search with optional filters
-func (c *ChromaStore) Search(ctx context.Context, queryEmbedding []float64, limit int, filters map[string]interface{}) ([]SearchResult, error) {
+// Search performs semantic search scoped by the given options
+func (c *ChromaStore) Search(ctx context.Context, queryEmbedding []float64, searchOpts SearchOptions) ([]SearchResult, error) {
+	if err := c.checkDimension(queryEmbedding); err != nil {
+		return nil, fmt.Errorf("failed to query collection: %w", err)
+	}
+
 	// Build query options
 	queryEmb := embeddings.NewEmbeddingFromFloat64(queryEmbedding)
+	include := []chroma.Include{chroma.IncludeMetadatas, chroma.IncludeDocuments, chroma.IncludeDistances}
+	if searchOpts.IncludeEmbeddings {
+		include = append(include, chroma.IncludeEmbeddings)
+	}
 	opts := []chroma.QueryOption{
 		chroma.WithQueryEmbeddings(queryEmb),
-		chroma.WithNResults(limit),
-		chroma.WithIncludeQuery(chroma.IncludeMetadatas, chroma.IncludeDocuments, chroma.IncludeDistances),
+		chroma.WithNResults(searchOpts.Offset + searchOpts.EffectiveLimit()), // Chroma has no query offset
+		chroma.WithIncludeQuery(include...),
 	}
 
-	// Add where clause if filters provided
-	if len(filters) > 0 {
-		whereClause := buildWhereClause(filters)
-		if whereClause != nil {
-			opts = append(opts, chroma.WithWhereQuery(whereClause))
-		}
+	// Add where clause if any filters are set
+	if whereClause := buildWhereClause(searchOpts); whereClause != nil {
+		opts = append(opts, chroma.WithWhereQuery(whereClause))
 	}
 
 	// Query the collection
 	queryResults, err := c.collection.Query(ctx, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query collection: %w", err)
+		return nil, fmt.Errorf("failed to query collection: %w", chromaError(err))
 	}
 
 	// Convert results to SearchResult format
@@ -166,6 +336,10 @@ func (c *ChromaStore) Search(ctx context.Context, queryEmbedding []float64, limi
 	documents := queryResults.GetDocumentsGroups()[0]
 	metadatas := queryResults.GetMetadatasGroups()[0]
 	distances := queryResults.GetDistancesGroups()[0]
+	var storedEmbeddings embeddings.Embeddings
+	if groups := queryResults.GetEmbeddingsGroups(); searchOpts.IncludeEmbeddings && len(groups) > 0 {
+		storedEmbeddings = groups[0]
+	}
 
 	for i := 0; i < len(ids); i++ {
 		// Reconstruct chunk from metadata
@@ -176,34 +350,144 @@ func (c *ChromaStore) Search(ctx context.Context, queryEmbedding []float64, limi
 		// Get distance (convert from float32 to float64)
 		distance := float64(distances[i])
 
-		// Calculate score from distance (cosine similarity: score = 1 - distance)
-		score := 1.0 - distance
+		// Convert distance to a higher-is-better score for the collection's metric
+		score := scoreFromDistance(c.metric, distance)
+		if score < searchOpts.MinScore {
+			continue
+		}
 
-		results = append(results, SearchResult{
+		result := SearchResult{
 			Chunk:    chunk,
 			Score:    score,
 			Distance: distance,
-		})
+		}
+		if i < len(storedEmbeddings) {
+			result.Embedding = embeddingToFloat64(storedEmbeddings[i])
+		}
+
+		results = append(results, result)
 	}
 
-	return results, nil
+	if searchOpts.Offset >= len(results) {
+		return []SearchResult{}, nil
+	}
+	return results[searchOpts.Offset:], nil
 }
 
 // Delete deletes all chunks for a project
 func (c *ChromaStore) Delete(ctx context.Context, projectName string) error {
 	whereClause := chroma.EqString(chroma.K("project"), projectName)
 
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
 	err := c.collection.Delete(
 		ctx,
 		chroma.WithWhereDelete(whereClause),
 	)
 	if err != nil {
-		return fmt.Errorf("failed to delete project '%s': %w", projectName, err)
+		return fmt.Errorf("failed to delete project '%s': %w", projectName, chromaError(err))
+	}
+
+	return nil
+}
+
+// DeleteByFile deletes the chunks of a single file within a project
+func (c *ChromaStore) DeleteByFile(ctx context.Context, projectName string, filePath string) error {
+	whereClause := chroma.And(
+		chroma.EqString(chroma.K("project"), projectName),
+		chroma.EqString(chroma.K("file_path"), filePath),
+	)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	err := c.collection.Delete(
+		ctx,
+		chroma.WithWhereDelete(whereClause),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete chunks of '%s' in project '%s': %w", filePath, projectName, chromaError(err))
+	}
+
+	return nil
+}
+
+// CountChunksByFile returns the number of chunks stored for one file of a
+// project
+func (c *ChromaStore) CountChunksByFile(ctx context.Context, projectName string, filePath string) (int, error) {
+	results, err := c.collection.Get(
+		ctx,
+		chroma.WithWhereGet(chroma.And(
+			chroma.EqString(chroma.K("project"), projectName),
+			chroma.EqString(chroma.K("file_path"), filePath),
+		)),
+		chroma.WithIncludeGet(chroma.IncludeMetadatas),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count chunks of '%s' in project '%s': %w", filePath, projectName, chromaError(err))
+	}
+	return len(results.GetIDs()), nil
+}
+
+// CountChunks returns the number of chunks stored for a project
+func (c *ChromaStore) CountChunks(ctx context.Context, projectName string) (int, error) {
+	ids, err := c.ListChunkIDs(ctx, projectName)
+	if err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+// DeleteChunks deletes chunks by ID, one insert batch per request
+func (c *ChromaStore) DeleteChunks(ctx context.Context, ids []string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	for start := 0; start < len(ids); start += c.batchSize {
+		end := start + c.batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		docIDs := make([]chroma.DocumentID, end-start)
+		for i, id := range ids[start:end] {
+			docIDs[i] = chroma.DocumentID(id)
+		}
+		if err := c.collection.Delete(ctx, chroma.WithIDsDelete(docIDs...)); err != nil {
+			return fmt.Errorf("failed to delete chunks: %w", chromaError(err))
+		}
 	}
 
 	return nil
 }
 
+// ListChunkIDs returns the IDs of every chunk stored for a project, fetched
+// one insert batch at a time
+func (c *ChromaStore) ListChunkIDs(ctx context.Context, projectName string) ([]string, error) {
+	var ids []string
+	for offset := 0; ; offset += c.batchSize {
+		results, err := c.collection.Get(
+			ctx,
+			chroma.WithWhereGet(chroma.EqString(chroma.K("project"), projectName)),
+			chroma.WithIncludeGet(chroma.IncludeMetadatas),
+			chroma.WithLimitGet(c.batchSize),
+			chroma.WithOffsetGet(offset),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list chunks of project '%s': %w", projectName, chromaError(err))
+		}
+
+		page := results.GetIDs()
+		for _, id := range page {
+			ids = append(ids, string(id))
+		}
+		if len(page) < c.batchSize {
+			return ids, nil
+		}
+	}
+}
+
 // ListProjects returns a list of all indexed projects
 func (c *ChromaStore) ListProjects(ctx context.Context) ([]string, error) {
 	// Get all documents (metadata only)
@@ -212,7 +496,7 @@ func (c *ChromaStore) ListProjects(ctx context.Context) ([]string, error) {
 		chroma.WithIncludeGet(chroma.IncludeMetadatas),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get projects: %w", err)
+		return nil, fmt.Errorf("failed to get projects: %w", chromaError(err))
 	}
 
 	// Extract unique project names
@@ -242,11 +526,11 @@ func (c *ChromaStore) GetChunk(ctx context.Context, id string) (*chunker.CodeChu
 		chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeDocuments),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get chunk %s: %w", id, err)
+		return nil, fmt.Errorf("failed to get chunk %s: %w", id, chromaError(err))
 	}
 
 	if results.Count() == 0 {
-		return nil, fmt.Errorf("chunk not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, id)
 	}
 
 	// Convert first result to CodeChunk
@@ -261,6 +545,232 @@ func (c *ChromaStore) GetChunk(ctx context.Context, id string) (*chunker.CodeChu
 	return &chunk, nil
 }
 
+// GetChunksByProject retrieves every chunk of a project, sorted by file path
+// and then by starting line
+func (c *ChromaStore) GetChunksByProject(ctx context.Context, projectName string) ([]chunker.CodeChunk, error) {
+	results, err := c.collection.Get(
+		ctx,
+		chroma.WithWhereGet(chroma.EqString(chroma.K("project"), projectName)),
+		chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeDocuments),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks for project '%s': %w", projectName, chromaError(err))
+	}
+
+	return sortedChunks(results), nil
+}
+
+// EachChunkByProject calls fn for every chunk of a project in the order the
+// store returns them, fetching one insert batch at a time so a large project
+// is never held in memory. It stops at the first error fn returns.
+func (c *ChromaStore) EachChunkByProject(ctx context.Context, projectName string, fn func(chunker.CodeChunk) error) error {
+	for offset := 0; ; offset += c.batchSize {
+		results, err := c.collection.Get(
+			ctx,
+			chroma.WithWhereGet(chroma.EqString(chroma.K("project"), projectName)),
+			chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeDocuments),
+			chroma.WithLimitGet(c.batchSize),
+			chroma.WithOffsetGet(offset),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to get chunks for project '%s': %w", projectName, chromaError(err))
+		}
+
+		ids := results.GetIDs()
+		documents := results.GetDocuments()
+		metadatas := results.GetMetadatas()
+		for i := range ids {
+			chunk := metadataToChunk(metadatas[i])
+			chunk.ID = string(ids[i])
+			if i < len(documents) {
+				chunk.Code = documents[i].ContentString()
+			}
+			if err := fn(chunk); err != nil {
+				return err
+			}
+		}
+
+		if len(ids) < c.batchSize {
+			return nil
+		}
+	}
+}
+
+// sortedChunks converts Get results to chunks sorted by file path and then
+// by starting line
+func sortedChunks(results chroma.GetResult) []chunker.CodeChunk {
+	ids := results.GetIDs()
+	documents := results.GetDocuments()
+	metadatas := results.GetMetadatas()
+
+	chunks := make([]chunker.CodeChunk, len(ids))
+	for i := range ids {
+		chunks[i] = metadataToChunk(metadatas[i])
+		chunks[i].ID = string(ids[i])
+		if i < len(documents) {
+			chunks[i].Code = documents[i].ContentString()
+		}
+	}
+
+	sort.Slice(chunks, func(a, b int) bool {
+		if chunks[a].FilePath != chunks[b].FilePath {
+			return chunks[a].FilePath < chunks[b].FilePath
+		}
+		return chunks[a].LineStart < chunks[b].LineStart
+	})
+
+	return chunks
+}
+
+// GetChunksByName retrieves the chunks of a project with any of the given
+// names, sorted by file path and then by starting line
+func (c *ChromaStore) GetChunksByName(ctx context.Context, projectName string, names []string) ([]chunker.CodeChunk, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	results, err := c.collection.Get(
+		ctx,
+		chroma.WithWhereGet(chroma.And(
+			chroma.EqString(chroma.K("project"), projectName),
+			chroma.InString(chroma.K("name"), names...),
+		)),
+		chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeDocuments),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks by name in project '%s': %w", projectName, chromaError(err))
+	}
+
+	return sortedChunks(results), nil
+}
+
+// GetChunksByFile retrieves the chunks of a project in any of the given
+// files, sorted by file path and then by starting line
+func (c *ChromaStore) GetChunksByFile(ctx context.Context, projectName string, filePaths []string) ([]chunker.CodeChunk, error) {
+	if len(filePaths) == 0 {
+		return nil, nil
+	}
+
+	results, err := c.collection.Get(
+		ctx,
+		chroma.WithWhereGet(chroma.And(
+			chroma.EqString(chroma.K("project"), projectName),
+			chroma.InString(chroma.K("file_path"), filePaths...),
+		)),
+		chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeDocuments),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunks by file in project '%s': %w", projectName, chromaError(err))
+	}
+
+	return sortedChunks(results), nil
+}
+
+// EmbeddingsByHash returns the stored vectors of a project's chunks keyed by
+// their content hash, fetched one insert batch at a time. Only the chunks of
+// filePaths are read, or every chunk of the project when filePaths is empty.
+// Chunks indexed before content hashes were recorded are skipped.
+func (c *ChromaStore) EmbeddingsByHash(ctx context.Context, projectName string, filePaths []string) (map[string][]float64, error) {
+	var where chroma.WhereFilter = chroma.EqString(chroma.K("project"), projectName)
+	if len(filePaths) > 0 {
+		where = chroma.And(
+			chroma.EqString(chroma.K("project"), projectName),
+			chroma.InString(chroma.K("file_path"), filePaths...),
+		)
+	}
+
+	byHash := make(map[string][]float64)
+	for offset := 0; ; offset += c.batchSize {
+		results, err := c.collection.Get(
+			ctx,
+			chroma.WithWhereGet(where),
+			chroma.WithIncludeGet(chroma.IncludeMetadatas, chroma.IncludeEmbeddings),
+			chroma.WithLimitGet(c.batchSize),
+			chroma.WithOffsetGet(offset),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get stored embeddings of project '%s': %w", projectName, chromaError(err))
+		}
+
+		ids := results.GetIDs()
+		metadatas := results.GetMetadatas()
+		embs := results.GetEmbeddings()
+		for i := range ids {
+			if i >= len(metadatas) || i >= len(embs) {
+				break
+			}
+			if hash := getStringMeta(metadatas[i], "content_hash"); hash != "" {
+				byHash[hash] = embeddingToFloat64(embs[i])
+			}
+		}
+
+		if len(ids) < c.batchSize {
+			return byHash, nil
+		}
+	}
+}
+
+// GetEmbedding retrieves the stored vector for a chunk by ID
+func (c *ChromaStore) GetEmbedding(ctx context.Context, id string) ([]float64, error) {
+	results, err := c.collection.Get(
+		ctx,
+		chroma.WithIDsGet(chroma.DocumentID(id)),
+		chroma.WithIncludeGet(chroma.IncludeEmbeddings),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get embedding for chunk %s: %w", id, chromaError(err))
+	}
+
+	embs := results.GetEmbeddings()
+	if results.Count() == 0 || len(embs) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrChunkNotFound, id)
+	}
+
+	return embeddingToFloat64(embs[0]), nil
+}
+
+// ListCollections lists every collection in the Chroma database, with its
+// chunk count
+func (c *ChromaStore) ListCollections(ctx context.Context) ([]CollectionInfo, error) {
+	collections, err := c.client.ListCollections(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", chromaError(err))
+	}
+
+	infos := make([]CollectionInfo, 0, len(collections))
+	for _, collection := range collections {
+		count, err := collection.Count(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count collection '%s': %w", collection.Name(), chromaError(err))
+		}
+		info := CollectionInfo{
+			Name:      collection.Name(),
+			Chunks:    count,
+			Dimension: collectionDimension(collection),
+		}
+		if space := collectionSpace(collection); space != "" {
+			if metric, err := ParseMetric(space); err == nil {
+				info.Metric = metric
+			}
+		}
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos, nil
+}
+
+// DeleteCollection deletes a collection and every chunk in it. Deleting the
+// store's own collection leaves the store unusable.
+func (c *ChromaStore) DeleteCollection(ctx context.Context, name string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.client.DeleteCollection(ctx, name); err != nil {
+		return fmt.Errorf("failed to delete collection '%s': %w", name, chromaError(err))
+	}
+	return nil
+}
+
 // Close closes the ChromaDB connection
 func (c *ChromaStore) Close() error {
 	if c.client != nil {
@@ -271,8 +781,22 @@ func (c *ChromaStore) Close() error {
 
 // Helper functions
 
-// parseEndpoint extracts ChromaDB server URL from config
-func parseEndpoint(config Config) string {
+// embeddingToFloat64 converts a stored ChromaDB embedding to a float64 vector
+func embeddingToFloat64(emb embeddings.Embedding) []float64 {
+	if emb == nil || !emb.IsDefined() {
+		return nil
+	}
+	values := emb.ContentAsFloat32()
+	vec := make([]float64, len(values))
+	for i, v := range values {
+		vec[i] = float64(v)
+	}
+	return vec
+}
+
+// Endpoint returns the ChromaDB server URL: Options["endpoint"], else Path
+// if it is a URL, else the local default
+func (config Config) Endpoint() string {
 	// Check options first
 	if endpoint, ok := config.Options["endpoint"]; ok && endpoint != "" {
 		return endpoint
@@ -287,35 +811,105 @@ func parseEndpoint(config Config) string {
 	return "http://localhost:8000"
 }
 
-// buildWhereClause converts filter map to ChromaDB Where clause
-func buildWhereClause(filters map[string]interface{}) chroma.WhereFilter {
-	if len(filters) == 0 {
-		return nil
+// clientOptions builds the ChromaDB client options for authentication and
+// tenancy. A token (auth_token, api_key, or the variable named by
+// auth_token_env) is sent as "Authorization: Bearer" unless auth_header is
+// x-chroma-token; username and password use basic auth instead. tenant and
+// database default to Chroma's defaults.
+func clientOptions(config Config) ([]chroma.ClientOption, error) {
+	var options []chroma.ClientOption
+
+	token := config.Options["auth_token"]
+	if token == "" {
+		token = config.Options["api_key"]
+	}
+	if env := config.Options["auth_token_env"]; token == "" && env != "" {
+		token = os.Getenv(env)
+		if token == "" {
+			return nil, fmt.Errorf("auth_token_env is set but %s is empty", env)
+		}
 	}
+	username, password := config.Options["username"], config.Options["password"]
 
-	// Build where clauses for each filter
+	switch {
+	case token != "" && username != "":
+		return nil, fmt.Errorf("set either an auth token or username/password, not both")
+	case token != "":
+		var header chroma.TokenTransportHeader
+		switch strings.ToLower(config.Options["auth_header"]) {
+		case "", "authorization":
+			header = chroma.AuthorizationTokenHeader
+		case "x-chroma-token":
+			header = chroma.XChromaTokenHeader
+		default:
+			return nil, fmt.Errorf("invalid auth_header %q (expected authorization or x-chroma-token)", config.Options["auth_header"])
+		}
+		options = append(options, chroma.WithAuth(chroma.NewTokenAuthCredentialsProvider(token, header)))
+	case username != "":
+		options = append(options, chroma.WithAuth(chroma.NewBasicAuthCredentialsProvider(username, password)))
+	case password != "":
+		return nil, fmt.Errorf("password is set without a username")
+	}
+
+	tenant, database := config.Options["tenant"], config.Options["database"]
+	if tenant != "" || database != "" {
+		if tenant == "" {
+			tenant = chroma.DefaultTenant
+		}
+		if database == "" {
+			database = chroma.DefaultDatabase
+		}
+		options = append(options, chroma.WithDatabaseAndTenant(database, tenant))
+	}
+
+	return options, nil
+}
+
+// buildWhereClause converts search options to a ChromaDB Where clause
+func buildWhereClause(opts SearchOptions) chroma.WhereFilter {
 	var clauses []chroma.WhereClause
-	for key, value := range filters {
-		// Map filter keys to metadata field names
-		switch key {
-		case "project", "language", "chunk_type", "package", "file_path":
-			if strVal, ok := value.(string); ok {
-				clauses = append(clauses, chroma.EqString(chroma.K(key), strVal))
-			}
-		case "projects": // Multiple projects (OR)
-			if projects, ok := value.([]string); ok && len(projects) > 0 {
-				if len(projects) == 1 {
-					clauses = append(clauses, chroma.EqString(chroma.K("project"), projects[0]))
-				} else {
-					// Build OR clause for multiple projects
-					var projectClauses []chroma.WhereClause
-					for _, proj := range projects {
-						projectClauses = append(projectClauses, chroma.EqString(chroma.K("project"), proj))
-					}
-					clauses = append(clauses, chroma.Or(projectClauses...))
-				}
-			}
+
+	switch len(opts.Projects) {
+	case 0:
+	case 1:
+		clauses = append(clauses, chroma.EqString(chroma.K("project"), opts.Projects[0]))
+	default:
+		// Build OR clause for multiple projects
+		var projectClauses []chroma.WhereClause
+		for _, proj := range opts.Projects {
+			projectClauses = append(projectClauses, chroma.EqString(chroma.K("project"), proj))
 		}
+		clauses = append(clauses, chroma.Or(projectClauses...))
+	}
+
+	switch len(opts.Packages) {
+	case 0:
+	case 1:
+		clauses = append(clauses, chroma.EqString(chroma.K("package"), opts.Packages[0]))
+	default:
+		var packageClauses []chroma.WhereClause
+		for _, pkg := range opts.Packages {
+			packageClauses = append(packageClauses, chroma.EqString(chroma.K("package"), pkg))
+		}
+		clauses = append(clauses, chroma.Or(packageClauses...))
+	}
+
+	// Exact-match metadata fields
+	for _, field := range []struct{ key, value string }{
+		{"language", opts.Language},
+		{"chunk_type", opts.ChunkType},
+		{"package", opts.Package},
+		{"file_path", opts.FilePath},
+		{symbolKey(opts.Symbol), opts.Symbol},
+		{"author", opts.Author},
+		{"test_kind", opts.TestKind},
+	} {
+		if field.value != "" {
+			clauses = append(clauses, chroma.EqString(chroma.K(field.key), field.value))
+		}
+	}
+	if opts.Receiver != "" {
+		clauses = append(clauses, receiverClause(opts.Receiver))
 	}
 
 	// If multiple clauses, combine with AND
@@ -329,8 +923,33 @@ func buildWhereClause(filters map[string]interface{}) chroma.WhereFilter {
 	return chroma.And(clauses...)
 }
 
-// chunkToMetadata converts CodeChunk to ChromaDB metadata
-func chunkToMetadata(chunk chunker.CodeChunk) chroma.DocumentMetadata {
+// symbolKey picks the metadata key a symbol filter matches: qualified_name
+// for "Receiver.Name", otherwise name
+func symbolKey(symbol string) string {
+	if strings.Contains(symbol, ".") {
+		return "qualified_name"
+	}
+	return "name"
+}
+
+// receiverClause matches methods on a type whether their receiver is a
+// value or a pointer. The receiver is stored as written, so a generic type
+// must be given with its type parameters, e.g. "Cache[K, V]".
+func receiverClause(receiver string) chroma.WhereClause {
+	name := strings.TrimPrefix(receiver, "*")
+	return chroma.Or(
+		chroma.EqString(chroma.K("receiver"), name),
+		chroma.EqString(chroma.K("receiver"), "*"+name),
+	)
+}
+
+// chunkToMetadata converts CodeChunk to ChromaDB metadata, with only the
+// OptionalMetadataFields in fields (all of them if nil)
+func chunkToMetadata(chunk chunker.CodeChunk, fields map[string]bool) chroma.DocumentMetadata {
+	optional := func(field string) bool {
+		return fields == nil || fields[field]
+	}
+
 	metadata := chroma.NewDocumentMetadata(
 		chroma.NewStringAttribute("project", chunk.Project),
 		chroma.NewStringAttribute("file_path", chunk.FilePath),
@@ -338,6 +957,7 @@ func chunkToMetadata(chunk chunker.CodeChunk) chroma.DocumentMetadata {
 		chroma.NewStringAttribute("language", chunk.Language),
 		chroma.NewStringAttribute("chunk_type", string(chunk.ChunkType)),
 		chroma.NewStringAttribute("name", chunk.Name),
+		chroma.NewStringAttribute("qualified_name", chunk.QualifiedName()),
 		chroma.NewStringAttribute("line_start", fmt.Sprintf("%d", chunk.LineStart)),
 		chroma.NewStringAttribute("line_end", fmt.Sprintf("%d", chunk.LineEnd)),
 	)
@@ -346,34 +966,70 @@ func chunkToMetadata(chunk chunker.CodeChunk) chroma.DocumentMetadata {
 	if chunk.Receiver != "" {
 		metadata.SetString("receiver", chunk.Receiver)
 	}
-	if chunk.DocString != "" {
+	if chunk.Signature != "" && optional("signature") {
+		metadata.SetString("signature", chunk.Signature)
+	}
+	if chunk.DocString != "" && optional("doc_string") {
 		metadata.SetString("doc_string", chunk.DocString)
 	}
-	if chunk.Comments != "" {
+	if chunk.Comments != "" && optional("comments") {
 		metadata.SetString("comments", chunk.Comments)
 	}
+	if chunk.Summary != "" && optional("summary") {
+		metadata.SetString("summary", chunk.Summary)
+	}
+	if chunk.FileContext != "" && optional("file_context") {
+		metadata.SetString("file_context", chunk.FileContext)
+	}
+	if chunk.ContentHash != "" {
+		metadata.SetString("content_hash", chunk.ContentHash)
+	}
+	if chunk.Author != "" {
+		metadata.SetString("author", chunk.Author)
+	}
+	if chunk.TestKind != "" {
+		metadata.SetString("test_kind", string(chunk.TestKind))
+	}
+	if !chunk.LastCommit.IsZero() && optional("last_commit") {
+		metadata.SetString("last_commit", chunk.LastCommit.Format(time.RFC3339))
+	}
 
 	// Serialize array fields to JSON
-	if len(chunk.HTTPEndpoints) > 0 {
+	if len(chunk.Params) > 0 && optional("params") {
+		if data, err := json.Marshal(chunk.Params); err == nil {
+			metadata.SetString("params", string(data))
+		}
+	}
+	if len(chunk.Returns) > 0 && optional("returns") {
+		if data, err := json.Marshal(chunk.Returns); err == nil {
+			metadata.SetString("returns", string(data))
+		}
+	}
+	if len(chunk.HTTPEndpoints) > 0 && optional("http_endpoints") {
 		if data, err := json.Marshal(chunk.HTTPEndpoints); err == nil {
 			metadata.SetString("http_endpoints", string(data))
 		}
 	}
-	if len(chunk.HTTPCalls) > 0 {
+	if len(chunk.HTTPCalls) > 0 && optional("http_calls") {
 		if data, err := json.Marshal(chunk.HTTPCalls); err == nil {
 			metadata.SetString("http_calls", string(data))
 		}
 	}
-	if len(chunk.GRPCMethods) > 0 {
+	if len(chunk.GRPCMethods) > 0 && optional("grpc_methods") {
 		if data, err := json.Marshal(chunk.GRPCMethods); err == nil {
 			metadata.SetString("grpc_methods", string(data))
 		}
 	}
-	if len(chunk.Imports) > 0 {
+	if len(chunk.Imports) > 0 && optional("imports") {
 		if data, err := json.Marshal(chunk.Imports); err == nil {
 			metadata.SetString("imports", string(data))
 		}
 	}
+	if len(chunk.Calls) > 0 && optional("calls") {
+		if data, err := json.Marshal(chunk.Calls); err == nil {
+			metadata.SetString("calls", string(data))
+		}
+	}
 
 	// Format time as RFC3339
 	if !chunk.LastModified.IsZero() {
@@ -393,13 +1049,32 @@ func metadataToChunk(metadata chroma.DocumentMetadata) chunker.CodeChunk {
 		ChunkType: chunker.ChunkType(getStringMeta(metadata, "chunk_type")),
 		Name:      getStringMeta(metadata, "name"),
 		Receiver:  getStringMeta(metadata, "receiver"),
+		Signature: getStringMeta(metadata, "signature"),
 		DocString: getStringMeta(metadata, "doc_string"),
 		Comments:  getStringMeta(metadata, "comments"),
+		Summary:   getStringMeta(metadata, "summary"),
+		Author:    getStringMeta(metadata, "author"),
+		TestKind:  chunker.TestKind(getStringMeta(metadata, "test_kind")),
 		LineStart: getIntMeta(metadata, "line_start"),
 		LineEnd:   getIntMeta(metadata, "line_end"),
+
+		ContentHash: getStringMeta(metadata, "content_hash"),
+		FileContext: getStringMeta(metadata, "file_context"),
 	}
 
 	// Deserialize array fields from JSON
+	if paramsStr := getStringMeta(metadata, "params"); paramsStr != "" {
+		var params []string
+		if err := json.Unmarshal([]byte(paramsStr), &params); err == nil {
+			chunk.Params = params
+		}
+	}
+	if returnsStr := getStringMeta(metadata, "returns"); returnsStr != "" {
+		var returns []string
+		if err := json.Unmarshal([]byte(returnsStr), &returns); err == nil {
+			chunk.Returns = returns
+		}
+	}
 	if httpEndpointsStr := getStringMeta(metadata, "http_endpoints"); httpEndpointsStr != "" {
 		var endpoints []string
 		if err := json.Unmarshal([]byte(httpEndpointsStr), &endpoints); err == nil {
@@ -424,6 +1099,12 @@ func metadataToChunk(metadata chroma.DocumentMetadata) chunker.CodeChunk {
 			chunk.Imports = imports
 		}
 	}
+	if callsStr := getStringMeta(metadata, "calls"); callsStr != "" {
+		var calls []string
+		if err := json.Unmarshal([]byte(callsStr), &calls); err == nil {
+			chunk.Calls = calls
+		}
+	}
 
 	// Parse timestamp
 	if lastModStr := getStringMeta(metadata, "last_modified"); lastModStr != "" {
@@ -431,6 +1112,11 @@ func metadataToChunk(metadata chroma.DocumentMetadata) chunker.CodeChunk {
 			chunk.LastModified = t
 		}
 	}
+	if lastCommitStr := getStringMeta(metadata, "last_commit"); lastCommitStr != "" {
+		if t, err := time.Parse(time.RFC3339, lastCommitStr); err == nil {
+			chunk.LastCommit = t
+		}
+	}
 
 	return chunk
 }
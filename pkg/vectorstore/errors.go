@@ -0,0 +1,46 @@
+package vectorstore
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned (wrapped) by VectorStore implementations; test with
+// errors.Is
+var (
+	// ErrUnavailable means the backend could not be reached
+	ErrUnavailable = errors.New("vector store unavailable")
+
+	// ErrCollectionMissing means the collection no longer exists, e.g. it
+	// was deleted out from under a running process
+	ErrCollectionMissing = errors.New("collection missing")
+
+	// ErrDimensionMismatch means a vector's length differs from the
+	// collection's, usually because the embedding model changed
+	ErrDimensionMismatch = errors.New("embedding dimension mismatch")
+
+	// ErrChunkNotFound means no chunk has the requested ID
+	ErrChunkNotFound = errors.New("chunk not found")
+
+	// ErrInvalidFilter means a legacy filter map has an unknown key or a
+	// value of the wrong type, which would otherwise be silently ignored
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// DimensionError reports a vector whose length differs from the vectors the
+// collection holds, usually because the configured embedding model is not
+// the one the collection was built with. It matches ErrDimensionMismatch.
+type DimensionError struct {
+	Collection string
+	Got        int // length of the rejected vector
+	Want       int // length of the collection's vectors
+}
+
+func (e *DimensionError) Error() string {
+	return fmt.Sprintf("%v: collection '%s' holds %d-dimensional vectors but got a %d-dimensional one; the embedding model differs from the one the collection was built with",
+		ErrDimensionMismatch, e.Collection, e.Want, e.Got)
+}
+
+func (e *DimensionError) Unwrap() error {
+	return ErrDimensionMismatch
+}
@@ -0,0 +1,41 @@
+package vectorstore
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Metric is the distance function a collection's index uses
+type Metric string
+
+const (
+	MetricCosine Metric = "cosine" // distance = 1 - cosine similarity
+	MetricL2     Metric = "l2"     // squared euclidean distance
+	MetricIP     Metric = "ip"     // distance = 1 - inner product
+)
+
+// ParseMetric parses a metric name; an empty name selects MetricCosine
+func ParseMetric(name string) (Metric, error) {
+	switch Metric(strings.ToLower(name)) {
+	case "", MetricCosine:
+		return MetricCosine, nil
+	case MetricL2:
+		return MetricL2, nil
+	case MetricIP:
+		return MetricIP, nil
+	default:
+		return "", fmt.Errorf("unsupported distance metric %q (expected cosine, l2, or ip)", name)
+	}
+}
+
+// scoreFromDistance converts a raw distance into a similarity score where
+// higher is better. Cosine and inner product map back to their similarity;
+// L2 is unbounded, so it is squashed into (0, 1].
+func scoreFromDistance(metric Metric, distance float64) float64 {
+	switch metric {
+	case MetricL2:
+		return 1 / (1 + distance)
+	default:
+		return 1 - distance
+	}
+}
@@ -3,8 +3,13 @@ package vectorstore
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/jayzheng/vectcode/pkg/chunker"
+	"github.com/jayzheng/vectcode/pkg/embedder"
 )
 
 // SearchResult represents a search result from the vector store
@@ -12,25 +17,445 @@ type SearchResult struct {
 	Chunk    chunker.CodeChunk `json:"chunk"`
 	Score    float64            `json:"score"`
 	Distance float64            `json:"distance"`
+
+	// Tokens estimates the tokens the chunk's docs and code take in a prompt
+	Tokens int `json:"tokens"`
+
+	// Embedding is the stored vector, set only when SearchOptions.IncludeEmbeddings is true
+	Embedding []float64 `json:"embedding,omitempty"`
+
+	// Callees are the chunks of the functions this chunk calls, set only when
+	// SearchOptions.IncludeCallees is true
+	Callees []chunker.CodeChunk `json:"callees,omitempty"`
+
+	// MatchedTerms are the query's words found in the chunk, set only when
+	// SearchOptions.Explain is true
+	MatchedTerms []TermMatch `json:"matched_terms,omitempty"`
+}
+
+// TermMatch is a query word found in a result and the fields it is in:
+// "name", "doc", "code", or "path"
+type TermMatch struct {
+	Term   string   `json:"term"`
+	Fields []string `json:"fields"`
+}
+
+// String formats the match as "token (name, code)"
+func (m TermMatch) String() string {
+	return m.Term + " (" + strings.Join(m.Fields, ", ") + ")"
+}
+
+// TotalTokens sums the estimated tokens of results
+func TotalTokens(results []SearchResult) int {
+	total := 0
+	for _, result := range results {
+		total += result.Tokens
+	}
+	return total
+}
+
+// DefaultSearchLimit is used when SearchOptions.Limit is not set
+const DefaultSearchLimit = 5
+
+// SearchOptions scopes and limits a search. Empty fields do not filter.
+type SearchOptions struct {
+	Projects  []string // match any of these projects
+	Language  string
+	ChunkType string
+	Package   string
+	Packages  []string // match any of these packages, e.g. the ones that matter in a large repo
+	FilePath  string
+	Symbol    string  // chunk name, or Receiver.Name for methods (e.g. "Server.Handle")
+	Receiver  string  // methods of this type; "Server" and "*Server" both match either receiver
+	Author    string  // chunks whose lines are mostly by this git author, by exact name (index --with-blame)
+	TestKind  string  // Go tests, benchmarks, fuzz tests, or examples: a chunker.TestKind
+	MinScore  float64 // drop results scoring below this
+	Limit     int     // maximum results; DefaultSearchLimit if <= 0
+	Offset    int     // skip this many top results, for paging
+
+	// PathPrefix keeps results under a directory, e.g. "internal/auth/".
+	// Stores cannot filter on it; query.Engine over-fetches and post-filters.
+	PathPrefix string
+
+	// ParamTypes and ReturnTypes keep functions and methods taking and
+	// returning every listed type, e.g. "context.Context" and "error".
+	// Like PathPrefix, query.Engine post-filters on them.
+	ParamTypes  []string
+	ReturnTypes []string
+
+	// PackageBoosts multiplies the scores of results in these packages,
+	// e.g. {"core": 1.5} to favor core and {"legacy": 0.5} to demote legacy.
+	// Stores ignore it; query.Engine over-fetches and re-scores.
+	PackageBoosts map[string]float64
+
+	// ExcludeTestKinds drops chunks of the chunker.TestKinds listed, e.g.
+	// "example". Most chunks have no test kind stored to compare, so
+	// query.Engine post-filters on it too.
+	ExcludeTestKinds []string
+
+	IncludeEmbeddings bool // return each result's stored vector
+
+	// IncludeCallees resolves each result's calls to chunks in the same
+	// project. Stores ignore it; query.Engine fills SearchResult.Callees.
+	IncludeCallees bool
+
+	// Sparse is the query's sparse vector. A HybridStore adds its score to
+	// the dense one; other stores ignore it.
+	Sparse embedder.SparseVector
+
+	// PreferRecent orders results whose scores are within query.RecencyEpsilon
+	// of each other by the chunk's LastModified, newest first. Stores ignore
+	// it; query.Engine reorders each page.
+	PreferRecent bool
+
+	// Explain lists the query's words each result contains, in
+	// SearchResult.MatchedTerms. Stores ignore it; query.Engine fills it in.
+	Explain bool
+}
+
+// EffectiveLimit returns Limit, or DefaultSearchLimit if unset
+func (o SearchOptions) EffectiveLimit() int {
+	if o.Limit <= 0 {
+		return DefaultSearchLimit
+	}
+	return o.Limit
+}
+
+// MatchesPath reports whether filePath falls under PathPrefix. An absolute
+// prefix must match from the start; a relative one may start at any
+// directory boundary, since chunk paths are recorded as indexed.
+func (o SearchOptions) MatchesPath(filePath string) bool {
+	if o.PathPrefix == "" {
+		return true
+	}
+	if strings.HasPrefix(filePath, o.PathPrefix) {
+		return true
+	}
+	if strings.HasPrefix(o.PathPrefix, "/") {
+		return false
+	}
+	return strings.Contains(filePath, "/"+o.PathPrefix)
+}
+
+// PostFiltered reports whether the options filter on fields stores cannot
+// query, which query.Engine checks with Matches after searching
+func (o SearchOptions) PostFiltered() bool {
+	return o.PathPrefix != "" || len(o.ParamTypes) > 0 || len(o.ReturnTypes) > 0 || len(o.ExcludeTestKinds) > 0
+}
+
+// Matches reports whether a chunk passes the post-filtered options:
+// PathPrefix, ParamTypes, ReturnTypes, and ExcludeTestKinds
+func (o SearchOptions) Matches(chunk chunker.CodeChunk) bool {
+	return o.MatchesPath(chunk.FilePath) &&
+		containsTypes(chunk.Params, o.ParamTypes) &&
+		containsTypes(chunk.Returns, o.ReturnTypes) &&
+		(chunk.TestKind == "" || !slices.Contains(o.ExcludeTestKinds, string(chunk.TestKind)))
+}
+
+// containsTypes reports whether every wanted type is among types. Types are
+// compared without spaces, so "map[string] int" matches "map[string]int".
+func containsTypes(types, wanted []string) bool {
+	for _, want := range wanted {
+		want = strings.ReplaceAll(want, " ", "")
+		found := false
+		for _, typ := range types {
+			if strings.ReplaceAll(typ, " ", "") == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// filterKeys are the keys SearchOptionsFromFilters recognizes
+var filterKeys = []string{"project", "projects", "language", "chunk_type", "package", "file_path"}
+
+// SearchOptionsFromFilters converts the legacy filter map into SearchOptions.
+// Recognized keys: project, projects, language, chunk_type, package, file_path.
+// Unknown keys and values of the wrong type are ignored; use
+// ParseSearchFilters to reject them instead.
+//
+// Deprecated: build a SearchOptions directly. This shim will be removed in the next release.
+func SearchOptionsFromFilters(limit int, filters map[string]interface{}) SearchOptions {
+	opts := SearchOptions{Limit: limit}
+	for key, value := range filters {
+		// Best effort, as before filters were checked
+		_ = applyFilter(&opts, key, value)
+	}
+	return opts
+}
+
+// ParseSearchFilters converts a legacy filter map into SearchOptions like
+// SearchOptionsFromFilters, but fails with ErrInvalidFilter on an unknown key
+// (e.g. a typo like "langauge") or a value of the wrong type rather than
+// searching unfiltered. projects may be a []string or, as decoded from JSON,
+// a []interface{} of strings.
+func ParseSearchFilters(limit int, filters map[string]interface{}) (SearchOptions, error) {
+	opts := SearchOptions{Limit: limit}
+
+	// Sorted so the error for several bad keys is deterministic
+	keys := make([]string, 0, len(filters))
+	for key := range filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := applyFilter(&opts, key, filters[key]); err != nil {
+			return SearchOptions{}, err
+		}
+	}
+	return opts, nil
+}
+
+// applyFilter sets the option for one filter map entry
+func applyFilter(opts *SearchOptions, key string, value interface{}) error {
+	ok := true
+	switch key {
+	case "project":
+		var project string
+		if project, ok = value.(string); ok {
+			opts.Projects = append(opts.Projects, project)
+		}
+	case "projects":
+		switch projects := value.(type) {
+		case []string:
+			opts.Projects = append(opts.Projects, projects...)
+		case []interface{}:
+			for n, item := range projects {
+				project, isString := item.(string)
+				if !isString {
+					return fmt.Errorf("%w: projects[%d] must be a string, got %T", ErrInvalidFilter, n, item)
+				}
+				opts.Projects = append(opts.Projects, project)
+			}
+		default:
+			ok = false
+		}
+	case "language":
+		opts.Language, ok = value.(string)
+	case "chunk_type":
+		opts.ChunkType, ok = value.(string)
+	case "package":
+		opts.Package, ok = value.(string)
+	case "file_path":
+		opts.FilePath, ok = value.(string)
+	default:
+		return fmt.Errorf("%w: unknown key %q (expected one of %s)", ErrInvalidFilter, key, strings.Join(filterKeys, ", "))
+	}
+	if !ok {
+		return fmt.Errorf("%w: %s must be a %s, got %T", ErrInvalidFilter, key, filterType(key), value)
+	}
+	return nil
+}
+
+// filterType names the value type a filter key takes
+func filterType(key string) string {
+	if key == "projects" {
+		return "[]string"
+	}
+	return "string"
 }
 
 // VectorStore defines the interface for vector storage backends
 type VectorStore interface {
 	Insert(ctx context.Context, chunk chunker.CodeChunk, embedding []float64) error
 	InsertBatch(ctx context.Context, chunks []chunker.CodeChunk, embeddings [][]float64) error
-	Search(ctx context.Context, queryEmbedding []float64, limit int, filters map[string]interface{}) ([]SearchResult, error)
+	Search(ctx context.Context, queryEmbedding []float64, opts SearchOptions) ([]SearchResult, error)
 	Delete(ctx context.Context, projectName string) error
+	DeleteByFile(ctx context.Context, projectName string, filePath string) error
+	DeleteChunks(ctx context.Context, ids []string) error
+	CountChunksByFile(ctx context.Context, projectName string, filePath string) (int, error)
+	CountChunks(ctx context.Context, projectName string) (int, error)
+	ListChunkIDs(ctx context.Context, projectName string) ([]string, error)
 	ListProjects(ctx context.Context) ([]string, error)
 	GetChunk(ctx context.Context, id string) (*chunker.CodeChunk, error)
+	GetChunksByProject(ctx context.Context, projectName string) ([]chunker.CodeChunk, error)            // sorted by file path, then line
+	EachChunkByProject(ctx context.Context, projectName string, fn func(chunker.CodeChunk) error) error // in index order, fetched in pages
+	GetChunksByName(ctx context.Context, projectName string, names []string) ([]chunker.CodeChunk, error)
+	GetChunksByFile(ctx context.Context, projectName string, filePaths []string) ([]chunker.CodeChunk, error) // sorted by file path, then line
+	GetEmbedding(ctx context.Context, id string) ([]float64, error)
+	EmbeddingsByHash(ctx context.Context, projectName string, filePaths []string) (map[string][]float64, error) // keyed by content hash
 	Close() error
 }
 
+// HybridStore is implemented by stores that index a sparse vector next to
+// each dense one and, when SearchOptions.Sparse is set, combine both scores
+// in Search, for an embedder.MultiEmbedder's vectors. The Chroma store does
+// not implement it, and the indexer and query engine do not use it yet.
+type HybridStore interface {
+	VectorStore
+	InsertBatchHybrid(ctx context.Context, chunks []chunker.CodeChunk, dense [][]float64, sparse []embedder.SparseVector) error
+}
+
+// CollectionInfo describes a collection of a CollectionManager's backend
+type CollectionInfo struct {
+	Name      string
+	Chunks    int
+	Dimension int    // vector length; 0 if not yet known
+	Metric    Metric // distance metric; empty if not recorded
+}
+
+// CollectionManager is implemented by stores that keep chunks in named
+// collections, each store using one, and can list and delete every
+// collection of the backend. The Chroma store implements it.
+type CollectionManager interface {
+	ListCollections(ctx context.Context) ([]CollectionInfo, error) // sorted by name
+	DeleteCollection(ctx context.Context, name string) error
+}
+
 // Config holds vector store configuration
 type Config struct {
 	Type       string            `yaml:"type"`
 	Path       string            `yaml:"path"`
 	Collection string            `yaml:"collection"`
 	Options    map[string]string `yaml:"options"`
+
+	// Dimensions is the length of the embedder's vectors, checked against the
+	// collection and every insert; zero skips the checks
+	Dimensions int `yaml:"-"`
+
+	// Model is the embedding model, which names the collection when
+	// Options["namespace_by_model"] is true
+	Model string `yaml:"-"`
+}
+
+// DefaultCollection is used when Config.Collection is not set
+const DefaultCollection = "vectcode"
+
+// maxCollectionName is the longest collection name Chroma accepts
+const maxCollectionName = 63
+
+// CollectionName returns the collection the store uses: Collection (or
+// DefaultCollection), suffixed with the model when Options["namespace_by_model"]
+// is true, e.g. "vectcode__bge-m3", so each model gets a collection of its own
+func (c Config) CollectionName() (string, error) {
+	name := c.Collection
+	if name == "" {
+		name = DefaultCollection
+	}
+
+	namespace := false
+	if value := c.Options["namespace_by_model"]; value != "" {
+		var err error
+		if namespace, err = strconv.ParseBool(value); err != nil {
+			return "", fmt.Errorf("invalid namespace_by_model %q (expected true or false)", value)
+		}
+	}
+	if !namespace || c.Model == "" {
+		return name, nil
+	}
+
+	name += "__" + collectionSafe(c.Model)
+	if len(name) > maxCollectionName {
+		name = strings.TrimRight(name[:maxCollectionName], "._-")
+	}
+	return name, nil
+}
+
+// collectionSafe replaces the characters Chroma rejects in collection names,
+// e.g. "nomic-embed-text:latest" -> "nomic-embed-text-latest"
+func collectionSafe(model string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, model), "._-")
+}
+
+// DefaultInsertBatchSize is the number of chunks written per upsert
+const DefaultInsertBatchSize = 1000
+
+// InsertBatchSize reads Options["insert_batch_size"], defaulting to
+// DefaultInsertBatchSize when unset
+func (c Config) InsertBatchSize() (int, error) {
+	value, ok := c.Options["insert_batch_size"]
+	if !ok || value == "" {
+		return DefaultInsertBatchSize, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid insert_batch_size %q (expected a positive integer)", value)
+	}
+	return size, nil
+}
+
+// StoreCode reads Options["store_code"]. When false, chunks are stored with
+// an empty document, keeping only their metadata and vector, so search
+// results and fetched chunks come back without code. Defaults to true.
+func (c Config) StoreCode() (bool, error) {
+	value, ok := c.Options["store_code"]
+	if !ok || value == "" {
+		return true, nil
+	}
+
+	store, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid store_code %q (expected true or false)", value)
+	}
+	return store, nil
+}
+
+// OptionalMetadataFields are the chunk fields the metadata_fields option can
+// leave out of the vector store. The fields searches filter and sort on are
+// always stored: project, file path, package, language, type, name,
+// receiver, lines, content hash, author, and modification time.
+var OptionalMetadataFields = []string{
+	"signature", "doc_string", "comments", "summary", "file_context", "params", "returns",
+	"http_endpoints", "http_calls", "grpc_methods", "imports", "calls", "last_commit",
+}
+
+// MetadataFields reads Options["metadata_fields"], a comma-separated list of
+// the OptionalMetadataFields to store with each chunk, "all" (the default),
+// or "none". Fields not stored come back empty in search results and
+// fetched chunks, and the features reading them lose them: params and
+// returns for --param-type and --return-type, calls for --with-callgraph.
+// Chunks rebuilt from the store also lack them, so reembed refuses when the
+// embedded text uses one (it would embed different text than index did),
+// and index --summarize --skip-unchanged cannot reuse summaries unless
+// summary is stored. It returns nil when every field is stored.
+func (c Config) MetadataFields() (map[string]bool, error) {
+	value := strings.TrimSpace(c.Options["metadata_fields"])
+	switch value {
+	case "", "all":
+		return nil, nil
+	case "none":
+		return map[string]bool{}, nil
+	}
+
+	fields := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(OptionalMetadataFields, field) {
+			return nil, fmt.Errorf("invalid metadata_fields entry %q (expected all, none, or some of %s)", field, strings.Join(OptionalMetadataFields, ", "))
+		}
+		fields[field] = true
+	}
+	return fields, nil
+}
+
+// UnstoredFields returns those of fields, chunk field names as in
+// OptionalMetadataFields, that metadata_fields leaves out of the store
+func (c Config) UnstoredFields(fields []string) ([]string, error) {
+	stored, err := c.MetadataFields()
+	if err != nil || stored == nil {
+		return nil, err
+	}
+	var unstored []string
+	for _, field := range fields {
+		if slices.Contains(OptionalMetadataFields, field) && !stored[field] {
+			unstored = append(unstored, field)
+		}
+	}
+	return unstored, nil
 }
 
 // New creates a vector store based on the type in the config